@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SignURL gives the query string that lets anyone holding it get the path until it expires
+func SignURL(path string, expires time.Time) string {
+	expiresAt := strconv.FormatInt(expires.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	query.Set("signature", signature(path, expiresAt))
+	return query.Encode()
+}
+
+// VerifySignedURL tells if the expiry and signature of a query made by SignURL are valid for the path
+func VerifySignedURL(path, expires, sig string) bool {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(signature(path, expires)))
+}
+
+func signature(path, expires string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("API_SECRET")))
+	mac.Write([]byte(path + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// UnsubscribeToken gives the token of the one-click unsubscribe links of the emails sent to the user.
+// It stops the emails of the kind, and does not expire: the links of old emails keep working
+func UnsubscribeToken(uid uint32, kind string) string {
+	payload := fmt.Sprintf("%d.%s", uid, kind)
+	return payload + "." + unsubscribeSignature(payload)
+}
+
+// ParseUnsubscribeToken gives the user and the kind of emails of a token made by UnsubscribeToken
+func ParseUnsubscribeToken(token string) (uint32, string, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, "", false
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(unsubscribeSignature(payload))) {
+		return 0, "", false
+	}
+	uid, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil || uid == 0 || parts[1] == "" {
+		return 0, "", false
+	}
+	return uint32(uid), parts[1], true
+}
+
+// The payload is prefixed, so a signature made for a URL is never valid here
+func unsubscribeSignature(payload string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("API_SECRET")))
+	mac.Write([]byte("unsubscribe\n" + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// No more than maxAttachments files are sent at once
+const maxAttachments = 10
+
+// attachmentQuota is the number of bytes each user can upload, 100MB unless ATTACHMENT_QUOTA_MB says otherwise
+func attachmentQuota() int64 {
+	megabytes, err := strconv.ParseInt(os.Getenv("ATTACHMENT_QUOTA_MB"), 10, 64)
+	if err != nil || megabytes <= 0 {
+		megabytes = 100
+	}
+	return megabytes << 20
+}
+
+func (server *Server) UploadPostAttachments(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	postID := c.Param("id")
+	pid, err := strconv.ParseUint(postID, 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// Drafts can have attachments too, so the post is not looked up as a published one
+	post := models.Post{}
+	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	if err != nil {
+		errList["No_post"] = "No Post Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	if uid != post.AuthorID {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	if post.Locked {
+		errList["Locked_post"] = "This post is locked"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
+	server.saveAttachments(c, uid, models.AttachmentPost, post.ID)
+}
+
+func (server *Server) UploadCommentAttachments(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	commentID := c.Param("id")
+	cid, err := strconv.ParseUint(commentID, 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	comment := models.Comment{}
+	err = server.DB.Debug().Model(models.Comment{}).Where("id = ?", cid).Take(&comment).Error
+	if err != nil {
+		errList["No_comment"] = "No Comment Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	if uid != comment.UserID {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	server.saveAttachments(c, uid, models.AttachmentComment, comment.ID)
+}
+
+// saveAttachments uploads the files of the "files" form field and links them to the post or comment
+func (server *Server) saveAttachments(c *gin.Context, uid uint32, resourceType string, rid uint64) {
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 {
+		errList["Invalid_file"] = "Invalid File"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	files := form.File["files"]
+	if len(files) > maxAttachments {
+		errList["Too_many_files"] = fmt.Sprintf("Sorry, upload %d files or less at once", maxAttachments)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	// The sizes sent are only used to refuse early, the quota is checked again against what is really stored
+	attachment := models.Attachment{}
+	used, err := attachment.UserQuotaUsed(server.DB, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	quota := attachmentQuota()
+	announced := used
+	for _, file := range files {
+		announced += file.Size
+	}
+	if announced > quota {
+		errList["Quota_exceeded"] = fmt.Sprintf("Sorry, you cannot upload more than %dMB", quota>>20)
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
+
+	// Every file is checked before any is stored, so a bad one leaves nothing behind
+	checkedFiles := []*fileupload.AttachmentFile{}
+	for _, file := range files {
+		checkedFile, fileErr := fileupload.FileUpload.ReadAttachment(file)
+		if fileErr != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"status": http.StatusUnprocessableEntity,
+				"error":  fileErr,
+			})
+			return
+		}
+		used += int64(len(checkedFile.Content))
+		if used > quota {
+			errList["Quota_exceeded"] = fmt.Sprintf("Sorry, you cannot upload more than %dMB", quota>>20)
+			c.JSON(http.StatusForbidden, gin.H{
+				"status": http.StatusForbidden,
+				"error":  errList,
+			})
+			return
+		}
+		checkedFiles = append(checkedFiles, checkedFile)
+	}
+
+	attachments := []models.Attachment{}
+	for _, checkedFile := range checkedFiles {
+		uploadedFile, err := fileupload.FileUpload.StoreAttachment(checkedFile)
+		if err != nil {
+			fmt.Println("cannot store the attachment: ", err)
+			deleteAttachmentFiles(attachments)
+			errList["Other_error"] = "Please try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+		attachments = append(attachments, models.Attachment{
+			ResourceType: resourceType,
+			ResourceID:   rid,
+			UserID:       uid,
+			Path:         uploadedFile.Path,
+			FileName:     uploadedFile.FileName,
+			ContentType:  uploadedFile.ContentType,
+			Size:         uploadedFile.Size,
+		})
+	}
+	// The quota is checked again while saving, as other uploads of the user may have been saved meanwhile
+	attachmentsCreated, err := attachment.SaveAttachments(server.DB, uid, quota, attachments)
+	if err != nil {
+		deleteAttachmentFiles(attachments)
+		if err == models.ErrQuotaExceeded {
+			errList["Quota_exceeded"] = fmt.Sprintf("Sorry, you cannot upload more than %dMB", quota>>20)
+			c.JSON(http.StatusForbidden, gin.H{
+				"status": http.StatusForbidden,
+				"error":  errList,
+			})
+			return
+		}
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   http.StatusCreated,
+		"response": attachmentsCreated,
+	})
+}
+
+// deleteAttachmentFiles removes the stored files of attachments that were not saved
+func deleteAttachmentFiles(attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		err := fileupload.FileUpload.DeleteFile(attachment.Path)
+		if err != nil {
+			fmt.Println("cannot delete the attachment file: ", err)
+		}
+	}
+}
+
+func (server *Server) DeleteAttachment(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	attachmentID := c.Param("id")
+	aid, err := strconv.ParseUint(attachmentID, 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	attachment := models.Attachment{}
+	err = server.DB.Debug().Model(models.Attachment{}).Where("id = ?", aid).Take(&attachment).Error
+	if err != nil {
+		errList["No_attachment"] = "No Attachment Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	if uid != attachment.UserID {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	err = fileupload.FileUpload.DeleteFile(attachment.Path)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	_, err = attachment.DeleteAttachment(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "Attachment deleted",
+	})
+}
+
+// ServeUpload gives a file of the local storage. It goes by the content type the extension was picked from,
+// and browsers are told not to guess another one, so no upload is run as a page of the API
+func (server *Server) ServeUpload(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	filePath := strings.TrimPrefix(c.Param("filepath"), "/")
+	content, err := fileupload.Store.Get(filePath)
+	if err != nil {
+		errList["No_file"] = "No File Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, fileupload.ContentType(filePath), content)
+}
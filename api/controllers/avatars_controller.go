@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/utils/identicon"
+)
+
+// GetAvatar serves the generated avatar of users without one of their own.
+// It only depends on the username, so it can be cached for good
+func (server *Server) GetAvatar(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	file := c.Param("file")
+	if !strings.HasSuffix(file, ".png") || file == ".png" {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	username := strings.TrimSuffix(file, ".png")
+
+	// The sizes are the ones of the uploaded avatars
+	size := 128
+	if c.Query("size") != "" {
+		size, _ = strconv.Atoi(c.Query("size"))
+		valid := false
+		for _, avatarSize := range fileupload.AvatarSizes {
+			if size == avatarSize {
+				valid = true
+			}
+		}
+		if !valid {
+			errList["Invalid_size"] = "Invalid Size"
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  errList,
+			})
+			return
+		}
+	}
+
+	etag := identicon.ETag(username, size)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	avatar, err := identicon.Render(username, size)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", avatar)
+}
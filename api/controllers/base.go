@@ -4,14 +4,20 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/mailer"
 	"github.com/victorsteven/forum/api/middlewares"
+	"github.com/victorsteven/forum/api/migrations"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 	_ "github.com/jinzhu/gorm/dialects/mysql"    //mysql database driver
 	_ "github.com/jinzhu/gorm/dialects/postgres" //postgres database driver
 	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/realtime"
 )
 
 type Server struct {
@@ -55,11 +61,55 @@ func (server *Server) Initialize(Dbdriver, DbUser, DbPassword, DbPort, DbHost, D
 		&models.ResetPassword{},
 		&models.Like{},
 		&models.Comment{},
+		&models.Revision{},
+		&models.PostSlug{},
+		&models.Poll{},
+		&models.PollOption{},
+		&models.PollVote{},
+		&models.PollChoice{},
+		&models.Attachment{},
+		&models.Follow{},
+		&models.CategoryFollow{},
+		&models.Block{},
+		&models.Mute{},
+		&models.UsernameChange{},
+		&models.EmailChange{},
+		&models.DataExport{},
+		&models.AccountClosure{},
+		&models.Notification{},
+		&models.NotificationActor{},
+		&models.Mention{},
+		&models.NotificationPreference{},
+		&models.EmailDigest{},
 	)
 
+	//data migration
+	err = migrations.Run(server.DB)
+	if err != nil {
+		log.Fatal("This is the error running the migrations:", err)
+	}
+
+	err = fileupload.Configure(os.Getenv("STORAGE_DRIVER"))
+	if err != nil {
+		log.Fatal("This is the error setting up the storage:", err)
+	}
+
+	err = mailer.Configure(os.Getenv("MAIL_TRANSPORT"))
+	if err != nil {
+		log.Fatal("This is the error setting up the mail transport:", err)
+	}
+
+	// The writes of the models are published to the real-time subscribers
+	realtime.Listen()
+
 	server.Router = gin.Default()
 	server.Router.Use(middlewares.CORSMiddleware())
 
+	// Files kept on the local disk are served by the API itself
+	if os.Getenv("STORAGE_DRIVER") == fileupload.DriverLocal {
+		server.Router.GET("/uploads/*filepath", server.ServeUpload)
+	}
+
 	server.initializeRoutes()
 
 }
@@ -67,3 +117,52 @@ func (server *Server) Initialize(Dbdriver, DbUser, DbPassword, DbPort, DbHost, D
 func (server *Server) Run(addr string) {
 	log.Fatal(http.ListenAndServe(addr, server.Router))
 }
+
+// Moderators and admins can act on the content of other users
+func (server *Server) isModerator(uid uint32) bool {
+	user := models.User{}
+	err := server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		return false
+	}
+	return user.IsModerator()
+}
+
+// Only admins can manage the site itself
+func (server *Server) isAdmin(uid uint32) bool {
+	user := models.User{}
+	err := server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		return false
+	}
+	return user.IsAdmin()
+}
+
+// The format query parameter picks the representation of posts and comments, both are given when it is missing
+func contentFormat(c *gin.Context) (string, bool) {
+	format := c.Query("format")
+	switch format {
+	case "", models.FormatRaw, models.FormatHTML:
+		return format, true
+	}
+	return "", false
+}
+
+// The page and per_page query parameters pick a page of a list, 20 items from the first page when they are missing
+func pagination(c *gin.Context) (int, int, bool) {
+	page, perPage := 1, 20
+	var err error
+	if c.Query("page") != "" {
+		page, err = strconv.Atoi(c.Query("page"))
+		if err != nil || page < 1 {
+			return 0, 0, false
+		}
+	}
+	if c.Query("per_page") != "" {
+		perPage, err = strconv.Atoi(c.Query("per_page"))
+		if err != nil || perPage < 1 || perPage > 100 {
+			return 0, 0, false
+		}
+	}
+	return page, perPage, true
+}
@@ -0,0 +1,225 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+)
+
+// otherUser gives the authenticated user and the user of the request, who must exist and be someone else.
+// Otherwise the error is sent and ok is false
+func (server *Server) otherUser(c *gin.Context) (uint32, uint32, bool) {
+
+	userID := c.Param("id")
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return 0, 0, false
+	}
+	tokenID, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return 0, 0, false
+	}
+	if tokenID == uint32(uid) {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return 0, 0, false
+	}
+	user := models.User{}
+	err = server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return 0, 0, false
+	}
+	return tokenID, user.ID, true
+}
+
+func (server *Server) BlockUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	tokenID, uid, ok := server.otherUser(c)
+	if !ok {
+		return
+	}
+	block := models.Block{}
+	block.BlockerID = tokenID
+	block.BlockedID = uid
+
+	blockCreated, err := block.SaveBlock(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   http.StatusCreated,
+		"response": blockCreated,
+	})
+}
+
+func (server *Server) UnblockUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	tokenID, uid, ok := server.otherUser(c)
+	if !ok {
+		return
+	}
+	block := models.Block{}
+	block.BlockerID = tokenID
+	block.BlockedID = uid
+
+	deleted, err := block.DeleteBlock(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if deleted == 0 {
+		errList["No_block"] = "You have not blocked this user"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "User unblocked",
+	})
+}
+
+func (server *Server) MuteUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	tokenID, uid, ok := server.otherUser(c)
+	if !ok {
+		return
+	}
+	mute := models.Mute{}
+	mute.MuterID = tokenID
+	mute.MutedID = uid
+
+	muteCreated, err := mute.SaveMute(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   http.StatusCreated,
+		"response": muteCreated,
+	})
+}
+
+func (server *Server) UnmuteUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	tokenID, uid, ok := server.otherUser(c)
+	if !ok {
+		return
+	}
+	mute := models.Mute{}
+	mute.MuterID = tokenID
+	mute.MutedID = uid
+
+	deleted, err := mute.DeleteMute(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if deleted == 0 {
+		errList["No_mute"] = "You have not muted this user"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "User unmuted",
+	})
+}
+
+// GetBlocksAndMutes gives the users the authenticated user blocked and muted, only they can see them
+func (server *Server) GetBlocksAndMutes(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	blocked, err := (&models.Block{}).FindBlockedUsers(server.DB, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	muted, err := (&models.Mute{}).FindMutedUsers(server.DB, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"response": gin.H{
+			"blocked": responses.NewAuthors(*blocked),
+			"muted":   responses.NewAuthors(*muted),
+		},
+	})
+}
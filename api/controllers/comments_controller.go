@@ -8,8 +8,10 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	"github.com/victorsteven/forum/api/auth"
 	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
 	"github.com/victorsteven/forum/api/utils/formaterror"
 )
 
@@ -54,7 +56,7 @@ func (server *Server) CreateComment(c *gin.Context) {
 	}
 	// check if the post exist:
 	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	err = server.DB.Debug().Model(models.Post{}).Scopes(models.PublishedPosts).Where("id = ?", pid).Take(&post).Error
 	if err != nil {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -63,6 +65,32 @@ func (server *Server) CreateComment(c *gin.Context) {
 		})
 		return
 	}
+	if post.Locked {
+		errList["Locked_post"] = "This post is locked"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
+	// Users blocked by the author cannot take part in the post
+	blocked, err := (&models.Block{}).IsBlocked(server.DB, post.AuthorID, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if blocked {
+		errList["Blocked_by_author"] = "The author of this post has blocked you"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
 	body, err := ioutil.ReadAll(c.Request.Body)
 	if err != nil {
 		errList["Invalid_body"] = "Unable to get request"
@@ -106,9 +134,19 @@ func (server *Server) CreateComment(c *gin.Context) {
 		})
 		return
 	}
+	// The comment as first written is the first revision
+	_, err = (&models.Revision{}).SaveCommentRevision(server.DB, commentCreated, uid, "")
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusCreated, gin.H{
 		"status":   http.StatusCreated,
-		"response": commentCreated,
+		"response": responses.NewComment(commentCreated),
 	})
 }
 
@@ -131,7 +169,7 @@ func (server *Server) GetComments(c *gin.Context) {
 	}
 	// check if the post exist:
 	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	err = server.DB.Debug().Model(models.Post{}).Scopes(models.PublishedPosts).Where("id = ?", pid).Take(&post).Error
 	if err != nil {
 		errList["No_post"] = "No post found"
 		c.JSON(http.StatusNotFound, gin.H{
@@ -141,9 +179,24 @@ func (server *Server) GetComments(c *gin.Context) {
 		return
 	}
 
+	format, ok := contentFormat(c)
+	if !ok {
+		errList["Invalid_format"] = "Format should be raw or html"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
 	comment := models.Comment{}
 
-	comments, err := comment.GetComments(server.DB, pid)
+	// The comments of the users the viewer muted are left out, when the viewer is logged in
+	var scopes []func(*gorm.DB) *gorm.DB
+	viewerID, err := auth.ExtractTokenID(c.Request)
+	if err == nil && viewerID != 0 {
+		scopes = append(scopes, models.WithoutMutedUsers(viewerID))
+	}
+	comments, err := comment.GetComments(server.DB, pid, scopes...)
 	if err != nil {
 		errList["No_comments"] = "No comments found"
 		c.JSON(http.StatusNotFound, gin.H{
@@ -152,10 +205,35 @@ func (server *Server) GetComments(c *gin.Context) {
 		})
 		return
 	}
+	attachment := models.Attachment{}
+	mention := models.Mention{}
+	for i, _ := range *comments {
+		(*comments)[i].Format(format)
+		attachments, err := attachment.FindAttachments(server.DB, models.AttachmentComment, (*comments)[i].ID)
+		if err != nil {
+			errList["Other_error"] = "Please try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+		(*comments)[i].Attachments = *attachments
+		mentions, err := mention.FindMentions(server.DB, (*comments)[i].PostID, (*comments)[i].ID)
+		if err != nil {
+			errList["Other_error"] = "Please try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+		(*comments)[i].Mentions = *mentions
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": comments,
+		"response": responses.NewComments(*comments),
 	})
 }
 
@@ -239,6 +317,17 @@ func (server *Server) UpdateComment(c *gin.Context) {
 	comment.UserID = origComment.UserID
 	comment.PostID = origComment.PostID
 
+	// Keep the comment as it was before this edit, in case it has no history yet
+	revision := models.Revision{}
+	err = revision.EnsureCommentHistory(server.DB, &origComment)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	commentUpdated, err := comment.UpdateAComment(server.DB)
 	if err != nil {
 		formattedError := formaterror.FormatError(err.Error())
@@ -249,9 +338,18 @@ func (server *Server) UpdateComment(c *gin.Context) {
 		})
 		return
 	}
+	_, err = revision.SaveCommentRevision(server.DB, commentUpdated, uid, "")
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": commentUpdated,
+		"response": responses.NewComment(commentUpdated),
 	})
 }
 
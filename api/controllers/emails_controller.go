@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/mailer"
+)
+
+// authenticatedAdmin tells if the authenticated user is an admin, otherwise the error is sent
+func (server *Server) authenticatedAdmin(c *gin.Context) bool {
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil || !server.isAdmin(uid) {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return false
+	}
+	return true
+}
+
+// GetEmailTemplates lists the emails the admins can preview, and the locales they are written in
+func (server *Server) GetEmailTemplates(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	if !server.authenticatedAdmin(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"response": gin.H{
+			"templates": mailer.Templates(),
+			"locales":   mailer.Locales(),
+		},
+	})
+}
+
+// PreviewEmail renders an email with sample data, in the locale of the locale query parameter
+func (server *Server) PreviewEmail(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	if !server.authenticatedAdmin(c) {
+		return
+	}
+	name := c.Param("name")
+	found := false
+	for _, template := range mailer.Templates() {
+		if template == name {
+			found = true
+		}
+	}
+	if !found {
+		errList["No_template"] = "No Email Template Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	locale := c.DefaultQuery("locale", mailer.DefaultLocale)
+	rendered, err := mailer.Preview(name, locale)
+	if err != nil {
+		errList["Cannot_render"] = "Cannot render the email template"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": rendered,
+	})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/realtime"
+)
+
+// How often an idle stream gets a comment, so that proxies do not close it
+const streamHeartbeat = 30 * time.Second
+
+// StreamPosts sends the new posts as Server-Sent Events
+func (server *Server) StreamPosts(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	server.stream(c, realtime.PostsTopic)
+}
+
+// StreamPost sends the comments and likes of the post as Server-Sent Events
+func (server *Server) StreamPost(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	postID := c.Param("id")
+	pid, err := strconv.ParseUint(postID, 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	post := models.Post{}
+	err = server.DB.Debug().Model(models.Post{}).Scopes(models.PublishedPosts).Where("id = ?", pid).Take(&post).Error
+	if err != nil {
+		errList["No_post"] = "No Post Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	server.stream(c, realtime.PostTopic(post.ID))
+}
+
+// StreamNotifications sends the notifications of the authenticated user as Server-Sent Events.
+// Browsers cannot set headers on an EventSource, so the token can be given as the token query parameter
+func (server *Server) StreamNotifications(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	server.stream(c, realtime.NotificationsTopic(uid))
+}
+
+// stream sends the messages of the topic until the client leaves.
+// The events of the users a logged in viewer muted are left out, as they are from the lists
+func (server *Server) stream(c *gin.Context, topic string) {
+	muted := map[uint32]bool{}
+	viewerID, err := auth.ExtractTokenID(c.Request)
+	if err == nil && viewerID != 0 {
+		mutedUsers, err := (&models.Mute{}).FindMutedUsers(server.DB, viewerID)
+		if err == nil {
+			for _, user := range *mutedUsers {
+				muted[user.ID] = true
+			}
+		}
+	}
+	messages, unsubscribe, err := realtime.Default.Subscribe(topic)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	// Keeps nginx from buffering the stream
+	c.Header("X-Accel-Buffering", "no")
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return false
+			}
+			if !muted[message.ActorID] {
+				c.SSEvent(message.Event, message.Data)
+			}
+			return true
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// RequestExport asks for an archive of the authenticated user's data.
+// It is built in the background, and the user gets an email with the link to download it
+func (server *Server) RequestExport(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	dataExport := models.DataExport{}
+	dataExport.UserID = uid
+
+	exportCreated, err := dataExport.SaveDataExport(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{
+		"status":   http.StatusAccepted,
+		"response": exportCreated,
+	})
+}
+
+// DownloadExport gives the archive of an export. The signed link is all that is needed, so it works from the email
+func (server *Server) DownloadExport(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	exportID := c.Param("id")
+	id, err := strconv.ParseUint(exportID, 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	dataExport := models.DataExport{ID: id}
+	if !auth.VerifySignedURL(dataExport.DownloadPath(), c.Query("expires"), c.Query("signature")) {
+		errList["Invalid_link"] = "Invalid or expired link"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
+	exportFound, err := dataExport.FindDataExportByID(server.DB, id)
+	if err != nil || exportFound.Status != models.ExportReady {
+		errList["No_export"] = "No Export Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	archive, err := fileupload.PrivateStore.Get(exportFound.Path)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="data-export-%d.zip"`, exportFound.ID))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
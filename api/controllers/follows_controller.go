@@ -0,0 +1,358 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+)
+
+func (server *Server) FollowUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	userID := c.Param("id")
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	tokenID, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	if tokenID == uint32(uid) {
+		errList["Self_follow"] = "You cannot follow yourself"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	// check if the user to follow exist, closed accounts cannot be followed:
+	user := models.User{}
+	err = server.DB.Debug().Model(models.User{}).Where("id = ? AND deactivated_at IS NULL", uid).Take(&user).Error
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	blocked, err := (&models.Block{}).IsBlocked(server.DB, user.ID, tokenID)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if blocked {
+		errList["Blocked_by_user"] = "This user has blocked you"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
+	follow := models.Follow{}
+	follow.FollowerID = tokenID
+	follow.FollowingID = user.ID
+
+	followCreated, err := follow.SaveFollow(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   http.StatusCreated,
+		"response": followCreated,
+	})
+}
+
+func (server *Server) UnfollowUser(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	userID := c.Param("id")
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	tokenID, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	follow := models.Follow{}
+	follow.FollowerID = tokenID
+	follow.FollowingID = uint32(uid)
+
+	deleted, err := follow.DeleteFollow(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if deleted == 0 {
+		errList["No_follow"] = "You do not follow this user"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "User unfollowed",
+	})
+}
+
+func (server *Server) GetFollowers(c *gin.Context) {
+	server.getFollows(c, true)
+}
+
+func (server *Server) GetFollowing(c *gin.Context) {
+	server.getFollows(c, false)
+}
+
+// getFollows gives a page of the followers of the user, or of the users the user follows
+func (server *Server) getFollows(c *gin.Context, followers bool) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	userID := c.Param("id")
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	page, perPage, ok := pagination(c)
+	if !ok {
+		errList["Invalid_page"] = "Page should be 1 or more and per_page between 1 and 100"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	user := models.User{}
+	err = server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	follow := models.Follow{}
+	var users *[]models.User
+	if followers {
+		users, err = follow.FindFollowers(server.DB, user.ID, page, perPage)
+	} else {
+		users, err = follow.FindFollowing(server.DB, user.ID, page, perPage)
+	}
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewAuthors(*users),
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+func (server *Server) FollowCategory(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	categoryFollow := models.CategoryFollow{}
+	categoryFollow.UserID = uid
+	categoryFollow.Category = c.Param("category")
+
+	followCreated, err := categoryFollow.SaveCategoryFollow(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   http.StatusCreated,
+		"response": followCreated,
+	})
+}
+
+func (server *Server) UnfollowCategory(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	categoryFollow := models.CategoryFollow{}
+	categoryFollow.UserID = uid
+	categoryFollow.Category = c.Param("category")
+
+	deleted, err := categoryFollow.DeleteCategoryFollow(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if deleted == 0 {
+		errList["No_follow"] = "You do not follow this category"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "Category unfollowed",
+	})
+}
+
+// GetFeed gives the posts of the users and categories the user follows.
+// The next_cursor of a page is sent back as the cursor parameter to get the following one
+func (server *Server) GetFeed(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	format, ok := contentFormat(c)
+	if !ok {
+		errList["Invalid_format"] = "Format should be raw or html"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	_, limit, ok := pagination(c)
+	if !ok {
+		errList["Invalid_page"] = "Page should be 1 or more and per_page between 1 and 100"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	var cursor *models.FeedCursor
+	if c.Query("cursor") != "" {
+		parsed, err := models.ParseFeedCursor(c.Query("cursor"))
+		if err != nil {
+			errList["Invalid_cursor"] = "Invalid Cursor"
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  errList,
+			})
+			return
+		}
+		cursor = &parsed
+	}
+
+	follow := models.Follow{}
+	posts, next, err := follow.FindFeed(server.DB, uid, cursor, limit)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	for i, _ := range *posts {
+		(*posts)[i].Format(format)
+	}
+	err = (&models.Mention{}).FindPostsMentions(server.DB, posts)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":      http.StatusOK,
+		"response":    responses.NewPosts(*posts),
+		"next_cursor": next,
+	})
+}
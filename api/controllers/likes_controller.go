@@ -48,7 +48,7 @@ func (server *Server) LikePost(c *gin.Context) {
 	}
 	// check if the post exist:
 	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	err = server.DB.Debug().Model(models.Post{}).Scopes(models.PublishedPosts).Where("id = ?", pid).Take(&post).Error
 	if err != nil {
 		errList["Unauthorized"] = "Unauthorized"
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -57,6 +57,32 @@ func (server *Server) LikePost(c *gin.Context) {
 		})
 		return
 	}
+	if post.Locked {
+		errList["Locked_post"] = "This post is locked"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
+	// Users blocked by the author cannot take part in the post
+	blocked, err := (&models.Block{}).IsBlocked(server.DB, post.AuthorID, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	if blocked {
+		errList["Blocked_by_author"] = "The author of this post has blocked you"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
 
 	like := models.Like{}
 	like.UserID = user.ID
@@ -99,7 +125,7 @@ func (server *Server) GetLikes(c *gin.Context) {
 
 	// Check if the post exist:
 	post := models.Post{}
-	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	err = server.DB.Debug().Model(models.Post{}).Scopes(models.PublishedPosts).Where("id = ?", pid).Take(&post).Error
 	if err != nil {
 		errList["No_post"] = "No Post Found"
 		c.JSON(http.StatusNotFound, gin.H{
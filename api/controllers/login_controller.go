@@ -68,7 +68,7 @@ func (server *Server) SignIn(email, password string) (map[string]interface{}, er
 
 	user := models.User{}
 
-	err = server.DB.Debug().Model(models.User{}).Where("email = ?", email).Take(&user).Error
+	err = server.DB.Debug().Model(models.User{}).Where("email = ? AND system_account = ?", email, false).Take(&user).Error
 	if err != nil {
 		fmt.Println("this is the error getting the user: ", err)
 		return nil, err
@@ -78,6 +78,14 @@ func (server *Server) SignIn(email, password string) (map[string]interface{}, er
 		fmt.Println("this is the error hashing the password: ", err)
 		return nil, err
 	}
+	// Logging in gives back a closed account that was not purged yet
+	if user.DeactivatedAt != nil {
+		err = user.Reactivate(server.DB, user.ID)
+		if err != nil {
+			fmt.Println("this is the error reactivating the user: ", err)
+			return nil, err
+		}
+	}
 	token, err := auth.CreateToken(user.ID)
 	if err != nil {
 		fmt.Println("this is the error creating the token: ", err)
@@ -0,0 +1,188 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+)
+
+// The scopes a post can be pinned in
+const (
+	pinGlobal   = "global"
+	pinCategory = "category"
+)
+
+type pinRequest struct {
+	Scope string     `json:"scope"`
+	Until *time.Time `json:"until"`
+}
+
+// postToModerate gives the post of the request when the authenticated user is a moderator,
+// otherwise the error is sent and nil is returned
+func (server *Server) postToModerate(c *gin.Context) *models.Post {
+
+	postID := c.Param("id")
+	pid, err := strconv.ParseUint(postID, 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return nil
+	}
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return nil
+	}
+	if !server.isModerator(uid) {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return nil
+	}
+	post := models.Post{}
+	err = server.DB.Debug().Model(models.Post{}).Scopes(models.PublishedPosts).Where("id = ?", pid).Take(&post).Error
+	if err != nil {
+		errList["No_post"] = "No Post Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return nil
+	}
+	return &post
+}
+
+func (server *Server) PinPost(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	post := server.postToModerate(c)
+	if post == nil {
+		return
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	request := pinRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	switch request.Scope {
+	case pinGlobal:
+	case pinCategory:
+		if post.Category == "" {
+			errList["Required_category"] = "The post has no category to be pinned in"
+		}
+	default:
+		errList["Invalid_scope"] = "Scope should be global or category"
+	}
+	// An announcement is pinned until a time to come
+	if request.Until != nil && !request.Until.After(time.Now()) {
+		errList["Invalid_until"] = "The pin should end in the future"
+	}
+	if len(errList) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	postPinned, err := post.Pin(server.DB, request.Scope == pinGlobal, request.Scope == pinCategory, request.Until)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewPost(postPinned),
+	})
+}
+
+func (server *Server) UnpinPost(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	post := server.postToModerate(c)
+	if post == nil {
+		return
+	}
+	postUnpinned, err := post.Unpin(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewPost(postUnpinned),
+	})
+}
+
+func (server *Server) LockPost(c *gin.Context) {
+	server.setPostLocked(c, true)
+}
+
+func (server *Server) UnlockPost(c *gin.Context) {
+	server.setPostLocked(c, false)
+}
+
+func (server *Server) setPostLocked(c *gin.Context, locked bool) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	post := server.postToModerate(c)
+	if post == nil {
+		return
+	}
+	postLocked, err := post.SetLocked(server.DB, locked)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewPost(postLocked),
+	})
+}
@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+)
+
+// GetNotifications gives a page of the notifications of the authenticated user, the latest first.
+// With unread=true only the unread ones are given
+func (server *Server) GetNotifications(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	page, perPage, ok := pagination(c)
+	if !ok {
+		errList["Invalid_page"] = "Page should be 1 or more and per_page between 1 and 100"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	unreadOnly := false
+	if c.Query("unread") != "" {
+		unreadOnly, err = strconv.ParseBool(c.Query("unread"))
+		if err != nil {
+			errList["Invalid_request"] = "Invalid Request"
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status": http.StatusBadRequest,
+				"error":  errList,
+			})
+			return
+		}
+	}
+	notification := models.Notification{}
+	notifications, err := notification.FindNotifications(server.DB, uid, unreadOnly, page, perPage)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	unread, err := notification.CountUnread(server.DB, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":       http.StatusOK,
+		"response":     responses.NewNotifications(*notifications),
+		"unread_count": unread,
+		"page":         page,
+		"per_page":     perPage,
+	})
+}
+
+func (server *Server) MarkNotificationRead(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	notificationID := c.Param("id")
+	id, err := strconv.ParseUint(notificationID, 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// The notifications of the others are not found either
+	notification := models.Notification{}
+	err = notification.MarkRead(server.DB, uid, id)
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			errList["No_notification"] = "No Notification Found"
+			c.JSON(http.StatusNotFound, gin.H{
+				"status": http.StatusNotFound,
+				"error":  errList,
+			})
+			return
+		}
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	err = server.DB.Debug().Model(&models.User{}).Where("id = ?", notification.ActorID).Take(&notification.Actor).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewNotification(&notification),
+	})
+}
+
+func (server *Server) MarkAllNotificationsRead(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	notification := models.Notification{}
+	marked, err := notification.MarkAllRead(server.DB, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": marked,
+	})
+}
+
+// GetNotificationPreferences gives how often the authenticated user is emailed about each kind of notification
+func (server *Server) GetNotificationPreferences(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	preference := models.NotificationPreference{}
+	preferences, err := preference.FindPreferences(server.DB, uid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": preferences,
+	})
+}
+
+// UpdateNotificationPreferences changes the email frequencies of the kinds of notifications given,
+// like {"like": "weekly", "mention": "immediate"}
+func (server *Server) UpdateNotificationPreferences(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	requestBody := map[string]string{}
+	err = json.Unmarshal(body, &requestBody)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	errorMessages := models.ValidatePreferences(requestBody)
+	if len(errorMessages) > 0 {
+		errList = errorMessages
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	preference := models.NotificationPreference{}
+	preferences, err := preference.SavePreferences(server.DB, uid, requestBody)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": preferences,
+	})
+}
+
+// Unsubscribe turns off the emails the token of an unsubscribe link is for. The token is in the query,
+// as mail clients post the link of the List-Unsubscribe header as it is, with a form body of their own (RFC 8058)
+func (server *Server) Unsubscribe(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	uid, kind, ok := auth.ParseUnsubscribeToken(c.Query("token"))
+	if !ok {
+		errList["Invalid_token"] = "Invalid link"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	// The links of a deleted user stay signed, but there is nobody left to unsubscribe
+	user := models.User{}
+	err := server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		errList["Invalid_token"] = "Invalid link"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	preference := models.NotificationPreference{}
+	_, err = preference.Unsubscribe(server.DB, uid, kind)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "You will not get these emails anymore",
+	})
+}
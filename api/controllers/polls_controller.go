@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+	"github.com/victorsteven/forum/api/utils/formaterror"
+)
+
+type voteRequest struct {
+	Options []uint64 `json:"options"`
+}
+
+func (server *Server) VotePoll(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	postID := c.Param("id")
+	pid, err := strconv.ParseUint(postID, 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// check if the user exist:
+	user := models.User{}
+	err = server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// check if the post exist:
+	post := models.Post{}
+	err = server.DB.Debug().Model(models.Post{}).Scopes(models.PublishedPosts).Where("id = ?", pid).Take(&post).Error
+	if err != nil {
+		errList["No_post"] = "No Post Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	if post.Locked {
+		errList["Locked_post"] = "This post is locked"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
+	poll := models.Poll{}
+	_, err = poll.FindPostPoll(server.DB, pid)
+	if err != nil {
+		errList["No_poll"] = "No Poll Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	if poll.Closed {
+		errList["Closed_poll"] = "This poll is closed"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	request := voteRequest{}
+	err = json.Unmarshal(body, &request)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	// The options picked should be options of this poll, each picked once
+	pollOptions := map[uint64]bool{}
+	for _, option := range poll.Options {
+		pollOptions[option.ID] = true
+	}
+	picked := map[uint64]bool{}
+	for _, optionID := range request.Options {
+		if !pollOptions[optionID] || picked[optionID] {
+			errList["Invalid_option"] = "Invalid Option"
+		}
+		picked[optionID] = true
+	}
+	if len(request.Options) == 0 || (!poll.Multiple && len(request.Options) > 1) {
+		errList["Invalid_options_count"] = "Pick one option, or more when the poll is multiple choice"
+	}
+	if len(errList) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+
+	vote := models.PollVote{}
+	vote.PollID = poll.ID
+	vote.UserID = user.ID
+
+	_, err = vote.SaveVote(server.DB, request.Options)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if err == models.ErrDoubleVote {
+			status = http.StatusConflict
+		}
+		errList = formaterror.FormatError(err.Error())
+		c.JSON(status, gin.H{
+			"status": status,
+			"error":  errList,
+		})
+		return
+	}
+	// Send back the results with this vote counted
+	pollResults, err := (&models.Poll{}).FindPostPoll(server.DB, pid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"status":   http.StatusCreated,
+		"response": responses.NewPoll(pollResults),
+	})
+}
@@ -6,10 +6,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	"github.com/victorsteven/forum/api/auth"
 	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
 	"github.com/victorsteven/forum/api/utils/formaterror"
 )
 
@@ -64,6 +67,14 @@ func (server *Server) CreatePost(c *gin.Context) {
 
 	post.Prepare()
 	errorMessages := post.Validate()
+	// A poll can only be attached when the post is created
+	poll := post.Poll
+	if poll != nil {
+		poll.Prepare()
+		for key, message := range poll.Validate() {
+			errorMessages[key] = message
+		}
+	}
 	if len(errorMessages) > 0 {
 		errList = errorMessages
 		c.JSON(http.StatusUnprocessableEntity, gin.H{
@@ -84,15 +95,37 @@ func (server *Server) CreatePost(c *gin.Context) {
 	}
 	c.JSON(http.StatusCreated, gin.H{
 		"status":   http.StatusCreated,
-		"response": postCreated,
+		"response": responses.NewPost(postCreated),
 	})
 }
 
 func (server *Server) GetPosts(c *gin.Context) {
 
+	format, ok := contentFormat(c)
+	if !ok {
+		errList["Invalid_format"] = "Format should be raw or html"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+
 	post := models.Post{}
 
-	posts, err := post.FindAllPosts(server.DB)
+	// The posts of the users the viewer muted are left out, when the viewer is logged in
+	var scopes []func(*gorm.DB) *gorm.DB
+	viewerID, err := auth.ExtractTokenID(c.Request)
+	if err == nil && viewerID != 0 {
+		scopes = append(scopes, models.WithoutMutedAuthors(viewerID))
+	}
+	var posts *[]models.Post
+	category := strings.ToLower(strings.TrimSpace(c.Query("category")))
+	if category != "" {
+		posts, err = post.FindCategoryPosts(server.DB, category, scopes...)
+	} else {
+		posts, err = post.FindAllPosts(server.DB, scopes...)
+	}
 	if err != nil {
 		errList["No_post"] = "No Post Found"
 		c.JSON(http.StatusNotFound, gin.H{
@@ -101,9 +134,21 @@ func (server *Server) GetPosts(c *gin.Context) {
 		})
 		return
 	}
+	for i, _ := range *posts {
+		(*posts)[i].Format(format)
+	}
+	err = (&models.Mention{}).FindPostsMentions(server.DB, posts)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": posts,
+		"response": responses.NewPosts(*posts),
 	})
 }
 
@@ -119,6 +164,15 @@ func (server *Server) GetPost(c *gin.Context) {
 		})
 		return
 	}
+	format, ok := contentFormat(c)
+	if !ok {
+		errList["Invalid_format"] = "Format should be raw or html"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
 	post := models.Post{}
 
 	postReceived, err := post.FindPostByID(server.DB, pid)
@@ -130,10 +184,46 @@ func (server *Server) GetPost(c *gin.Context) {
 		})
 		return
 	}
+	postReceived.Format(format)
+
+	// The results of the poll, when the post has one
+	poll := models.Poll{}
+	postReceived.Poll, err = poll.FindPostPoll(server.DB, pid)
+	if err != nil {
+		if !gorm.IsRecordNotFoundError(err) {
+			errList["Other_error"] = "Please try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+		postReceived.Poll = nil
+	}
+	attachments, err := (&models.Attachment{}).FindAttachments(server.DB, models.AttachmentPost, pid)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	postReceived.Attachments = *attachments
+	mentions, err := (&models.Mention{}).FindMentions(server.DB, pid, 0)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	postReceived.Mentions = *mentions
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": postReceived,
+		"response": responses.NewPost(postReceived),
 	})
 }
 
@@ -182,6 +272,14 @@ func (server *Server) UpdatePost(c *gin.Context) {
 		})
 		return
 	}
+	if origPost.Locked {
+		errList["Locked_post"] = "This post is locked"
+		c.JSON(http.StatusForbidden, gin.H{
+			"status": http.StatusForbidden,
+			"error":  errList,
+		})
+		return
+	}
 	// Read the data posted
 	body, err := ioutil.ReadAll(c.Request.Body)
 	if err != nil {
@@ -216,6 +314,17 @@ func (server *Server) UpdatePost(c *gin.Context) {
 		})
 		return
 	}
+	// Keep the post as it was before this edit, in case it has no history yet
+	revision := models.Revision{}
+	err = revision.EnsurePostHistory(server.DB, &origPost)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	postUpdated, err := post.UpdateAPost(server.DB)
 	if err != nil {
 		errList := formaterror.FormatError(err.Error())
@@ -225,9 +334,18 @@ func (server *Server) UpdatePost(c *gin.Context) {
 		})
 		return
 	}
+	_, err = revision.SavePostRevision(server.DB, postUpdated, uid, "")
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": postUpdated,
+		"response": responses.NewPost(postUpdated),
 	})
 }
 
@@ -277,7 +395,8 @@ func (server *Server) DeletePost(c *gin.Context) {
 		})
 		return
 	}
-	// If all the conditions are met, delete the post
+	// If all the conditions are met, move the post to the trash.
+	// The likes and the comments are kept so the post can be restored, the purger removes them with the post
 	_, err = post.DeleteAPost(server.DB)
 	if err != nil {
 		errList["Other_error"] = "Please try again later"
@@ -287,20 +406,49 @@ func (server *Server) DeletePost(c *gin.Context) {
 		})
 		return
 	}
-	comment := models.Comment{}
-	like := models.Like{}
 
-	// Also delete the likes and the comments that this post have:
-	_, err = comment.DeletePostComments(server.DB, pid)
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "Post deleted",
+	})
+}
+
+func (server *Server) GetUserPosts(c *gin.Context) {
+
+	userID := c.Param("id")
+	// Is a valid user id given to us?
+	uid, err := strconv.ParseUint(userID, 10, 64)
 	if err != nil {
-		errList["Other_error"] = "Please try again later"
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"status": http.StatusInternalServerError,
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
 			"error":  errList,
 		})
 		return
 	}
-	_, err = like.DeletePostLikes(server.DB, pid)
+	format, ok := contentFormat(c)
+	if !ok {
+		errList["Invalid_format"] = "Format should be raw or html"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	post := models.Post{}
+	posts, err := post.FindUserPosts(server.DB, uint32(uid))
+	if err != nil {
+		errList["No_post"] = "No Post Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	for i, _ := range *posts {
+		(*posts)[i].Format(format)
+	}
+	err = (&models.Mention{}).FindPostsMentions(server.DB, posts)
 	if err != nil {
 		errList["Other_error"] = "Please try again later"
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -309,20 +457,23 @@ func (server *Server) DeletePost(c *gin.Context) {
 		})
 		return
 	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": "Post deleted",
+		"response": responses.NewPosts(*posts),
 	})
 }
 
-func (server *Server) GetUserPosts(c *gin.Context) {
+// GetPostBySlug gives the post at /api/v1/posts_by_slug/:slug. A former slug of the post redirects to its current one
+func (server *Server) GetPostBySlug(c *gin.Context) {
 
-	userID := c.Param("id")
-	// Is a valid user id given to us?
-	uid, err := strconv.ParseUint(userID, 10, 64)
-	if err != nil {
-		errList["Invalid_request"] = "Invalid Request"
+	//clear previous error if any
+	errList = map[string]string{}
+
+	slug := c.Param("slug")
+
+	format, ok := contentFormat(c)
+	if !ok {
+		errList["Invalid_format"] = "Format should be raw or html"
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status": http.StatusBadRequest,
 			"error":  errList,
@@ -330,7 +481,29 @@ func (server *Server) GetUserPosts(c *gin.Context) {
 		return
 	}
 	post := models.Post{}
-	posts, err := post.FindUserPosts(server.DB, uint32(uid))
+
+	postReceived, err := post.FindPostBySlug(server.DB, slug)
+	if err == nil {
+		postReceived.Format(format)
+		mentions, err := (&models.Mention{}).FindMentions(server.DB, postReceived.ID, 0)
+		if err != nil {
+			errList["Other_error"] = "Please try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+		postReceived.Mentions = *mentions
+		c.JSON(http.StatusOK, gin.H{
+			"status":   http.StatusOK,
+			"response": responses.NewPost(postReceived),
+		})
+		return
+	}
+	// The slug may be one the post had before its title was edited
+	postSlug := models.PostSlug{}
+	renamedPost, err := postSlug.FindPostBySlug(server.DB, slug)
 	if err != nil {
 		errList["No_post"] = "No Post Found"
 		c.JSON(http.StatusNotFound, gin.H{
@@ -339,8 +512,49 @@ func (server *Server) GetUserPosts(c *gin.Context) {
 		})
 		return
 	}
+	location := "/api/v1/posts_by_slug/" + renamedPost.Slug
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusMovedPermanently, location)
+}
+
+func (server *Server) GetDrafts(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	// Only the author can see the drafts
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	post := models.Post{}
+	posts, err := post.FindUserDrafts(server.DB, uid)
+	if err != nil {
+		errList["No_post"] = "No Post Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	err = (&models.Mention{}).FindPostsMentions(server.DB, posts)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": posts,
+		"response": responses.NewPosts(*posts),
 	})
 }
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"os"
 
 	"github.com/gin-gonic/gin"
 	"github.com/victorsteven/forum/api/mailer"
@@ -76,7 +75,7 @@ func (server *Server) ForgotPassword(c *gin.Context) {
 	}
 	fmt.Println("THIS OCCURRED HERE")
 	//Send welcome mail to the user:
-	response, err := mailer.SendMail.SendResetPassword(resetDetails.Email, os.Getenv("SENDGRID_FROM"), resetDetails.Token, os.Getenv("SENDGRID_API_KEY"), os.Getenv("APP_ENV"))
+	response, err := mailer.SendMail.SendResetPassword(resetDetails.Email, user.Locale, resetDetails.Token)
 	if err != nil {
 		c.JSON(http.StatusUnprocessableEntity, gin.H{
 			"status": http.StatusUnprocessableEntity,
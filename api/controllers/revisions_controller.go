@@ -0,0 +1,385 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+	"github.com/victorsteven/forum/api/utils/formaterror"
+)
+
+func (server *Server) GetPostRevisions(c *gin.Context) {
+	server.getRevisions(c, models.RevisionPost)
+}
+
+func (server *Server) GetCommentRevisions(c *gin.Context) {
+	server.getRevisions(c, models.RevisionComment)
+}
+
+func (server *Server) GetPostRevision(c *gin.Context) {
+	server.getRevision(c, models.RevisionPost)
+}
+
+func (server *Server) GetCommentRevision(c *gin.Context) {
+	server.getRevision(c, models.RevisionComment)
+}
+
+func (server *Server) GetPostRevisionsDiff(c *gin.Context) {
+	server.getRevisionsDiff(c, models.RevisionPost)
+}
+
+func (server *Server) GetCommentRevisionsDiff(c *gin.Context) {
+	server.getRevisionsDiff(c, models.RevisionComment)
+}
+
+// Check that the post or the comment the revisions belong to exists
+func (server *Server) revisionResourceExists(resourceType string, rid uint64) error {
+	if resourceType == models.RevisionPost {
+		return server.DB.Debug().Model(models.Post{}).Scopes(models.PublishedPosts).Where("id = ?", rid).Take(&models.Post{}).Error
+	}
+	return server.DB.Debug().Model(models.Comment{}).Where("id = ?", rid).Take(&models.Comment{}).Error
+}
+
+func (server *Server) getRevisions(c *gin.Context, resourceType string) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	rid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	err = server.revisionResourceExists(resourceType, rid)
+	if err != nil {
+		errList["No_record"] = "No Record Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	revision := models.Revision{}
+	revisions, err := revision.FindRevisions(server.DB, resourceType, rid)
+	if err != nil {
+		errList["No_revisions"] = "No Revisions Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewRevisions(*revisions),
+	})
+}
+
+func (server *Server) getRevision(c *gin.Context, resourceType string) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	rid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	err = server.revisionResourceExists(resourceType, rid)
+	if err != nil {
+		errList["No_record"] = "No Record Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	revision := models.Revision{}
+	revisionReceived, err := revision.FindRevision(server.DB, resourceType, rid, number)
+	if err != nil {
+		errList["No_revision"] = "No Revision Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewRevision(revisionReceived),
+	})
+}
+
+// The diff between two revisions is given with the "from" and "to" query parameters
+func (server *Server) getRevisionsDiff(c *gin.Context, resourceType string) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	rid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	err = server.revisionResourceExists(resourceType, rid)
+	if err != nil {
+		errList["No_record"] = "No Record Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	fromRevision, err := (&models.Revision{}).FindRevision(server.DB, resourceType, rid, from)
+	if err != nil {
+		errList["No_revision"] = "No Revision Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	toRevision, err := (&models.Revision{}).FindRevision(server.DB, resourceType, rid, to)
+	if err != nil {
+		errList["No_revision"] = "No Revision Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	diff, err := fromRevision.Diff(toRevision)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": http.StatusOK,
+		"response": gin.H{
+			"from": from,
+			"to":   to,
+			"diff": diff,
+		},
+	})
+}
+
+func (server *Server) RollbackPost(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	pid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// Only moderators can roll back
+	if !server.isModerator(uid) {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	post := models.Post{}
+	err = server.DB.Debug().Model(models.Post{}).Where("id = ?", pid).Take(&post).Error
+	if err != nil {
+		errList["No_post"] = "No Post Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	revision := models.Revision{}
+	revisionReceived, err := revision.FindRevision(server.DB, models.RevisionPost, pid, number)
+	if err != nil {
+		errList["No_revision"] = "No Revision Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	post.Title = revisionReceived.Title
+	post.Content = revisionReceived.Content
+	postUpdated, err := post.UpdateAPost(server.DB)
+	if err != nil {
+		errList := formaterror.FormatError(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	// The rollback is itself an edit, so it is kept in the history
+	_, err = (&models.Revision{}).SavePostRevision(server.DB, postUpdated, uid, revisionReceived.RollbackNote())
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewPost(postUpdated),
+	})
+}
+
+func (server *Server) RollbackComment(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	cid, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	number, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	uid, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// Only moderators can roll back
+	if !server.isModerator(uid) {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	comment := models.Comment{}
+	err = server.DB.Debug().Model(models.Comment{}).Where("id = ?", cid).Take(&comment).Error
+	if err != nil {
+		errList["No_comment"] = "No Comment Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	revision := models.Revision{}
+	revisionReceived, err := revision.FindRevision(server.DB, models.RevisionComment, cid, number)
+	if err != nil {
+		errList["No_revision"] = "No Revision Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
+		})
+		return
+	}
+	comment.Body = revisionReceived.Content
+	commentUpdated, err := comment.UpdateAComment(server.DB)
+	if err != nil {
+		errList := formaterror.FormatError(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	// The rollback is itself an edit, so it is kept in the history
+	_, err = (&models.Revision{}).SaveCommentRevision(server.DB, commentUpdated, uid, revisionReceived.RollbackNote())
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewComment(commentUpdated),
+	})
+}
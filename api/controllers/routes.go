@@ -15,32 +15,114 @@ func (s *Server) initializeRoutes() {
 		v1.POST("/password/forgot", s.ForgotPassword)
 		v1.POST("/password/reset", s.ResetPassword)
 
+		// Email change and account closure links
+		v1.POST("/email/confirm", s.ConfirmEmail)
+		v1.POST("/email/revert", s.RevertEmail)
+		v1.POST("/account/close/confirm", s.ConfirmAccountClosure)
+
 		//Users routes
 		v1.POST("/users", s.CreateUser)
 		// The user of the app have no business getting all the users.
 		// v1.GET("/users", s.GetUsers)
 		// v1.GET("/users/:id", s.GetUser)
-		v1.PUT("/users/:id", middlewares.TokenAuthMiddleware(), s.UpdateUser)
-		v1.PUT("/avatar/users/:id", middlewares.TokenAuthMiddleware(), s.UpdateAvatar)
-		v1.DELETE("/users/:id", middlewares.TokenAuthMiddleware(), s.DeleteUser)
+		// Public profiles are looked up by username
+		v1.GET("/users/:id", s.GetUserProfile)
+		v1.PUT("/users/:id/profile", middlewares.TokenAuthMiddleware(s.DB), s.UpdateProfile)
+		v1.PUT("/users/:id/username", middlewares.TokenAuthMiddleware(s.DB), s.ChangeUsername)
+		v1.PUT("/users/:id", middlewares.TokenAuthMiddleware(s.DB), s.UpdateUser)
+		v1.PUT("/avatar/users/:id", middlewares.TokenAuthMiddleware(s.DB), s.UpdateAvatar)
+		v1.GET("/avatars/:file", s.GetAvatar)
+		v1.DELETE("/users/:id", middlewares.TokenAuthMiddleware(s.DB), s.DeleteUser)
+		v1.POST("/users/:id/deactivate", middlewares.TokenAuthMiddleware(s.DB), s.DeactivateUser)
+
+		//Follow routes
+		v1.POST("/users/:id/follow", middlewares.TokenAuthMiddleware(s.DB), s.FollowUser)
+		v1.DELETE("/users/:id/follow", middlewares.TokenAuthMiddleware(s.DB), s.UnfollowUser)
+		v1.GET("/users/:id/followers", s.GetFollowers)
+		v1.GET("/users/:id/following", s.GetFollowing)
+		v1.POST("/categories/:category/follow", middlewares.TokenAuthMiddleware(s.DB), s.FollowCategory)
+		v1.DELETE("/categories/:category/follow", middlewares.TokenAuthMiddleware(s.DB), s.UnfollowCategory)
+		v1.GET("/feed", middlewares.TokenAuthMiddleware(s.DB), s.GetFeed)
+
+		//Block and mute routes
+		v1.POST("/users/:id/block", middlewares.TokenAuthMiddleware(s.DB), s.BlockUser)
+		v1.DELETE("/users/:id/block", middlewares.TokenAuthMiddleware(s.DB), s.UnblockUser)
+		v1.POST("/users/:id/mute", middlewares.TokenAuthMiddleware(s.DB), s.MuteUser)
+		v1.DELETE("/users/:id/mute", middlewares.TokenAuthMiddleware(s.DB), s.UnmuteUser)
+		v1.GET("/me/blocks", middlewares.TokenAuthMiddleware(s.DB), s.GetBlocksAndMutes)
+
+		//Notification routes
+		v1.GET("/notifications", middlewares.TokenAuthMiddleware(s.DB), s.GetNotifications)
+		v1.PUT("/notifications/:id/read", middlewares.TokenAuthMiddleware(s.DB), s.MarkNotificationRead)
+		// Marking them all is under /me, a static segment in place of the id would clash with the route above
+		v1.PUT("/me/notifications/read", middlewares.TokenAuthMiddleware(s.DB), s.MarkAllNotificationsRead)
+		v1.GET("/me/notification_preferences", middlewares.TokenAuthMiddleware(s.DB), s.GetNotificationPreferences)
+		v1.PUT("/me/notification_preferences", middlewares.TokenAuthMiddleware(s.DB), s.UpdateNotificationPreferences)
+		// The one-click unsubscribe links of the emails work without logging in
+		v1.POST("/unsubscribe", s.Unsubscribe)
+
+		//Real-time routes, served as Server-Sent Events
+		v1.GET("/events/posts", s.StreamPosts)
+		v1.GET("/events/posts/:id", s.StreamPost)
+		v1.GET("/events/notifications", middlewares.TokenAuthMiddleware(s.DB), s.StreamNotifications)
+
+		//Data export routes
+		v1.POST("/me/export", middlewares.TokenAuthMiddleware(s.DB), s.RequestExport)
+		v1.GET("/exports/:id/download", s.DownloadExport)
 
 		//Posts routes
-		v1.POST("/posts", middlewares.TokenAuthMiddleware(), s.CreatePost)
+		v1.POST("/posts", middlewares.TokenAuthMiddleware(s.DB), s.CreatePost)
 		v1.GET("/posts", s.GetPosts)
 		v1.GET("/posts/:id", s.GetPost)
-		v1.PUT("/posts/:id", middlewares.TokenAuthMiddleware(), s.UpdatePost)
-		v1.DELETE("/posts/:id", middlewares.TokenAuthMiddleware(), s.DeletePost)
+		v1.PUT("/posts/:id", middlewares.TokenAuthMiddleware(s.DB), s.UpdatePost)
+		v1.DELETE("/posts/:id", middlewares.TokenAuthMiddleware(s.DB), s.DeletePost)
 		v1.GET("/user_posts/:id", s.GetUserPosts)
+		// Not /posts/by-slug/:slug, which the router cannot have next to /posts/:id, so it is named like /user_posts
+		v1.GET("/posts_by_slug/:slug", s.GetPostBySlug)
+		v1.GET("/me/drafts", middlewares.TokenAuthMiddleware(s.DB), s.GetDrafts)
 
 		//Like route
 		v1.GET("/likes/:id", s.GetLikes)
-		v1.POST("/likes/:id", middlewares.TokenAuthMiddleware(), s.LikePost)
-		v1.DELETE("/likes/:id", middlewares.TokenAuthMiddleware(), s.UnLikePost)
+		v1.POST("/likes/:id", middlewares.TokenAuthMiddleware(s.DB), s.LikePost)
+		v1.DELETE("/likes/:id", middlewares.TokenAuthMiddleware(s.DB), s.UnLikePost)
 
 		//Comment routes
-		v1.POST("/comments/:id", middlewares.TokenAuthMiddleware(), s.CreateComment)
+		v1.POST("/comments/:id", middlewares.TokenAuthMiddleware(s.DB), s.CreateComment)
 		v1.GET("/comments/:id", s.GetComments)
-		v1.PUT("/comments/:id", middlewares.TokenAuthMiddleware(), s.UpdateComment)
-		v1.DELETE("/comments/:id", middlewares.TokenAuthMiddleware(), s.DeleteComment)
+		v1.PUT("/comments/:id", middlewares.TokenAuthMiddleware(s.DB), s.UpdateComment)
+		v1.DELETE("/comments/:id", middlewares.TokenAuthMiddleware(s.DB), s.DeleteComment)
+
+		//Revision routes
+		v1.GET("/posts/:id/revisions", s.GetPostRevisions)
+		v1.GET("/posts/:id/revisions/:rev", s.GetPostRevision)
+		v1.GET("/posts/:id/diff", s.GetPostRevisionsDiff)
+		v1.POST("/posts/:id/revisions/:rev/rollback", middlewares.TokenAuthMiddleware(s.DB), s.RollbackPost)
+		v1.GET("/comments/:id/revisions", s.GetCommentRevisions)
+		v1.GET("/comments/:id/revisions/:rev", s.GetCommentRevision)
+		v1.GET("/comments/:id/diff", s.GetCommentRevisionsDiff)
+		v1.POST("/comments/:id/revisions/:rev/rollback", middlewares.TokenAuthMiddleware(s.DB), s.RollbackComment)
+
+		//Trash routes
+		v1.GET("/trash", middlewares.TokenAuthMiddleware(s.DB), s.GetTrash)
+		v1.POST("/posts/:id/restore", middlewares.TokenAuthMiddleware(s.DB), s.RestorePost)
+		v1.POST("/comments/:id/restore", middlewares.TokenAuthMiddleware(s.DB), s.RestoreComment)
+
+		//Attachment routes
+		v1.POST("/posts/:id/attachments", middlewares.TokenAuthMiddleware(s.DB), s.UploadPostAttachments)
+		v1.POST("/comments/:id/attachments", middlewares.TokenAuthMiddleware(s.DB), s.UploadCommentAttachments)
+		v1.DELETE("/attachments/:id", middlewares.TokenAuthMiddleware(s.DB), s.DeleteAttachment)
+
+		//Poll routes
+		v1.POST("/posts/:id/poll/votes", middlewares.TokenAuthMiddleware(s.DB), s.VotePoll)
+
+		//Moderation routes
+		v1.PUT("/posts/:id/pin", middlewares.TokenAuthMiddleware(s.DB), s.PinPost)
+		v1.DELETE("/posts/:id/pin", middlewares.TokenAuthMiddleware(s.DB), s.UnpinPost)
+		v1.PUT("/posts/:id/lock", middlewares.TokenAuthMiddleware(s.DB), s.LockPost)
+		v1.DELETE("/posts/:id/lock", middlewares.TokenAuthMiddleware(s.DB), s.UnlockPost)
+
+		//Email routes
+		v1.GET("/admin/emails", middlewares.TokenAuthMiddleware(s.DB), s.GetEmailTemplates)
+		v1.GET("/admin/emails/:name", middlewares.TokenAuthMiddleware(s.DB), s.PreviewEmail)
 	}
 }
@@ -2,18 +2,25 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/victorsteven/forum/api/fileupload"
+	"html"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/mailer"
 	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
 	"github.com/victorsteven/forum/api/security"
 	"github.com/victorsteven/forum/api/utils/formaterror"
 )
@@ -44,6 +51,9 @@ func (server *Server) CreateUser(c *gin.Context) {
 		})
 		return
 	}
+	// The role and the state of the account are not for the new user to choose
+	user.Role = models.RoleUser
+	user.DeactivatedAt = nil
 	user.Prepare()
 	errorMessages := user.Validate("")
 	if len(errorMessages) > 0 {
@@ -64,9 +74,14 @@ func (server *Server) CreateUser(c *gin.Context) {
 		})
 		return
 	}
+	// The account is there even if the welcome email is not
+	_, err = mailer.SendMail.SendWelcome(userCreated.Email, userCreated.Locale, userCreated.Username)
+	if err != nil {
+		fmt.Println("cannot send the welcome email: ", err)
+	}
 	c.JSON(http.StatusCreated, gin.H{
 		"status":   http.StatusCreated,
-		"response": userCreated,
+		"response": responses.NewUser(userCreated),
 	})
 }
 
@@ -88,7 +103,7 @@ func (server *Server) GetUsers(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": users,
+		"response": responses.NewUsers(*users),
 	})
 }
 
@@ -121,7 +136,7 @@ func (server *Server) GetUser(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": userGotten,
+		"response": responses.NewUser(userGotten),
 	})
 }
 
@@ -175,6 +190,17 @@ func (server *Server) UpdateAvatar(c *gin.Context) {
 		})
 		return
 	}
+	// The avatar being replaced, as stored rather than as its address
+	var formerPaths []string
+	err = server.DB.Debug().Model(&models.User{}).Where("id = ?", uid).Pluck("avatar_path", &formerPaths).Error
+	if err != nil {
+		errList["Cannot_Save"] = "Cannot Save Image, Pls try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	uploadedFile, fileErr := fileupload.FileUpload.UploadFile(file)
 	if fileErr != nil {
 		c.JSON(http.StatusUnprocessableEntity, fileErr)
@@ -187,6 +213,11 @@ func (server *Server) UpdateAvatar(c *gin.Context) {
 	user.Prepare()
 	updatedUser, err := user.UpdateAUserAvatar(server.DB, uint32(uid))
 	if err != nil {
+		// Nothing points to the new avatar, so it does not stay in the storage
+		deleteErr := fileupload.DeleteAvatar(uploadedFile)
+		if deleteErr != nil {
+			fmt.Println("cannot delete the unsaved avatar: ", deleteErr)
+		}
 		errList["Cannot_Save"] = "Cannot Save Image, Pls try again later"
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
@@ -194,9 +225,16 @@ func (server *Server) UpdateAvatar(c *gin.Context) {
 		})
 		return
 	}
+	// The former avatar is deleted in every size once the new one is saved
+	if len(formerPaths) > 0 && formerPaths[0] != "" && formerPaths[0] != uploadedFile {
+		err = fileupload.DeleteAvatar(formerPaths[0])
+		if err != nil {
+			fmt.Println("cannot delete the former avatar: ", err)
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": updatedUser,
+		"response": responses.NewUser(updatedUser),
 	})
 }
 
@@ -268,9 +306,15 @@ func (server *Server) UpdateUser(c *gin.Context) {
 	}
 
 	newUser := models.User{}
+	newUser.Username = formerUser.Username //remember, the username is changed with ChangeUsername
+	newUser.Email = requestBody["email"]
+	newUser.Prepare()
+
+	// A new email address needs the password too, and is only used once it is confirmed
+	emailChanged := newUser.Email != formerUser.Email
 
 	//When current password has content.
-	if requestBody["current_password"] == "" && requestBody["new_password"] != "" {
+	if requestBody["current_password"] == "" && (requestBody["new_password"] != "" || emailChanged) {
 		errList["Empty_current"] = "Please Provide current password"
 		c.JSON(http.StatusUnprocessableEntity, gin.H{
 			"status": http.StatusUnprocessableEntity,
@@ -278,7 +322,7 @@ func (server *Server) UpdateUser(c *gin.Context) {
 		})
 		return
 	}
-	if requestBody["current_password"] != "" && requestBody["new_password"] == "" {
+	if requestBody["current_password"] != "" && requestBody["new_password"] == "" && !emailChanged {
 		errList["Empty_new"] = "Please Provide new password"
 		c.JSON(http.StatusUnprocessableEntity, gin.H{
 			"status": http.StatusUnprocessableEntity,
@@ -286,9 +330,9 @@ func (server *Server) UpdateUser(c *gin.Context) {
 		})
 		return
 	}
-	if requestBody["current_password"] != "" && requestBody["new_password"] != "" {
+	if requestBody["current_password"] != "" {
 		//Also check if the new password
-		if len(requestBody["new_password"]) < 6 {
+		if requestBody["new_password"] != "" && len(requestBody["new_password"]) < 6 {
 			errList["Invalid_password"] = "Password should be atleast 6 characters"
 			c.JSON(http.StatusUnprocessableEntity, gin.H{
 				"status": http.StatusUnprocessableEntity,
@@ -306,17 +350,9 @@ func (server *Server) UpdateUser(c *gin.Context) {
 			})
 			return
 		}
-		//update both the password and the email
-		newUser.Username = formerUser.Username //remember, you cannot update the username
-		newUser.Email = requestBody["email"]
 		newUser.Password = requestBody["new_password"]
 	}
 
-	//The password fields not entered, so update only the email
-	newUser.Username = formerUser.Username
-	newUser.Email = requestBody["email"]
-
-	newUser.Prepare()
 	errorMessages := newUser.Validate("update")
 	if len(errorMessages) > 0 {
 		errList = errorMessages
@@ -326,6 +362,66 @@ func (server *Server) UpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	if emailChanged {
+		var count int
+		err = server.DB.Debug().Model(models.User{}).Where("email = ? AND id <> ?", newUser.Email, uid).Count(&count).Error
+		if err != nil {
+			errList["Other_error"] = "Please try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+		if count > 0 {
+			errList["Taken_email"] = "Email Already Taken"
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"status": http.StatusUnprocessableEntity,
+				"error":  errList,
+			})
+			return
+		}
+	}
+	// The new address gets the link that confirms it, the old one a notice with the link that reverts it.
+	// They are sent before anything is saved, so nothing changes when they cannot be
+	pendingEmail := ""
+	if emailChanged {
+		emailChange := models.EmailChange{}
+		emailChange.UserID = formerUser.ID
+		emailChange.OldEmail = formerUser.Email
+		emailChange.NewEmail = newUser.Email
+		emailChange.ConfirmToken = security.TokenHash(newUser.Email)
+		emailChange.RevertToken = security.TokenHash(formerUser.Email)
+
+		changeSaved, err := emailChange.SaveEmailChange(server.DB)
+		if err != nil {
+			errList["Other_error"] = "Please try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+		_, err = mailer.SendMail.SendEmailChangeConfirmation(changeSaved.NewEmail, formerUser.Locale, changeSaved.ConfirmToken)
+		if err == nil {
+			_, err = mailer.SendMail.SendEmailChangeNotice(changeSaved.OldEmail, formerUser.Locale, changeSaved.RevertToken)
+		}
+		if err != nil {
+			_, deleteErr := changeSaved.DeleteEmailChange(server.DB)
+			if deleteErr != nil {
+				fmt.Println("cannot delete the email change: ", deleteErr)
+			}
+			errList["Cannot_send"] = "Cannot send the confirmation email, Pls try again later"
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status": http.StatusInternalServerError,
+				"error":  errList,
+			})
+			return
+		}
+		pendingEmail = changeSaved.NewEmail
+	}
+
+	newUser.Email = formerUser.Email
 	updatedUser, err := newUser.UpdateAUser(server.DB, uint32(uid))
 	if err != nil {
 		errList := formaterror.FormatError(err.Error())
@@ -335,13 +431,106 @@ func (server *Server) UpdateUser(c *gin.Context) {
 		})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":        http.StatusOK,
+		"response":      responses.NewUser(updatedUser),
+		"pending_email": pendingEmail,
+	})
+}
+
+// ConfirmEmail applies the email change the token of the link sent to the new address belongs to
+func (server *Server) ConfirmEmail(c *gin.Context) {
+	server.applyEmailChange(c, true)
+}
+
+// RevertEmail cancels the email change the token of the link sent to the old address belongs to
+func (server *Server) RevertEmail(c *gin.Context) {
+	server.applyEmailChange(c, false)
+}
+
+func (server *Server) applyEmailChange(c *gin.Context, confirm bool) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	requestBody := map[string]string{}
+	err = json.Unmarshal(body, &requestBody)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	emailChange := models.EmailChange{}
+	var user *models.User
+	if confirm {
+		user, err = emailChange.ConfirmEmailChange(server.DB, requestBody["token"])
+	} else {
+		user, err = emailChange.RevertEmailChange(server.DB, requestBody["token"])
+	}
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) || err == models.ErrEmailChangeExpired {
+			errList["Invalid_token"] = "Invalid link. Try requesting again"
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"status": http.StatusUnprocessableEntity,
+				"error":  errList,
+			})
+			return
+		}
+		if err == models.ErrEmailTaken && !confirm {
+			// The old address went to another account meanwhile, the user cannot get it back alone
+			errList["Taken_email"] = "Your previous email is now used by another account, Pls contact support"
+			c.JSON(http.StatusConflict, gin.H{
+				"status": http.StatusConflict,
+				"error":  errList,
+			})
+			return
+		}
+		if err == models.ErrEmailTaken {
+			errList["Taken_email"] = "Email Already Taken"
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"status": http.StatusUnprocessableEntity,
+				"error":  errList,
+			})
+			return
+		}
+		errList = formaterror.FormatError(err.Error())
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": updatedUser,
+		"response": responses.NewUser(user),
 	})
 }
 
+// DeleteUser asks for the deletion of the account. Once confirmed from the email, the account is hidden,
+// and when the grace period is over its posts and comments are given to the deleted user and the rest is removed
 func (server *Server) DeleteUser(c *gin.Context) {
+	server.requestAccountClosure(c, models.ClosureDelete)
+}
+
+// DeactivateUser asks for the deactivation of the account. Once confirmed from the email, the account is hidden
+// until the user logs in again
+func (server *Server) DeactivateUser(c *gin.Context) {
+	server.requestAccountClosure(c, models.ClosureDeactivate)
+}
+
+func (server *Server) requestAccountClosure(c *gin.Context, mode string) {
 
 	//clear previous error if any
 	errList = map[string]string{}
@@ -376,11 +565,10 @@ func (server *Server) DeleteUser(c *gin.Context) {
 		})
 		return
 	}
-
 	user := models.User{}
-	_, err = user.DeleteAUser(server.DB, uint32(uid))
+	err = server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&user).Error
 	if err != nil {
-		errList["Other_error"] = "Please try again later"
+		errList["No_user"] = "No User Found"
 		c.JSON(http.StatusNotFound, gin.H{
 			"status": http.StatusNotFound,
 			"error":  errList,
@@ -388,41 +576,336 @@ func (server *Server) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	// Also delete the posts, likes and the comments that this user created if any:
-	comment := models.Comment{}
-	like := models.Like{}
-	post := models.Post{}
+	closure := models.AccountClosure{}
+	closure.UserID = user.ID
+	closure.Mode = mode
+	closure.Token = security.TokenHash(user.Email)
+
+	closureSaved, err := closure.SaveAccountClosure(server.DB)
+	if err != nil {
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	_, err = mailer.SendMail.SendAccountClosureConfirmation(user.Email, user.Locale, closureSaved.Mode, closureSaved.Token)
+	if err != nil {
+		errList["Cannot_send"] = "Cannot send the confirmation email, Pls try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": "Please click on the link provided in your email to confirm",
+	})
+}
+
+// ConfirmAccountClosure closes the account of the request the token of the emailed link belongs to
+func (server *Server) ConfirmAccountClosure(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
 
-	_, err = post.DeleteUserPosts(server.DB, uint32(uid))
+	body, err := ioutil.ReadAll(c.Request.Body)
 	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	requestBody := map[string]string{}
+	err = json.Unmarshal(body, &requestBody)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	closure := models.AccountClosure{}
+	closureConfirmed, err := closure.ConfirmAccountClosure(server.DB, requestBody["token"])
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) || err == models.ErrAccountClosureExpired {
+			errList["Invalid_token"] = "Invalid link. Try requesting again"
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"status": http.StatusUnprocessableEntity,
+				"error":  errList,
+			})
+			return
+		}
 		errList["Other_error"] = "Please try again later"
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
-			"error":  err,
+			"error":  errList,
 		})
 		return
 	}
-	_, err = comment.DeleteUserComments(server.DB, uint32(uid))
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": closureConfirmed,
+	})
+}
+
+type profileRequest struct {
+	Bio          *string `json:"bio"`
+	ShowEmail    *bool   `json:"show_email"`
+	ShowActivity *bool   `json:"show_activity"`
+	Locale       *string `json:"locale"`
+}
+
+// GetUserProfile gives the public profile of a user. The route shares its wildcard with the
+// other user routes, so the username comes in the id parameter
+func (server *Server) GetUserProfile(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	username := c.Param("id")
+
+	// Logged in viewers may see more of the profile
+	var viewer *models.User
+	viewerID, err := auth.ExtractTokenID(c.Request)
+	if err == nil && viewerID != 0 {
+		user := models.User{}
+		err = server.DB.Debug().Model(models.User{}).Where("id = ?", viewerID).Take(&user).Error
+		if err == nil {
+			viewer = &user
+		}
+	}
+	user := models.User{}
+	profile, err := user.FindProfile(server.DB, username, viewer)
 	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			// The username may be one the user recently left
+			usernameChange := models.UsernameChange{}
+			renamedUser, err := usernameChange.FindUserByOldUsername(server.DB, username)
+			if err == nil {
+				location := "/api/v1/users/" + url.PathEscape(renamedUser.Username)
+				c.Redirect(http.StatusMovedPermanently, location)
+				return
+			}
+			errList["No_user"] = "No User Found"
+			c.JSON(http.StatusNotFound, gin.H{
+				"status": http.StatusNotFound,
+				"error":  errList,
+			})
+			return
+		}
 		errList["Other_error"] = "Please try again later"
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
-			"error":  err,
+			"error":  errList,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": profile,
+	})
+}
+
+// UpdateProfile changes the bio and what others can see on the profile, the fields left out keep their value
+func (server *Server) UpdateProfile(c *gin.Context) {
+
+	//clear previous error if any
+	errList = map[string]string{}
+
+	userID := c.Param("id")
+	// Check if the user id is valid
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	// Get user id from the token for valid tokens
+	tokenID, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// If the id is not the authenticated user id
+	if tokenID != 0 && tokenID != uint32(uid) {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	requestBody := profileRequest{}
+	err = json.Unmarshal(body, &requestBody)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	user := models.User{}
+	err = server.DB.Debug().Model(models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		errList["No_user"] = "No User Found"
+		c.JSON(http.StatusNotFound, gin.H{
+			"status": http.StatusNotFound,
+			"error":  errList,
 		})
 		return
 	}
-	_, err = like.DeleteUserLikes(server.DB, uint32(uid))
+	if requestBody.Bio != nil {
+		user.Bio = html.EscapeString(strings.TrimSpace(*requestBody.Bio))
+	}
+	if requestBody.ShowEmail != nil {
+		user.ShowEmail = *requestBody.ShowEmail
+	}
+	if requestBody.ShowActivity != nil {
+		user.ShowActivity = *requestBody.ShowActivity
+	}
+	if requestBody.Locale != nil {
+		user.Locale = strings.TrimSpace(*requestBody.Locale)
+	}
+	errorMessages := user.Validate("profile")
+	if len(errorMessages) > 0 {
+		errList = errorMessages
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	updatedUser, err := user.UpdateAUserProfile(server.DB, uint32(uid))
 	if err != nil {
 		errList["Other_error"] = "Please try again later"
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status": http.StatusInternalServerError,
-			"error":  err,
+			"error":  errList,
 		})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":   http.StatusOK,
+		"response": responses.NewUser(updatedUser),
+	})
+}
+
+// ChangeUsername renames the user. The old username keeps leading to the user for a while
+func (server *Server) ChangeUsername(c *gin.Context) {
 
+	//clear previous error if any
+	errList = map[string]string{}
+
+	userID := c.Param("id")
+	// Check if the user id is valid
+	uid, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		errList["Invalid_request"] = "Invalid Request"
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status": http.StatusBadRequest,
+			"error":  errList,
+		})
+		return
+	}
+	// Get user id from the token for valid tokens
+	tokenID, err := auth.ExtractTokenID(c.Request)
+	if err != nil {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	// If the id is not the authenticated user id
+	if tokenID != 0 && tokenID != uint32(uid) {
+		errList["Unauthorized"] = "Unauthorized"
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status": http.StatusUnauthorized,
+			"error":  errList,
+		})
+		return
+	}
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		errList["Invalid_body"] = "Unable to get request"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	requestBody := map[string]string{}
+	err = json.Unmarshal(body, &requestBody)
+	if err != nil {
+		errList["Unmarshal_error"] = "Cannot unmarshal body"
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	username := strings.TrimSpace(requestBody["username"])
+	errorMessages := models.ValidateUsername(username)
+	if len(errorMessages) > 0 {
+		errList = errorMessages
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status": http.StatusUnprocessableEntity,
+			"error":  errList,
+		})
+		return
+	}
+	user := models.User{}
+	updatedUser, err := user.ChangeUsername(server.DB, uint32(uid), username)
+	if err != nil {
+		if err == models.ErrUsernameCooldown {
+			errList["Username_cooldown"] = "You can only change your username once every 30 days"
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"status": http.StatusTooManyRequests,
+				"error":  errList,
+			})
+			return
+		}
+		if err == models.ErrUsernameTaken {
+			errList["Taken_username"] = "Username Already Taken"
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"status": http.StatusUnprocessableEntity,
+				"error":  errList,
+			})
+			return
+		}
+		errList["Other_error"] = "Please try again later"
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status": http.StatusInternalServerError,
+			"error":  errList,
+		})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"status":   http.StatusOK,
-		"response": "User deleted",
+		"response": responses.NewUser(updatedUser),
 	})
 }
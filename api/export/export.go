@@ -0,0 +1,119 @@
+// Package export builds the archive a user downloads to get all of their data
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+)
+
+// exportedAttachment is an attachment with the place of its file in the archive
+type exportedAttachment struct {
+	models.Attachment
+	ArchivePath string `json:"archive_path"`
+}
+
+// BuildArchive gathers the profile, posts, comments and likes of the user into JSON files,
+// and the files they uploaded as they were, into a ZIP. Deleted posts and comments are in too
+func BuildArchive(db *gorm.DB, uid uint32) ([]byte, error) {
+	user := models.User{}
+	err := db.Debug().Model(&models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	// The avatar path is turned into a url when the user is read, the stored one is needed here
+	var avatarPaths []string
+	err = db.Debug().Model(&models.User{}).Where("id = ?", uid).Pluck("avatar_path", &avatarPaths).Error
+	if err != nil {
+		return nil, err
+	}
+	posts := []models.Post{}
+	err = db.Debug().Unscoped().Model(&models.Post{}).Where("author_id = ?", uid).Order("id asc").Find(&posts).Error
+	if err != nil {
+		return nil, err
+	}
+	comments := []models.Comment{}
+	err = db.Debug().Unscoped().Model(&models.Comment{}).Where("user_id = ?", uid).Order("id asc").Find(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+	likes := []models.Like{}
+	err = db.Debug().Model(&models.Like{}).Where("user_id = ?", uid).Order("id asc").Find(&likes).Error
+	if err != nil {
+		return nil, err
+	}
+	attachments := []models.Attachment{}
+	err = db.Debug().Model(&models.Attachment{}).Where("user_id = ?", uid).Order("id asc").Find(&attachments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	archive := zip.NewWriter(buffer)
+
+	exported := make([]exportedAttachment, len(attachments))
+	for i, attachment := range attachments {
+		exported[i] = exportedAttachment{attachment, fmt.Sprintf("attachments/%d-%s", attachment.ID, path.Base(attachment.FileName))}
+		err = addStoredFile(archive, exported[i].ArchivePath, attachment.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(avatarPaths) > 0 && avatarPaths[0] != "" {
+		err = addStoredFile(archive, "avatar/"+path.Base(avatarPaths[0]), avatarPaths[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", responses.NewUser(&user)},
+		{"posts.json", responses.NewPosts(posts)},
+		{"comments.json", responses.NewComments(comments)},
+		{"likes.json", likes},
+		{"attachments.json", exported},
+	}
+	for _, file := range files {
+		err = addJSON(archive, file.name, file.content)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func addJSON(archive *zip.Writer, name string, content interface{}) error {
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(content)
+}
+
+func addStoredFile(archive *zip.Writer, name string, storedPath string) error {
+	content, err := fileupload.Store.Get(storedPath)
+	if err != nil {
+		return err
+	}
+	file, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	return err
+}
@@ -0,0 +1,156 @@
+package fileupload
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/twinj/uuid"
+)
+
+// The content types that can be attached to posts and comments, with the largest size allowed for each
+var attachmentLimits = map[string]int64{
+	"image/jpeg":                5 << 20,
+	"image/png":                 5 << 20,
+	"image/gif":                 5 << 20,
+	"application/pdf":           10 << 20,
+	"text/plain; charset=utf-8": 1 << 20,
+}
+
+// The extension of a stored attachment comes from its sniffed content type, never from the name the client gave.
+// A text file named x.html is stored as .txt, so it is not served as a page
+var attachmentExtensions = map[string]string{
+	"image/jpeg":                ".jpg",
+	"image/png":                 ".png",
+	"image/gif":                 ".gif",
+	"application/pdf":           ".pdf",
+	"text/plain; charset=utf-8": ".txt",
+}
+
+// MaxAttachmentSize is the largest of the limits, nothing bigger is read
+const MaxAttachmentSize = 10 << 20
+
+// Images with more pixels are refused before being decoded, a small file can hold a huge image
+const maxAttachmentPixels = 25000000
+
+// UploadedFile is what is known of a file once it is stored
+type UploadedFile struct {
+	Path        string
+	FileName    string
+	ContentType string
+	Size        int64
+}
+
+// AttachmentFile is a file that was read and checked, but not stored yet
+type AttachmentFile struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// ReadAttachment reads the file and checks it. Nothing is stored, so the files of a request can all be checked first
+func (fu *fileUpload) ReadAttachment(file *multipart.FileHeader) (*AttachmentFile, map[string]string) {
+
+	errList := map[string]string{}
+
+	f, err := file.Open()
+	if err != nil {
+		errList["Invalid_file"] = "Invalid File"
+		return nil, errList
+	}
+	defer f.Close()
+
+	// The size sent by the client is not trusted, the file is read up to one byte past the limit
+	content, err := ioutil.ReadAll(io.LimitReader(f, MaxAttachmentSize+1))
+	if err != nil {
+		errList["Invalid_file"] = "Invalid File"
+		return nil, errList
+	}
+	fileType, errList := CheckAttachment(content)
+	if errList != nil {
+		return nil, errList
+	}
+	return &AttachmentFile{
+		FileName:    file.Filename,
+		ContentType: fileType,
+		Content:     content,
+	}, nil
+}
+
+// StoreAttachment puts the checked file in the storage
+func (fu *fileUpload) StoreAttachment(attachmentFile *AttachmentFile) (*UploadedFile, error) {
+	filePath := uuid.NewV4().String() + attachmentExtensions[attachmentFile.ContentType]
+	err := Store.Put(filePath, attachmentFile.Content, attachmentFile.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	return &UploadedFile{
+		Path:        filePath,
+		FileName:    attachmentFile.FileName,
+		ContentType: attachmentFile.ContentType,
+		Size:        int64(len(attachmentFile.Content)),
+	}, nil
+}
+
+// CheckAttachment sniffs the content type of the file and checks the whole of it is what it claims to be
+func CheckAttachment(content []byte) (string, map[string]string) {
+
+	errList := map[string]string{}
+
+	if len(content) == 0 {
+		errList["Invalid_file"] = "Invalid File"
+		return "", errList
+	}
+	fileType := http.DetectContentType(content)
+	limit, ok := attachmentLimits[fileType]
+	if !ok {
+		errList["Invalid_type"] = "Please upload an image, a PDF or a text file"
+		return "", errList
+	}
+	if int64(len(content)) > limit {
+		errList["Too_large"] = fmt.Sprintf("Sorry, this file should be %dMB or less", limit>>20)
+		return "", errList
+	}
+	switch fileType {
+	case "image/jpeg", "image/png", "image/gif":
+		config, _, err := image.DecodeConfig(bytes.NewReader(content))
+		if err != nil {
+			errList["Invalid_file"] = "Please upload a valid image"
+			return "", errList
+		}
+		if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxAttachmentPixels {
+			errList["Too_large"] = "Sorry, this image has too many pixels"
+			return "", errList
+		}
+		// Decoding goes through every pixel, so a file that only starts like an image is refused
+		_, _, err = image.Decode(bytes.NewReader(content))
+		if err != nil {
+			errList["Invalid_file"] = "Please upload a valid image"
+			return "", errList
+		}
+	case "application/pdf":
+		// A PDF ends with its trailer, a truncated or padded file does not
+		tail := content
+		if len(tail) > 1024 {
+			tail = tail[len(tail)-1024:]
+		}
+		if !bytes.Contains(tail, []byte("%%EOF")) {
+			errList["Invalid_file"] = "Please upload a valid PDF"
+			return "", errList
+		}
+	default:
+		if !utf8.Valid(content) {
+			errList["Invalid_file"] = "Please upload a valid text file"
+			return "", errList
+		}
+	}
+	return fileType, nil
+}
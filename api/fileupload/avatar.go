@@ -0,0 +1,145 @@
+package fileupload
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"strconv"
+	"strings"
+)
+
+// AvatarSizes are the sizes in pixels an avatar is stored in, the largest one last
+var AvatarSizes = []int{48, 128, 512}
+
+// Larger images are refused before being decoded, a small file can hold a huge image
+const maxAvatarPixels = 25000000
+
+// ProcessAvatar decodes the image, crops the center square and renders it in every avatar size as PNG.
+// Nothing of the original file is kept, so its metadata like the EXIF location goes away
+func ProcessAvatar(content []byte) (map[int][]byte, map[string]string) {
+
+	errList := map[string]string{}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		errList["Not_Image"] = "Please Upload a valid image"
+		return nil, errList
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxAvatarPixels {
+		errList["Too_large"] = "Sorry, this image has too many pixels"
+		return nil, errList
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		errList["Not_Image"] = "Please Upload a valid image"
+		return nil, errList
+	}
+
+	// The center square, copied once so the resizing works on plain pixels
+	bounds := img.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	origin := image.Pt(bounds.Min.X+(bounds.Dx()-side)/2, bounds.Min.Y+(bounds.Dy()-side)/2)
+	square := image.NewNRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, origin, draw.Src)
+
+	// Each size is made from the next larger one, which keeps the work small
+	avatars := map[int][]byte{}
+	current := square
+	for i := len(AvatarSizes) - 1; i >= 0; i-- {
+		current = resize(current, AvatarSizes[i])
+		var buffer bytes.Buffer
+		err = png.Encode(&buffer, current)
+		if err != nil {
+			errList["Other_Err"] = "something went wrong"
+			return nil, errList
+		}
+		avatars[AvatarSizes[i]] = buffer.Bytes()
+	}
+	return avatars, nil
+}
+
+// resize scales a square image to size x size, averaging the pixels that fall in each target pixel.
+// A smaller image is scaled up by repeating its pixels
+func resize(src *image.NRGBA, size int) *image.NRGBA {
+	side := src.Bounds().Dx()
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for dy := 0; dy < size; dy++ {
+		y0, y1 := dy*side/size, (dy+1)*side/size
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for dx := 0; dx < size; dx++ {
+			x0, x1 := dx*side/size, (dx+1)*side/size
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, count int
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					i := y*src.Stride + x*4
+					// Colors are weighted by their alpha, so transparent pixels do not darken the edges
+					alpha := int(src.Pix[i+3])
+					r += int(src.Pix[i]) * alpha
+					g += int(src.Pix[i+1]) * alpha
+					b += int(src.Pix[i+2]) * alpha
+					a += alpha
+					count++
+				}
+			}
+			j := dy*dst.Stride + dx*4
+			if a > 0 {
+				dst.Pix[j] = uint8(r / a)
+				dst.Pix[j+1] = uint8(g / a)
+				dst.Pix[j+2] = uint8(b / a)
+			}
+			dst.Pix[j+3] = uint8(a / count)
+		}
+	}
+	return dst
+}
+
+// The avatars of a user are stored as avatars/<name>-<size>.png, the path saved on the user is the one of the largest size
+func avatarPath(name string, size int) string {
+	return fmt.Sprintf("avatars/%s-%d.png", name, size)
+}
+
+// sizedAvatarPath gives the path of the avatar in the size.
+// Avatars uploaded before they were resized only have the original, which is given for every size
+func sizedAvatarPath(filePath string, size int) string {
+	largest := strconv.Itoa(AvatarSizes[len(AvatarSizes)-1])
+	if strings.HasPrefix(filePath, "avatars/") && strings.HasSuffix(filePath, "-"+largest+".png") {
+		return strings.TrimSuffix(filePath, largest+".png") + strconv.Itoa(size) + ".png"
+	}
+	return filePath
+}
+
+// AvatarURLs gives the address of the avatar in every size
+func AvatarURLs(filePath string) map[string]string {
+	urls := map[string]string{}
+	for _, size := range AvatarSizes {
+		urls[strconv.Itoa(size)] = URL(sizedAvatarPath(filePath, size))
+	}
+	return urls
+}
+
+// DeleteAvatar deletes the avatar in every size
+func DeleteAvatar(filePath string) error {
+	deleted := map[string]bool{}
+	for _, size := range AvatarSizes {
+		sizePath := sizedAvatarPath(filePath, size)
+		if deleted[sizePath] {
+			continue
+		}
+		err := Store.Delete(sizePath)
+		if err != nil {
+			return err
+		}
+		deleted[sizePath] = true
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package fileupload
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Storage keeps the uploaded files. Paths are relative to the storage, URL gives the public address of one
+type Storage interface {
+	Put(filePath string, content []byte, contentType string) error
+	Get(filePath string) ([]byte, error)
+	Delete(filePath string) error
+	URL(filePath string) string
+}
+
+// The storage drivers that can be picked with STORAGE_DRIVER
+const (
+	DriverLocal  = "local"
+	DriverS3     = "s3"
+	DriverMemory = "memory"
+)
+
+// Store is where the uploaded files go, it is set up once with Configure
+var Store Storage
+
+// PrivateStore keeps the files only the API gives out, like the data exports. Nothing in it is public:
+// Configure sets it up apart from Store, in a directory that is not served or a bucket of its own
+var PrivateStore Storage
+
+// Configure sets up the storage of the given driver from the environment.
+// S3 is used when no driver is given, as the files were kept on DigitalOcean Spaces before drivers existed
+func Configure(driver string) error {
+	switch driver {
+	case DriverLocal:
+		baseURL := os.Getenv("STORAGE_URL")
+		if baseURL == "" {
+			baseURL = "/uploads/"
+		}
+		store, err := NewLocalStorage(LocalDir(), baseURL)
+		if err != nil {
+			return err
+		}
+		Store = store
+		PrivateStore, err = NewLocalStorage(PrivateDir(), "")
+		if err != nil {
+			return err
+		}
+	case DriverS3, "":
+		bucket := os.Getenv("STORAGE_BUCKET")
+		if bucket == "" {
+			bucket = "chodapi"
+		}
+		store, err := NewS3Storage(os.Getenv("DO_SPACES_ENDPOINT"), os.Getenv("DO_SPACES_KEY"), os.Getenv("DO_SPACES_SECRET"), bucket, os.Getenv("DO_SPACES_URL"))
+		if err != nil {
+			return err
+		}
+		Store = store
+		// The private files are uploaded without a public ACL, a bucket of their own keeps them private
+		// even when the policy of the public bucket makes everything readable. Without one, nothing starts
+		privateBucket := os.Getenv("STORAGE_PRIVATE_BUCKET")
+		if privateBucket == "" || privateBucket == bucket {
+			return errors.New("STORAGE_PRIVATE_BUCKET should name a private bucket other than STORAGE_BUCKET")
+		}
+		PrivateStore, err = NewPrivateS3Storage(os.Getenv("DO_SPACES_ENDPOINT"), os.Getenv("DO_SPACES_KEY"), os.Getenv("DO_SPACES_SECRET"), privateBucket)
+		if err != nil {
+			return err
+		}
+	case DriverMemory:
+		Store = NewMemoryStorage(os.Getenv("STORAGE_URL"))
+		PrivateStore = NewMemoryStorage("")
+	default:
+		return fmt.Errorf("unknown storage driver %q", driver)
+	}
+	return nil
+}
+
+// LocalDir is the directory of the local storage, which the API serves under /uploads
+func LocalDir() string {
+	dir := os.Getenv("STORAGE_LOCAL_DIR")
+	if dir == "" {
+		dir = "uploads"
+	}
+	return dir
+}
+
+// PrivateDir is the directory of the local private storage. It must not be served, so it is not under LocalDir
+func PrivateDir() string {
+	dir := os.Getenv("STORAGE_PRIVATE_DIR")
+	if dir == "" {
+		dir = "private"
+	}
+	return dir
+}
+
+// ContentType gives the content type a stored file is served with. The API picks the extensions of what it stores,
+// so a file with any other extension is served as plain bytes
+func ContentType(filePath string) string {
+	ext := strings.ToLower(path.Ext(filePath))
+	for contentType, attachmentExt := range attachmentExtensions {
+		if attachmentExt == ext {
+			return contentType
+		}
+	}
+	// Avatars uploaded before they were resized kept the extension of the original image
+	if ext == ".jpeg" {
+		return "image/jpeg"
+	}
+	return "application/octet-stream"
+}
+
+// URL gives the public address of a stored file, or the path itself when no storage is set up
+func URL(filePath string) string {
+	if Store == nil {
+		return filePath
+	}
+	return Store.URL(filePath)
+}
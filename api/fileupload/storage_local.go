@@ -0,0 +1,67 @@
+package fileupload
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localStorage keeps the files in a directory of the server, which the API serves itself
+type localStorage struct {
+	dir     string
+	baseURL string
+}
+
+func NewLocalStorage(dir, baseURL string) (Storage, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &localStorage{dir: dir, baseURL: baseURL}, nil
+}
+
+// fullPath refuses paths that would get out of the storage directory
+func (ls *localStorage) fullPath(filePath string) (string, error) {
+	if strings.Contains(filePath, "..") {
+		return "", errors.New("invalid file path")
+	}
+	return filepath.Join(ls.dir, filepath.Clean("/"+filePath)), nil
+}
+
+func (ls *localStorage) Put(filePath string, content []byte, contentType string) error {
+	fullPath, err := ls.fullPath(filePath)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(fullPath), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fullPath, content, 0644)
+}
+
+func (ls *localStorage) Get(filePath string) ([]byte, error) {
+	fullPath, err := ls.fullPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(fullPath)
+}
+
+func (ls *localStorage) Delete(filePath string) error {
+	fullPath, err := ls.fullPath(filePath)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(fullPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (ls *localStorage) URL(filePath string) string {
+	return ls.baseURL + filePath
+}
@@ -0,0 +1,47 @@
+package fileupload
+
+import (
+	"os"
+	"sync"
+)
+
+// memoryStorage keeps the files in memory, for the tests
+type memoryStorage struct {
+	mu      sync.RWMutex
+	files   map[string][]byte
+	baseURL string
+}
+
+func NewMemoryStorage(baseURL string) Storage {
+	return &memoryStorage{files: map[string][]byte{}, baseURL: baseURL}
+}
+
+func (ms *memoryStorage) Put(filePath string, content []byte, contentType string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	stored := make([]byte, len(content))
+	copy(stored, content)
+	ms.files[filePath] = stored
+	return nil
+}
+
+func (ms *memoryStorage) Get(filePath string) ([]byte, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	content, ok := ms.files[filePath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return content, nil
+}
+
+func (ms *memoryStorage) Delete(filePath string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.files, filePath)
+	return nil
+}
+
+func (ms *memoryStorage) URL(filePath string) string {
+	return ms.baseURL + filePath
+}
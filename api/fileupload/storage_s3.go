@@ -0,0 +1,62 @@
+package fileupload
+
+import (
+	"bytes"
+	"io/ioutil"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// s3Storage keeps the files in a bucket of an S3 compatible service, like DigitalOcean Spaces
+type s3Storage struct {
+	client  *minio.Client
+	bucket  string
+	baseURL string
+	public  bool
+}
+
+func NewS3Storage(endpoint, accessKey, secretKey, bucket, baseURL string) (Storage, error) {
+	client, err := minio.New(endpoint, accessKey, secretKey, true)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Storage{client: client, bucket: bucket, baseURL: baseURL, public: true}, nil
+}
+
+// NewPrivateS3Storage gives a storage whose files can only be read with the keys, and are never cached
+func NewPrivateS3Storage(endpoint, accessKey, secretKey, bucket string) (Storage, error) {
+	client, err := minio.New(endpoint, accessKey, secretKey, true)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Storage{client: client, bucket: bucket}, nil
+}
+
+func (ss *s3Storage) Put(filePath string, content []byte, contentType string) error {
+	cacheControl := "private, no-store"
+	userMetaData := map[string]string{"x-amz-acl": "private"}
+	if ss.public {
+		cacheControl = "max-age=31536000"
+		// make it public
+		userMetaData = map[string]string{"x-amz-acl": "public-read"}
+	}
+	_, err := ss.client.PutObject(ss.bucket, filePath, bytes.NewReader(content), int64(len(content)), minio.PutObjectOptions{ContentType: contentType, CacheControl: cacheControl, UserMetadata: userMetaData})
+	return err
+}
+
+func (ss *s3Storage) Get(filePath string) ([]byte, error) {
+	object, err := ss.client.GetObject(ss.bucket, filePath, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+	return ioutil.ReadAll(object)
+}
+
+func (ss *s3Storage) Delete(filePath string) error {
+	return ss.client.RemoveObject(ss.bucket, filePath)
+}
+
+func (ss *s3Storage) URL(filePath string) string {
+	return ss.baseURL + filePath
+}
@@ -0,0 +1,6 @@
+package mailer
+
+// SendAccountClosureConfirmation sends the link that confirms the deactivation or the deletion of the account
+func (s *sendMail) SendAccountClosureConfirmation(ToUser string, Locale string, Mode string, Token string) (*EmailResponse, error) {
+	return sendEmail(TemplateAccountClosure, ToUser, Locale, Data{"Mode": Mode, "Token": Token}, nil)
+}
@@ -0,0 +1,39 @@
+package mailer
+
+import (
+	"os"
+	"strings"
+)
+
+// Brand is what the emails show of the product, set from the environment
+type Brand struct {
+	Name string
+	URL  string
+	Logo string
+	// FrontendURL is the address of the frontend app the links of the emails lead to
+	FrontendURL string
+}
+
+// CurrentBrand reads the brand from MAIL_PRODUCT_NAME, MAIL_PRODUCT_URL, MAIL_LOGO_URL and FRONTEND_URL.
+// Without FRONTEND_URL, APP_ENV picks the production or the local frontend, as it did before the setting existed
+func CurrentBrand() Brand {
+	brand := Brand{
+		Name:        os.Getenv("MAIL_PRODUCT_NAME"),
+		URL:         os.Getenv("MAIL_PRODUCT_URL"),
+		Logo:        os.Getenv("MAIL_LOGO_URL"),
+		FrontendURL: strings.TrimRight(os.Getenv("FRONTEND_URL"), "/"),
+	}
+	if brand.Name == "" {
+		brand.Name = "SeamFlow"
+	}
+	if brand.URL == "" {
+		brand.URL = "https://seamflow.com"
+	}
+	if brand.FrontendURL == "" {
+		brand.FrontendURL = "http://127.0.0.1:3000"
+		if os.Getenv("APP_ENV") == "production" {
+			brand.FrontendURL = "https://seamflow.com"
+		}
+	}
+	return brand
+}
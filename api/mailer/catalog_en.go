@@ -0,0 +1,98 @@
+package mailer
+
+var catalogEN = Catalog{
+	Emails: map[string]Copy{
+		TemplateReset: {
+			Subject: "Reset Password",
+			Intros:  []string{"Welcome to {{.Product}}! Good to have you here."},
+			Actions: []Action{
+				{Instructions: "Click this link to reset your password", Button: "Reset Password", Link: "{{.FrontendURL}}/resetpassword/{{.Token}}"},
+			},
+			Outros: []string{"Need help, or have questions? Just reply to this email, we'd love to help."},
+		},
+		TemplateVerify: {
+			Subject: "Confirm your new email address",
+			Intros:  []string{"You asked to use this address for your {{.Product}} account."},
+			Actions: []Action{
+				{Instructions: "Click this link to confirm your new email address, it can be used for 24 hours", Button: "Confirm Email", Link: "{{.FrontendURL}}/email/confirm/{{.Token}}"},
+			},
+			Outros: []string{"If you did not ask for this, you can ignore this email."},
+		},
+		TemplateEmailChangeNotice: {
+			Subject: "Your email address is being changed",
+			Intros:  []string{"Someone asked to change the email address of your {{.Product}} account."},
+			Actions: []Action{
+				{Instructions: "If it was not you, click this link within 7 days to keep this address", Button: "Keep This Address", Link: "{{.FrontendURL}}/email/revert/{{.Token}}"},
+			},
+			Outros: []string{"If it was you, there is nothing to do."},
+		},
+		TemplateWelcome: {
+			Subject: "Welcome to {{.Product}}",
+			Intros:  []string{"Welcome to {{.Product}}, {{.Username}}! Good to have you here."},
+			Actions: []Action{
+				{Instructions: "Click this link to write your first post", Button: "Get Started", Link: "{{.FrontendURL}}/"},
+			},
+			Outros: []string{"Need help, or have questions? Just reply to this email, we'd love to help."},
+		},
+		TemplateLockout: {
+			Subject: "Your account is locked",
+			Intros:  []string{"Your {{.Product}} account was locked after too many failed logins. You can try again in {{.Minutes}} minutes."},
+			Actions: []Action{
+				{Instructions: "If it was not you, click this link to change your password", Button: "Reset Password", Link: "{{.FrontendURL}}/password/forgot"},
+			},
+		},
+		TemplateDataExport: {
+			Subject: "Your data is ready to download",
+			Intros:  []string{"The archive of your {{.Product}} data is ready."},
+			Actions: []Action{
+				{Instructions: "Click this link to download it, it can be used for 7 days", Button: "Download Your Data", Link: "{{.Link}}"},
+			},
+			Outros: []string{"If you did not ask for your data, please change your password."},
+		},
+		TemplateAccountClosure: {
+			Subject: `{{if eq .Mode "delete"}}Confirm the deletion of your account{{else}}Confirm the deactivation of your account{{end}}`,
+			Intros: []string{
+				`{{if eq .Mode "delete"}}You asked to delete your {{.Product}} account. Your posts and comments will stay, under a deleted user, and everything else will be removed.` +
+					`{{else}}You asked to deactivate your {{.Product}} account. Your profile will be hidden until you log in again.{{end}}`,
+			},
+			Actions: []Action{
+				{Instructions: "Click this link to confirm, it can be used for 24 hours", Button: `{{if eq .Mode "delete"}}Delete Account{{else}}Deactivate Account{{end}}`, Link: "{{.FrontendURL}}/account/close/{{.Token}}"},
+			},
+			Outros: []string{
+				"You have 30 days to change your mind: logging in again cancels it.",
+				"If you did not ask for this, you can ignore this email.",
+			},
+		},
+		TemplateNotification: {
+			Subject: "{{.Text}}",
+			Intros:  []string{"{{.Text}}."},
+			Actions: []Action{
+				{Instructions: "Click this link to see it on {{.Product}}", Button: "See It", Link: "{{.FrontendURL}}/{{.Path}}"},
+				{Instructions: "To stop getting an email about these, click this link", Button: "Unsubscribe", Link: "{{.UnsubscribeURL}}"},
+			},
+		},
+		TemplateDigest: {
+			Subject:      `{{if eq .Frequency "weekly"}}Your weekly {{.Product}} digest{{else}}Your daily {{.Product}} digest{{end}}`,
+			Intros:       []string{`Here is what happened on {{.Product}} {{if eq .Frequency "weekly"}}this week{{else}}today{{end}}.`},
+			ItemsHeading: "Notification",
+			Actions: []Action{
+				{Instructions: "Click this link to see your notifications", Button: "See Notifications", Link: "{{.FrontendURL}}/notifications"},
+				{Instructions: "To stop getting emails about your notifications, click this link", Button: "Unsubscribe", Link: "{{.UnsubscribeURL}}"},
+			},
+		},
+	},
+	Notifications: map[string]string{
+		"like":    "{{.Who}} liked your post",
+		"comment": "{{.Who}} commented on your post",
+		"reply":   "{{.Who}} replied to a post you commented on",
+		"mention": "{{.Who}} mentioned you",
+		"follow":  "{{.Who}} followed you",
+	},
+	One:         "{{.Actor}}",
+	Two:         "{{.Actor}} and 1 other",
+	Many:        "{{.Actor}} and {{.Others}} others",
+	Greeting:    "Hi",
+	Signature:   "Yours truly",
+	TroubleText: "If you’re having trouble with the button '{ACTION}', copy and paste the URL below into your web browser.",
+	Copyright:   "Copyright © {{.Year}} {{.Product}}. All rights reserved.",
+}
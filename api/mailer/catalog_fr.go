@@ -0,0 +1,98 @@
+package mailer
+
+var catalogFR = Catalog{
+	Emails: map[string]Copy{
+		TemplateReset: {
+			Subject: "Réinitialisation du mot de passe",
+			Intros:  []string{"Bienvenue sur {{.Product}} ! Ravis de vous compter parmi nous."},
+			Actions: []Action{
+				{Instructions: "Cliquez sur ce lien pour réinitialiser votre mot de passe", Button: "Réinitialiser le mot de passe", Link: "{{.FrontendURL}}/resetpassword/{{.Token}}"},
+			},
+			Outros: []string{"Besoin d'aide, ou une question ? Répondez simplement à cet email, nous serons ravis de vous aider."},
+		},
+		TemplateVerify: {
+			Subject: "Confirmez votre nouvelle adresse email",
+			Intros:  []string{"Vous avez demandé à utiliser cette adresse pour votre compte {{.Product}}."},
+			Actions: []Action{
+				{Instructions: "Cliquez sur ce lien pour confirmer votre nouvelle adresse, il est valable 24 heures", Button: "Confirmer l'adresse", Link: "{{.FrontendURL}}/email/confirm/{{.Token}}"},
+			},
+			Outros: []string{"Si vous n'êtes pas à l'origine de cette demande, vous pouvez ignorer cet email."},
+		},
+		TemplateEmailChangeNotice: {
+			Subject: "Votre adresse email va être modifiée",
+			Intros:  []string{"Quelqu'un a demandé à modifier l'adresse email de votre compte {{.Product}}."},
+			Actions: []Action{
+				{Instructions: "Si ce n'était pas vous, cliquez sur ce lien dans les 7 jours pour garder cette adresse", Button: "Garder cette adresse", Link: "{{.FrontendURL}}/email/revert/{{.Token}}"},
+			},
+			Outros: []string{"Si c'était vous, il n'y a rien à faire."},
+		},
+		TemplateWelcome: {
+			Subject: "Bienvenue sur {{.Product}}",
+			Intros:  []string{"Bienvenue sur {{.Product}}, {{.Username}} ! Ravis de vous compter parmi nous."},
+			Actions: []Action{
+				{Instructions: "Cliquez sur ce lien pour écrire votre premier post", Button: "Commencer", Link: "{{.FrontendURL}}/"},
+			},
+			Outros: []string{"Besoin d'aide, ou une question ? Répondez simplement à cet email, nous serons ravis de vous aider."},
+		},
+		TemplateLockout: {
+			Subject: "Votre compte est bloqué",
+			Intros:  []string{"Votre compte {{.Product}} a été bloqué après trop de tentatives de connexion. Vous pourrez réessayer dans {{.Minutes}} minutes."},
+			Actions: []Action{
+				{Instructions: "Si ce n'était pas vous, cliquez sur ce lien pour changer votre mot de passe", Button: "Réinitialiser le mot de passe", Link: "{{.FrontendURL}}/password/forgot"},
+			},
+		},
+		TemplateDataExport: {
+			Subject: "Vos données sont prêtes à être téléchargées",
+			Intros:  []string{"L'archive de vos données {{.Product}} est prête."},
+			Actions: []Action{
+				{Instructions: "Cliquez sur ce lien pour la télécharger, il est valable 7 jours", Button: "Télécharger vos données", Link: "{{.Link}}"},
+			},
+			Outros: []string{"Si vous n'avez pas demandé vos données, changez votre mot de passe."},
+		},
+		TemplateAccountClosure: {
+			Subject: `{{if eq .Mode "delete"}}Confirmez la suppression de votre compte{{else}}Confirmez la désactivation de votre compte{{end}}`,
+			Intros: []string{
+				`{{if eq .Mode "delete"}}Vous avez demandé à supprimer votre compte {{.Product}}. Vos posts et commentaires resteront, au nom d'un utilisateur supprimé, et tout le reste sera effacé.` +
+					`{{else}}Vous avez demandé à désactiver votre compte {{.Product}}. Votre profil sera masqué jusqu'à votre prochaine connexion.{{end}}`,
+			},
+			Actions: []Action{
+				{Instructions: "Cliquez sur ce lien pour confirmer, il est valable 24 heures", Button: `{{if eq .Mode "delete"}}Supprimer le compte{{else}}Désactiver le compte{{end}}`, Link: "{{.FrontendURL}}/account/close/{{.Token}}"},
+			},
+			Outros: []string{
+				"Vous avez 30 jours pour changer d'avis : il suffit de vous reconnecter pour annuler.",
+				"Si vous n'êtes pas à l'origine de cette demande, vous pouvez ignorer cet email.",
+			},
+		},
+		TemplateNotification: {
+			Subject: "{{.Text}}",
+			Intros:  []string{"{{.Text}}."},
+			Actions: []Action{
+				{Instructions: "Cliquez sur ce lien pour le voir sur {{.Product}}", Button: "Voir", Link: "{{.FrontendURL}}/{{.Path}}"},
+				{Instructions: "Pour ne plus recevoir d'email à ce sujet, cliquez sur ce lien", Button: "Se désabonner", Link: "{{.UnsubscribeURL}}"},
+			},
+		},
+		TemplateDigest: {
+			Subject:      `{{if eq .Frequency "weekly"}}Votre résumé {{.Product}} de la semaine{{else}}Votre résumé {{.Product}} du jour{{end}}`,
+			Intros:       []string{`Voici ce qui s'est passé sur {{.Product}} {{if eq .Frequency "weekly"}}cette semaine{{else}}aujourd'hui{{end}}.`},
+			ItemsHeading: "Notification",
+			Actions: []Action{
+				{Instructions: "Cliquez sur ce lien pour voir vos notifications", Button: "Voir les notifications", Link: "{{.FrontendURL}}/notifications"},
+				{Instructions: "Pour ne plus recevoir d'emails sur vos notifications, cliquez sur ce lien", Button: "Se désabonner", Link: "{{.UnsubscribeURL}}"},
+			},
+		},
+	},
+	Notifications: map[string]string{
+		"like":    "{{.Who}} {{if gt .Count 1}}ont aimé{{else}}a aimé{{end}} votre post",
+		"comment": "{{.Who}} {{if gt .Count 1}}ont commenté{{else}}a commenté{{end}} votre post",
+		"reply":   "{{.Who}} {{if gt .Count 1}}ont répondu{{else}}a répondu{{end}} à un post que vous avez commenté",
+		"mention": "{{.Who}} {{if gt .Count 1}}vous ont mentionné{{else}}vous a mentionné{{end}}",
+		"follow":  "{{.Who}} {{if gt .Count 1}}vous suivent{{else}}vous suit{{end}}",
+	},
+	One:         "{{.Actor}}",
+	Two:         "{{.Actor}} et 1 autre personne",
+	Many:        "{{.Actor}} et {{.Others}} autres personnes",
+	Greeting:    "Bonjour",
+	Signature:   "Bien à vous",
+	TroubleText: "Si le bouton '{ACTION}' ne fonctionne pas, copiez l'adresse ci-dessous dans votre navigateur.",
+	Copyright:   "Copyright © {{.Year}} {{.Product}}. Tous droits réservés.",
+}
@@ -0,0 +1,6 @@
+package mailer
+
+// SendDataExportReady sends the link to download the archive of the user's data
+func (s *sendMail) SendDataExportReady(ToUser string, Locale string, Link string) (*EmailResponse, error) {
+	return sendEmail(TemplateDataExport, ToUser, Locale, Data{"Link": Link}, nil)
+}
@@ -0,0 +1,11 @@
+package mailer
+
+// SendEmailChangeConfirmation sends the link that verifies the new address
+func (s *sendMail) SendEmailChangeConfirmation(ToUser string, Locale string, Token string) (*EmailResponse, error) {
+	return sendEmail(TemplateVerify, ToUser, Locale, Data{"Token": Token}, nil)
+}
+
+// SendEmailChangeNotice tells the old address about the change, with the link that reverts it
+func (s *sendMail) SendEmailChangeNotice(ToUser string, Locale string, Token string) (*EmailResponse, error) {
+	return sendEmail(TemplateEmailChangeNotice, ToUser, Locale, Data{"Token": Token}, nil)
+}
@@ -2,71 +2,57 @@ package mailer
 
 import (
 	"net/http"
-	"os"
-
-	"github.com/matcornic/hermes/v2"
-	"github.com/sendgrid/sendgrid-go"
-	"github.com/sendgrid/sendgrid-go/helpers/mail"
 )
 
-type sendMail struct {}
+type sendMail struct{}
 
+// SendMailer sends the emails of the registry, in the locale of the user, with the configured Sender
 type SendMailer interface {
-	SendResetPassword(string, string, string, string, string)  (*EmailResponse, error)
+	SendResetPassword(string, string, string) (*EmailResponse, error)
+	SendEmailChangeConfirmation(string, string, string) (*EmailResponse, error)
+	SendEmailChangeNotice(string, string, string) (*EmailResponse, error)
+	SendWelcome(string, string, string) (*EmailResponse, error)
+	SendLockout(string, string, int) (*EmailResponse, error)
+	SendDataExportReady(string, string, string) (*EmailResponse, error)
+	SendAccountClosureConfirmation(string, string, string, string) (*EmailResponse, error)
+	SendNotificationEmail(string, string, NotificationItem, string, string) (*EmailResponse, error)
+	SendNotificationDigest(string, string, string, []NotificationItem, string) (*EmailResponse, error)
 }
+
 var (
-	SendMail SendMailer = &sendMail{} //this is useful when we start testing
+	SendMail SendMailer = &sendMail{}
 )
 
 type EmailResponse struct {
-	Status int
+	Status   int
 	RespBody string
 }
 
-func (s *sendMail) SendResetPassword(ToUser string, FromAdmin string, Token string, Sendgridkey string, AppEnv string) (*EmailResponse, error) {
-	h := hermes.Hermes{
-		Product: hermes.Product{
-			Name: "SeamFlow",
-			Link: "https://seamflow.com",
-		},
-	}
-	var forgotUrl string
-	if os.Getenv("APP_ENV") == "production" {
-		forgotUrl = "https://seamflow.com/resetpassword/" + Token //this is the url of the frontend app
-	} else {
-		forgotUrl = "http://127.0.0.1:3000/resetpassword/" + Token //this is the url of the local frontend app
+func (s *sendMail) SendResetPassword(ToUser string, Locale string, Token string) (*EmailResponse, error) {
+	return sendEmail(TemplateReset, ToUser, Locale, Data{"Token": Token}, nil)
+}
+
+// sendEmail renders the email of the registry and sends it with the Sender
+func sendEmail(name string, ToUser string, Locale string, data Data, headers map[string]string) (*EmailResponse, error) {
+	if Sender == nil {
+		return nil, errNoTransport
 	}
-	email := hermes.Email{
-		Body: hermes.Body{
-			Name: ToUser,
-			Intros: []string{
-				"Welcome to SeamFlow! Good to have you here.",
-			},
-			Actions: []hermes.Action{
-				{
-					Instructions: "Click this link to reset your password",
-					Button: hermes.Button{
-						Color: "#FFFFFF",
-						Text:  "Reset Password",
-						Link:  forgotUrl,
-					},
-				},
-			},
-			Outros: []string{
-				"Need help, or have questions? Just reply to this email, we'd love to help.",
-			},
-		},
+	if _, ok := data["Name"]; !ok {
+		data["Name"] = ToUser
 	}
-	emailBody, err := h.GenerateHTML(email)
+	rendered, err := Render(name, Locale, data)
 	if err != nil {
 		return nil, err
 	}
-	from := mail.NewEmail("SeamFlow", FromAdmin)
-	subject := "Reset Password"
-	to := mail.NewEmail("Reset Password", ToUser)
-	message := mail.NewSingleEmail(from, subject, to, emailBody, emailBody)
-	client := sendgrid.NewSendClient(Sendgridkey)
-	_, err = client.Send(message)
+	err = Sender.Send(&Message{
+		From:     fromAddress(),
+		FromName: CurrentBrand().Name,
+		To:       ToUser,
+		Subject:  rendered.Subject,
+		HTML:     rendered.HTML,
+		Text:     rendered.Text,
+		Headers:  headers,
+	})
 	if err != nil {
 		return nil, err
 	}
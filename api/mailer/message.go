@@ -0,0 +1,72 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Bytes gives the message in the Internet Message Format (RFC 5322), its body in a plain text and an HTML part
+func (m *Message) Bytes() []byte {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	writePart(parts, "text/plain; charset=utf-8", m.Text)
+	writePart(parts, "text/html; charset=utf-8", m.HTML)
+	parts.Close()
+
+	headers := map[string]string{
+		"From":         (&mail.Address{Name: m.FromName, Address: m.From}).String(),
+		"To":           (&mail.Address{Address: m.To}).String(),
+		"Subject":      mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-ID":   messageID(m.From),
+		"MIME-Version": "1.0",
+		"Content-Type": "multipart/alternative; boundary=" + parts.Boundary(),
+	}
+	for key, value := range m.Headers {
+		headers[textproto.CanonicalMIMEHeaderKey(key)] = value
+	}
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var message bytes.Buffer
+	for _, key := range keys {
+		fmt.Fprintf(&message, "%s: %s\r\n", key, headers[key])
+	}
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+	return message.Bytes()
+}
+
+func writePart(parts *multipart.Writer, contentType, content string) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, _ := parts.CreatePart(header)
+	encoder := quotedprintable.NewWriter(part)
+	encoder.Write([]byte(content))
+	encoder.Close()
+}
+
+// messageID is a new unique id on the domain of the sender
+func messageID(from string) string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = from[at+1:]
+	}
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">"
+}
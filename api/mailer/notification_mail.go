@@ -0,0 +1,41 @@
+package mailer
+
+import (
+	"net/url"
+	"os"
+)
+
+// unsubscribeHeaders let mail clients unsubscribe in one click, without opening the email (RFC 8058)
+func unsubscribeHeaders(Token string) map[string]string {
+	link := os.Getenv("API_URL") + "/api/v1/unsubscribe?token=" + url.QueryEscape(Token)
+	return map[string]string{
+		"List-Unsubscribe":      "<" + link + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+// unsubscribeURL is the link of the email body, the page of the frontend app unsubscribes when opened
+func unsubscribeURL(Token string) string {
+	return CurrentBrand().FrontendURL + "/unsubscribe/" + url.PathEscape(Token)
+}
+
+// SendNotificationEmail tells the user about a notification as soon as it happens.
+// Path is the page of the frontend app the notification is about
+func (s *sendMail) SendNotificationEmail(ToUser string, Locale string, Notification NotificationItem, Path string, UnsubscribeToken string) (*EmailResponse, error) {
+	data := Data{
+		"Notification":   Notification,
+		"Path":           Path,
+		"UnsubscribeURL": unsubscribeURL(UnsubscribeToken),
+	}
+	return sendEmail(TemplateNotification, ToUser, Locale, data, unsubscribeHeaders(UnsubscribeToken))
+}
+
+// SendNotificationDigest sends the notifications of the day, or of the week, in one email
+func (s *sendMail) SendNotificationDigest(ToUser string, Locale string, Frequency string, Notifications []NotificationItem, UnsubscribeToken string) (*EmailResponse, error) {
+	data := Data{
+		"Frequency":      Frequency,
+		"Notifications":  Notifications,
+		"UnsubscribeURL": unsubscribeURL(UnsubscribeToken),
+	}
+	return sendEmail(TemplateDigest, ToUser, Locale, data, unsubscribeHeaders(UnsubscribeToken))
+}
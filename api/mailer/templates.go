@@ -0,0 +1,389 @@
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/matcornic/hermes/v2"
+)
+
+// The emails of the registry
+const (
+	TemplateReset             = "reset"
+	TemplateVerify            = "verify"
+	TemplateEmailChangeNotice = "email_change_notice"
+	TemplateWelcome           = "welcome"
+	TemplateLockout           = "lockout"
+	TemplateDataExport        = "data_export"
+	TemplateAccountClosure    = "account_closure"
+	TemplateNotification      = "notification"
+	TemplateDigest            = "digest"
+)
+
+// The emails are in this locale when the user has none, or one without a catalog
+const DefaultLocale = "en"
+
+// Data is what a template is rendered with.
+// The brand is added as Product, ProductURL and FrontendURL, and Name defaults to the address of the user
+type Data map[string]interface{}
+
+// NotificationItem is a notification told in an email: its type, the latest actor, and how many actors there are.
+// Given as the Notification or the Notifications of the data, they are told in the locale as Text or Items
+type NotificationItem struct {
+	Type  string
+	Actor string
+	Count int
+}
+
+// Action is a button of an email
+type Action struct {
+	Instructions string
+	Button       string
+	Link         string
+}
+
+// Copy is the text of an email in a locale. Every string is a text/template executed with the data of the email
+type Copy struct {
+	Subject string
+	Intros  []string
+	// The Items of the data are listed under this heading
+	ItemsHeading string
+	Actions      []Action
+	Outros       []string
+}
+
+// Catalog is the text of every email in a locale
+type Catalog struct {
+	Emails map[string]Copy
+	// What the actors of each type of notification did, like "{{.Who}} liked your post". Count is the number of actors
+	Notifications map[string]string
+	// Who did it when there is one actor, two, and more, like "{{.Actor}} and {{.Others}} others"
+	One, Two, Many string
+	// The words hermes puts around the copy
+	Greeting    string
+	Signature   string
+	TroubleText string
+	Copyright   string
+}
+
+// catalogs are the locales the emails are written in
+var catalogs = map[string]*Catalog{
+	"en": &catalogEN,
+	"fr": &catalogFR,
+}
+
+// samples are the data the templates are previewed with
+var samples = map[string]Data{
+	TemplateReset:             {"Token": "sample-token"},
+	TemplateVerify:            {"Token": "sample-token"},
+	TemplateEmailChangeNotice: {"Token": "sample-token"},
+	TemplateWelcome:           {"Username": "steven"},
+	TemplateLockout:           {"Minutes": 15},
+	TemplateDataExport:        {"Link": "https://seamflow.com/api/v1/exports/1/download?expires=0&signature=sample"},
+	TemplateAccountClosure:    {"Mode": "delete", "Token": "sample-token"},
+	TemplateNotification: {
+		"Notification":   NotificationItem{Type: "like", Actor: "steven", Count: 3},
+		"Path":           "posts/1",
+		"UnsubscribeURL": "https://seamflow.com/unsubscribe/sample-token",
+	},
+	TemplateDigest: {
+		"Frequency":      "daily",
+		"Notifications":  []NotificationItem{{Type: "comment", Actor: "steven", Count: 1}, {Type: "follow", Actor: "magu", Count: 2}},
+		"UnsubscribeURL": "https://seamflow.com/unsubscribe/sample-token",
+	},
+}
+
+// Templates gives the names of the emails of the registry
+func Templates() []string {
+	names := make([]string, 0, len(samples))
+	for name := range samples {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Locales gives the locales the emails are written in
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// catalogFor gives the catalog of the locale, of its language when there is none for its region, or the default one
+func catalogFor(locale string) (string, *Catalog) {
+	if catalog, ok := catalogs[locale]; ok {
+		return locale, catalog
+	}
+	language := strings.ToLower(strings.SplitN(strings.Replace(locale, "_", "-", 1), "-", 2)[0])
+	if catalog, ok := catalogs[language]; ok {
+		return language, catalog
+	}
+	return DefaultLocale, catalogs[DefaultLocale]
+}
+
+// Rendered is an email ready to be sent
+type Rendered struct {
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// Preview renders the template with its sample data
+func Preview(name, locale string) (*Rendered, error) {
+	sample, ok := samples[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+	data := Data{"Name": "pet@example.com"}
+	for key, value := range sample {
+		data[key] = value
+	}
+	return Render(name, locale, data)
+}
+
+// Render writes the email of the registry in the locale of the user. The copy of the catalog is laid out by hermes,
+// unless MAIL_TEMPLATES_DIR has files overriding it: <name>.subject, <name>.txt and <name>.html,
+// or <name>.<locale>.subject and so on for a locale only. The text files are text/templates and the HTML ones
+// html/templates, executed with the same data as the copy, Items and Actions included
+func Render(name, locale string, data Data) (*Rendered, error) {
+	if _, ok := samples[name]; !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+	locale, catalog := catalogFor(locale)
+	// An email missing from a catalog is written in the default locale
+	emailCopy, ok := catalog.Emails[name]
+	if !ok {
+		emailCopy = catalogs[DefaultLocale].Emails[name]
+	}
+	brand := CurrentBrand()
+	values := Data{
+		"Product":     brand.Name,
+		"ProductURL":  brand.URL,
+		"FrontendURL": brand.FrontendURL,
+		"Year":        time.Now().Year(),
+	}
+	for key, value := range data {
+		values[key] = value
+	}
+	err := tellNotifications(catalog, values)
+	if err != nil {
+		return nil, err
+	}
+
+	email := hermes.Email{}
+	email.Body.Name, _ = values["Name"].(string)
+	email.Body.Intros, err = executeAll(emailCopy.Intros, values)
+	if err != nil {
+		return nil, err
+	}
+	email.Body.Outros, err = executeAll(emailCopy.Outros, values)
+	if err != nil {
+		return nil, err
+	}
+	actions := []Action{}
+	for _, action := range emailCopy.Actions {
+		executed, err := executeAll([]string{action.Instructions, action.Button, action.Link}, values)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, Action{Instructions: executed[0], Button: executed[1], Link: executed[2]})
+		email.Body.Actions = append(email.Body.Actions, hermes.Action{
+			Instructions: executed[0],
+			Button: hermes.Button{
+				Color: "#FFFFFF",
+				Text:  executed[1],
+				Link:  executed[2],
+			},
+		})
+	}
+	values["Actions"] = actions
+	if items, ok := values["Items"].([]string); ok && len(items) > 0 {
+		heading, err := execute(emailCopy.ItemsHeading, values)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			email.Body.Table.Data = append(email.Body.Table.Data, []hermes.Entry{{Key: heading, Value: item}})
+		}
+	}
+	email.Body.Greeting, err = execute(catalog.Greeting, values)
+	if err != nil {
+		return nil, err
+	}
+	email.Body.Signature, err = execute(catalog.Signature, values)
+	if err != nil {
+		return nil, err
+	}
+	copyright, err := execute(catalog.Copyright, values)
+	if err != nil {
+		return nil, err
+	}
+	h := hermes.Hermes{
+		Product: hermes.Product{
+			Name:        brand.Name,
+			Link:        brand.URL,
+			Logo:        brand.Logo,
+			Copyright:   copyright,
+			TroubleText: catalog.TroubleText,
+		},
+	}
+
+	rendered := &Rendered{}
+	rendered.Subject, err = execute(emailCopy.Subject, values)
+	if err != nil {
+		return nil, err
+	}
+	rendered.HTML, err = h.GenerateHTML(email)
+	if err != nil {
+		return nil, err
+	}
+	rendered.Text, err = h.GeneratePlainText(email)
+	if err != nil {
+		return nil, err
+	}
+	err = applyOverrides(rendered, name, locale, values)
+	if err != nil {
+		return nil, err
+	}
+	return rendered, nil
+}
+
+// tellNotifications puts the notifications of the data in words, in the locale of the catalog
+func tellNotifications(catalog *Catalog, values Data) error {
+	if notification, ok := values["Notification"].(NotificationItem); ok {
+		text, err := notificationText(catalog, notification)
+		if err != nil {
+			return err
+		}
+		values["Text"] = text
+	}
+	if notifications, ok := values["Notifications"].([]NotificationItem); ok {
+		items := make([]string, len(notifications))
+		for i, notification := range notifications {
+			text, err := notificationText(catalog, notification)
+			if err != nil {
+				return err
+			}
+			items[i] = text
+		}
+		values["Items"] = items
+	}
+	return nil
+}
+
+func notificationText(catalog *Catalog, notification NotificationItem) (string, error) {
+	who := catalog.One
+	switch {
+	case notification.Count == 2:
+		who = catalog.Two
+	case notification.Count > 2:
+		who = catalog.Many
+	}
+	whoText, err := execute(who, Data{"Actor": notification.Actor, "Others": notification.Count - 1})
+	if err != nil {
+		return "", err
+	}
+	// Count lets the languages whose verbs agree with the actors say it right
+	return execute(catalog.Notifications[notification.Type], Data{"Who": whoText, "Count": notification.Count})
+}
+
+func execute(text string, values Data) (string, error) {
+	parsed, err := template.New("copy").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	err = parsed.Execute(&out, values)
+	if err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func executeAll(texts []string, values Data) ([]string, error) {
+	executed := make([]string, len(texts))
+	for i, text := range texts {
+		out, err := execute(text, values)
+		if err != nil {
+			return nil, err
+		}
+		executed[i] = out
+	}
+	return executed, nil
+}
+
+// applyOverrides replaces the parts of the email MAIL_TEMPLATES_DIR has a file for
+func applyOverrides(rendered *Rendered, name, locale string, values Data) error {
+	dir := os.Getenv("MAIL_TEMPLATES_DIR")
+	if dir == "" {
+		return nil
+	}
+	for _, part := range []struct {
+		extension string
+		target    *string
+	}{
+		{"subject", &rendered.Subject},
+		{"txt", &rendered.Text},
+		{"html", &rendered.HTML},
+	} {
+		content, ok, err := readOverride(dir, name, locale, part.extension)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		var out bytes.Buffer
+		if part.extension == "html" {
+			parsed, err := htmltemplate.New(name).Option("missingkey=error").Parse(content)
+			if err != nil {
+				return err
+			}
+			err = parsed.Execute(&out, values)
+			if err != nil {
+				return err
+			}
+		} else {
+			parsed, err := template.New(name).Option("missingkey=error").Parse(content)
+			if err != nil {
+				return err
+			}
+			err = parsed.Execute(&out, values)
+			if err != nil {
+				return err
+			}
+		}
+		*part.target = out.String()
+		// A subject is a single line, whatever the editor of the file added at its end
+		if part.extension == "subject" {
+			*part.target = strings.TrimSpace(*part.target)
+		}
+	}
+	return nil
+}
+
+// readOverride gives the file of the locale, or the one of every locale
+func readOverride(dir, name, locale, extension string) (string, bool, error) {
+	for _, file := range []string{name + "." + locale + "." + extension, name + "." + extension} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err == nil {
+			return string(content), true, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", false, err
+		}
+	}
+	return "", false, nil
+}
@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Message is an email ready to be sent, with the HTML and the plain text versions of its body
+type Message struct {
+	From     string
+	FromName string
+	To       string
+	Subject  string
+	HTML     string
+	Text     string
+	Headers  map[string]string
+}
+
+// Transport delivers the emails, whatever the service behind it
+type Transport interface {
+	Send(message *Message) error
+}
+
+// The transports that can be picked with MAIL_TRANSPORT
+const (
+	TransportSendGrid = "sendgrid"
+	TransportSMTP     = "smtp"
+	TransportFile     = "file"
+)
+
+// Sender is how the emails go out, it is set up once with Configure
+var Sender Transport
+
+var errNoTransport = errors.New("no mail transport is set up")
+
+// Configure sets up the transport of the given name from the environment.
+// SendGrid is used when none is given, as it was the only way to send emails before transports existed
+func Configure(transport string) error {
+	switch transport {
+	case TransportSendGrid, "":
+		Sender = NewSendGridTransport(os.Getenv("SENDGRID_API_KEY"))
+	case TransportSMTP:
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		// STARTTLS is required unless it is turned off, so that passwords never go in the clear
+		requireTLS := os.Getenv("SMTP_STARTTLS") != "false"
+		Sender = NewSMTPTransport(os.Getenv("SMTP_HOST"), port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), requireTLS)
+	case TransportFile:
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "mails"
+		}
+		sender, err := NewFileTransport(dir)
+		if err != nil {
+			return err
+		}
+		Sender = sender
+	default:
+		return fmt.Errorf("unknown mail transport %q", transport)
+	}
+	return nil
+}
+
+// fromAddress is the address the emails are sent from
+func fromAddress() string {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = os.Getenv("SENDGRID_FROM")
+	}
+	return from
+}
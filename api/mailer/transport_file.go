@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/twinj/uuid"
+)
+
+// fileTransport writes every email as an .eml file in a directory, for development and tests.
+// The files open in any mail client
+type fileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) (Transport, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+	return &fileTransport{dir: dir}, nil
+}
+
+func (ft *fileTransport) Send(message *Message) error {
+	// The time first, so that the files are listed in the order they were sent
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), uuid.NewV4().String())
+	path := filepath.Join(ft.dir, name)
+	err := ioutil.WriteFile(path, message.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Email written to %s\n", path)
+	return nil
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"fmt"
+
+	"github.com/sendgrid/sendgrid-go"
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+// sendGridTransport sends the emails with the API of SendGrid
+type sendGridTransport struct {
+	apiKey string
+}
+
+func NewSendGridTransport(apiKey string) Transport {
+	return &sendGridTransport{apiKey: apiKey}
+}
+
+func (st *sendGridTransport) Send(message *Message) error {
+	from := mail.NewEmail(message.FromName, message.From)
+	to := mail.NewEmail(message.To, message.To)
+	email := mail.NewSingleEmail(from, message.Subject, to, message.Text, message.HTML)
+	for key, value := range message.Headers {
+		email.SetHeader(key, value)
+	}
+	response, err := sendgrid.NewSendClient(st.apiKey).Send(email)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= 400 {
+		return fmt.Errorf("sendgrid refused the email: %d %s", response.StatusCode, response.Body)
+	}
+	return nil
+}
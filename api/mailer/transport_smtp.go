@@ -0,0 +1,76 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// How long connecting to the SMTP server can take
+const smtpDialTimeout = 10 * time.Second
+
+// smtpTransport sends the emails to an SMTP server, upgrading the connection with STARTTLS when it can
+type smtpTransport struct {
+	host       string
+	port       string
+	username   string
+	password   string
+	requireTLS bool
+}
+
+// NewSMTPTransport gives a transport to the server. It logs in when a username is given,
+// and with requireTLS it refuses to send anything to a server without STARTTLS
+func NewSMTPTransport(host, port, username, password string, requireTLS bool) Transport {
+	return &smtpTransport{host: host, port: port, username: username, password: password, requireTLS: requireTLS}
+}
+
+func (st *smtpTransport) Send(message *Message) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(st.host, st.port), smtpDialTimeout)
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, st.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: st.host})
+		if err != nil {
+			return err
+		}
+	} else if st.requireTLS {
+		return errors.New("the SMTP server does not support STARTTLS")
+	}
+	if st.username != "" {
+		err = client.Auth(smtp.PlainAuth("", st.username, st.password, st.host))
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(message.From)
+	if err != nil {
+		return err
+	}
+	err = client.Rcpt(message.To)
+	if err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(message.Bytes())
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
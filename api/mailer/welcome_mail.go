@@ -0,0 +1,11 @@
+package mailer
+
+// SendWelcome greets the user who just signed up
+func (s *sendMail) SendWelcome(ToUser string, Locale string, Username string) (*EmailResponse, error) {
+	return sendEmail(TemplateWelcome, ToUser, Locale, Data{"Username": Username}, nil)
+}
+
+// SendLockout tells the user their account is locked after too many failed logins, and for how many minutes
+func (s *sendMail) SendLockout(ToUser string, Locale string, Minutes int) (*EmailResponse, error) {
+	return sendEmail(TemplateLockout, ToUser, Locale, Data{"Minutes": Minutes}, nil)
+}
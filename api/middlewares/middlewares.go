@@ -4,13 +4,24 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/models"
 )
 
-func TokenAuthMiddleware() gin.HandlerFunc {
+// TokenAuthMiddleware lets through the requests with a valid token of an active user.
+// A token issued before the account was closed, or deleted, is refused
+func TokenAuthMiddleware(db *gorm.DB) gin.HandlerFunc {
 	errList := make(map[string]string)
 	return func(c *gin.Context) {
 		err := auth.TokenValid(c.Request)
+		if err == nil {
+			var uid uint32
+			uid, err = auth.ExtractTokenID(c.Request)
+			if err == nil {
+				err = (&models.User{}).CheckActive(db, uid)
+			}
+		}
 		if err != nil {
 			errList["unauthorized"] = "Unauthorized"
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"html"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/utils/markdown"
+)
+
+// Posts and comments used to be stored with html.EscapeString applied, they are turned back to what the users wrote
+func unescapeContent(db *gorm.DB) error {
+	posts := []models.Post{}
+	err := db.Unscoped().Model(&models.Post{}).Find(&posts).Error
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		content := html.UnescapeString(p.Content)
+		err = db.Unscoped().Model(&models.Post{}).Where("id = ?", p.ID).UpdateColumns(map[string]interface{}{
+			"title":        html.UnescapeString(p.Title),
+			"content":      content,
+			"content_html": markdown.Render(content),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	comments := []models.Comment{}
+	err = db.Unscoped().Model(&models.Comment{}).Find(&comments).Error
+	if err != nil {
+		return err
+	}
+	for _, c := range comments {
+		body := html.UnescapeString(c.Body)
+		err = db.Unscoped().Model(&models.Comment{}).Where("id = ?", c.ID).UpdateColumns(map[string]interface{}{
+			"body":      body,
+			"body_html": markdown.Render(body),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	revisions := []models.Revision{}
+	err = db.Model(&models.Revision{}).Find(&revisions).Error
+	if err != nil {
+		return err
+	}
+	for _, r := range revisions {
+		err = db.Model(&models.Revision{}).Where("id = ?", r.ID).UpdateColumns(map[string]interface{}{
+			"title":   html.UnescapeString(r.Title),
+			"content": html.UnescapeString(r.Content),
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// Titles no longer have to be unique, and the posts created before slugs existed get one
+func postSlugs(db *gorm.DB) error {
+	var err error
+	switch db.Dialect().GetName() {
+	case "postgres":
+		err = db.Exec("ALTER TABLE posts DROP CONSTRAINT IF EXISTS posts_title_key").Error
+	case "mysql":
+		if db.Dialect().HasIndex("posts", "title") {
+			err = db.Exec("ALTER TABLE posts DROP INDEX title").Error
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	posts := []models.Post{}
+	err = db.Unscoped().Model(&models.Post{}).Where("slug IS NULL OR slug = ''").Order("id").Find(&posts).Error
+	if err != nil {
+		return err
+	}
+	for _, p := range posts {
+		// BeforeCreate gives the slug on create, here the same is done for the existing posts
+		p.Slug = ""
+		err = p.BeforeCreate(db)
+		if err != nil {
+			return err
+		}
+		err = db.Unscoped().Model(&models.Post{}).Where("id = ?", p.ID).UpdateColumn("slug", p.Slug).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,11 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// Posts written before drafts existed were published when they were created
+func postPublishAt(db *gorm.DB) error {
+	return db.Unscoped().Model(&models.Post{}).Where("publish_at IS NULL AND status = ?", models.PostPublished).UpdateColumn("publish_at", gorm.Expr("created_at")).Error
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// The deleted user used to be created the first time an account was purged, and found by its email.
+// It is now a system account: the one created before is flagged, otherwise it is created
+func deletedUser(db *gorm.DB) error {
+	updated := db.Model(&models.User{}).Where("username = ? AND email = ?", models.DeletedUsername, models.DeletedUserEmail).UpdateColumn("system_account", true)
+	if updated.Error != nil {
+		return updated.Error
+	}
+	if updated.RowsAffected > 0 {
+		return nil
+	}
+	// When someone signed up with its username or email, this fails and the account has to be renamed by hand
+	_, err := models.CreateDeletedUser(db)
+	return err
+}
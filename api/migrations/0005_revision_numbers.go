@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// Revisions saved at the same time could get the same number. The history they are in is numbered again
+// in the order the revisions were saved, then the numbers are made unique
+func revisionNumbers(db *gorm.DB) error {
+	var duplicated []struct {
+		ResourceType string
+		ResourceID   uint64
+	}
+	err := db.Model(&models.Revision{}).Select("DISTINCT resource_type, resource_id").Group("resource_type, resource_id, number").Having("COUNT(*) > 1").Scan(&duplicated).Error
+	if err != nil {
+		return err
+	}
+	for _, d := range duplicated {
+		revisions := []models.Revision{}
+		err = db.Model(&models.Revision{}).Where("resource_type = ? AND resource_id = ?", d.ResourceType, d.ResourceID).Order("number, id").Find(&revisions).Error
+		if err != nil {
+			return err
+		}
+		for i, r := range revisions {
+			err = db.Model(&models.Revision{}).Where("id = ?", r.ID).UpdateColumn("number", i+1).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+	// AutoMigrate could not add the index while the numbers were duplicated
+	if !db.Dialect().HasIndex("revisions", "idx_revision_number") {
+		err = db.Model(&models.Revision{}).AddUniqueIndex("idx_revision_number", "resource_type", "resource_id", "number").Error
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Data migrations that AutoMigrate cannot do. They run once, in order, and are never edited after being released
+var migrations = []migration{
+	{Name: "0001_unescape_content", Run: unescapeContent},
+	{Name: "0002_post_slugs", Run: postSlugs},
+	{Name: "0003_post_publish_at", Run: postPublishAt},
+	{Name: "0004_deleted_user", Run: deletedUser},
+	{Name: "0005_revision_numbers", Run: revisionNumbers},
+}
+
+type migration struct {
+	Name string
+	Run  func(db *gorm.DB) error
+}
+
+// schemaMigration records the migrations that were applied to the database
+type schemaMigration struct {
+	Name      string    `gorm:"primary_key;size:255"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Run applies the migrations that were not applied yet
+func Run(db *gorm.DB) error {
+	err := db.Debug().AutoMigrate(&schemaMigration{}).Error
+	if err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		var count int
+		err = db.Debug().Model(&schemaMigration{}).Where("name = ?", m.Name).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		// The record is written first, so when two instances start together the second one fails on the primary key and rolls back
+		tx := db.Begin()
+		err = tx.Create(&schemaMigration{Name: m.Name, AppliedAt: time.Now()}).Error
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("cannot record migration %s: %v", m.Name, err)
+		}
+		err = m.Run(tx)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("cannot run migration %s: %v", m.Name, err)
+		}
+		err = tx.Commit().Error
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Applied migration %s\n", m.Name)
+	}
+	return nil
+}
@@ -0,0 +1,265 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/security"
+)
+
+// The ways a user can close their account
+const (
+	// The account is hidden until the user logs in again
+	ClosureDeactivate = "deactivate"
+	// The account is removed, the posts and comments stay under the deleted user
+	ClosureDelete = "delete"
+)
+
+// AccountClosure is the request of a user to close their account. Once it is confirmed from the email,
+// the account is hidden at once, and purged when the grace period is over. Logging in before cancels it
+type AccountClosure struct {
+	ID          uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID      uint32     `gorm:"not null;index" json:"user_id"`
+	Mode        string     `gorm:"size:20;not null" json:"mode"`
+	Token       string     `gorm:"size:255;not null;unique_index" json:"-"`
+	ConfirmedAt *time.Time `json:"confirmed_at"`
+	PurgeAfter  *time.Time `gorm:"index" json:"purge_after"`
+	CancelledAt *time.Time `json:"cancelled_at"`
+	PurgedAt    *time.Time `json:"purged_at"`
+	CreatedAt   time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+const (
+	// How long the link of the confirmation email can be used
+	ClosureConfirmLifetime = 24 * time.Hour
+	// How long a confirmed closure waits before it is purged
+	AccountClosureGracePeriod = 30 * 24 * time.Hour
+)
+
+// The user the posts and comments of the deleted accounts are given to. It is a system user,
+// created with the database, and its username and email are reserved so nobody can sign up with them
+const (
+	DeletedUsername  = "deleted"
+	DeletedUserEmail = "deleted@seamflow.invalid"
+)
+
+var ErrAccountClosureExpired = errors.New("account closure link expired")
+
+// SaveAccountClosure records the request. A request the user made before and did not confirm is dropped
+func (ac *AccountClosure) SaveAccountClosure(db *gorm.DB) (*AccountClosure, error) {
+	err := db.Debug().Model(&AccountClosure{}).Where("user_id = ? AND confirmed_at IS NULL", ac.UserID).Delete(&AccountClosure{}).Error
+	if err != nil {
+		return &AccountClosure{}, err
+	}
+	ac.CreatedAt = time.Now()
+	err = db.Debug().Model(&AccountClosure{}).Create(&ac).Error
+	if err != nil {
+		return &AccountClosure{}, err
+	}
+	return ac, nil
+}
+
+// ConfirmAccountClosure deactivates the user of the request the token belongs to, and schedules its purge
+func (ac *AccountClosure) ConfirmAccountClosure(db *gorm.DB, token string) (*AccountClosure, error) {
+	err := db.Debug().Model(&AccountClosure{}).Where("token = ? AND confirmed_at IS NULL", token).Take(&ac).Error
+	if err != nil {
+		return &AccountClosure{}, err
+	}
+	if time.Since(ac.CreatedAt) > ClosureConfirmLifetime {
+		return &AccountClosure{}, ErrAccountClosureExpired
+	}
+	now := time.Now()
+	purgeAfter := now.Add(AccountClosureGracePeriod)
+	tx := db.Begin()
+	err = tx.Debug().Model(&User{}).Where("id = ?", ac.UserID).UpdateColumn("deactivated_at", now).Error
+	if err != nil {
+		tx.Rollback()
+		return &AccountClosure{}, err
+	}
+	// Only the last confirmed request is purged
+	err = tx.Debug().Model(&AccountClosure{}).Where("user_id = ? AND confirmed_at IS NOT NULL AND cancelled_at IS NULL AND purged_at IS NULL", ac.UserID).UpdateColumn("cancelled_at", now).Error
+	if err != nil {
+		tx.Rollback()
+		return &AccountClosure{}, err
+	}
+	err = tx.Debug().Model(&AccountClosure{}).Where("id = ?", ac.ID).UpdateColumns(
+		map[string]interface{}{
+			"confirmed_at": now,
+			"purge_after":  purgeAfter,
+		},
+	).Error
+	if err != nil {
+		tx.Rollback()
+		return &AccountClosure{}, err
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return &AccountClosure{}, err
+	}
+	ac.ConfirmedAt = &now
+	ac.PurgeAfter = &purgeAfter
+	return ac, nil
+}
+
+// ErrInactiveUser is returned for a user whose account is closed or gone
+var ErrInactiveUser = errors.New("inactive user")
+
+// CheckActive tells if the account of the user is open. The tokens of a closed account are refused with it
+func (u *User) CheckActive(db *gorm.DB, uid uint32) error {
+	var count int
+	err := db.Debug().Model(&User{}).Where("id = ? AND deactivated_at IS NULL", uid).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrInactiveUser
+	}
+	return nil
+}
+
+// Reactivate gives the user back their account, and cancels the closures waiting to be purged
+func (u *User) Reactivate(db *gorm.DB, uid uint32) error {
+	now := time.Now()
+	tx := db.Begin()
+	err := tx.Debug().Model(&User{}).Where("id = ?", uid).UpdateColumn("deactivated_at", gorm.Expr("NULL")).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Debug().Model(&AccountClosure{}).Where("user_id = ? AND cancelled_at IS NULL AND purged_at IS NULL", uid).UpdateColumn("cancelled_at", now).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+// FindDueClosures gives the confirmed closures whose grace period is over
+func (ac *AccountClosure) FindDueClosures(db *gorm.DB, now time.Time) (*[]AccountClosure, error) {
+	closures := []AccountClosure{}
+	err := db.Debug().Model(&AccountClosure{}).Where("confirmed_at IS NOT NULL AND cancelled_at IS NULL AND purged_at IS NULL AND purge_after <= ?", now).Order("purge_after asc").Find(&closures).Error
+	if err != nil {
+		return &[]AccountClosure{}, err
+	}
+	return &closures, nil
+}
+
+func (ac *AccountClosure) MarkPurged(db *gorm.DB) error {
+	now := time.Now()
+	err := db.Debug().Model(&AccountClosure{}).Where("id = ?", ac.ID).UpdateColumn("purged_at", now).Error
+	if err != nil {
+		return err
+	}
+	ac.PurgedAt = &now
+	return nil
+}
+
+// DeletedUser gives the user the content of the deleted accounts belongs to
+func DeletedUser(db *gorm.DB) (*User, error) {
+	user := User{}
+	err := db.Debug().Model(&User{}).Where("system_account = ? AND username = ?", true, DeletedUsername).Take(&user).Error
+	if err == nil {
+		return &user, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return &User{}, err
+	}
+	return CreateDeletedUser(db)
+}
+
+// CreateDeletedUser creates the deleted user. It is done when the database is migrated or seeded,
+// before anyone could sign up with its username or email
+func CreateDeletedUser(db *gorm.DB) (*User, error) {
+	now := time.Now()
+	user := User{
+		Username:      DeletedUsername,
+		Email:         DeletedUserEmail,
+		Password:      security.TokenHash(DeletedUserEmail),
+		Role:          RoleUser,
+		ShowActivity:  false,
+		SystemAccount: true,
+		// It is never shown as a profile
+		DeactivatedAt: &now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	err := db.Debug().Model(&User{}).Create(&user).Error
+	if err != nil {
+		return &User{}, err
+	}
+	return &user, nil
+}
+
+// AnonymizeUser gives the posts, comments, revisions and attachments of the user to the deleted user,
+// then removes everything else the user had, their part in the notifications of others included, and the user
+func AnonymizeUser(db *gorm.DB, uid uint32) error {
+	deleted, err := DeletedUser(db)
+	if err != nil {
+		return err
+	}
+	if deleted.ID == uid {
+		return errors.New("the deleted user cannot be anonymized")
+	}
+	user := User{}
+	err = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		return err
+	}
+
+	tx := db.Begin()
+	err = tx.Debug().Unscoped().Model(&Post{}).Where("author_id = ?", uid).UpdateColumn("author_id", deleted.ID).Error
+	if err == nil {
+		err = tx.Debug().Unscoped().Model(&Comment{}).Where("user_id = ?", uid).UpdateColumn("user_id", deleted.ID).Error
+	}
+	if err == nil {
+		err = tx.Debug().Model(&Revision{}).Where("editor_id = ?", uid).UpdateColumn("editor_id", deleted.ID).Error
+	}
+	if err == nil {
+		err = tx.Debug().Model(&Attachment{}).Where("user_id = ?", uid).UpdateColumn("user_id", deleted.ID).Error
+	}
+	if err == nil {
+		err = tx.Debug().Model(&Mention{}).Where("author_id = ?", uid).UpdateColumn("author_id", deleted.ID).Error
+	}
+	if err == nil {
+		err = tx.Debug().Model(&Mention{}).Where("user_id = ?", uid).Delete(&Mention{}).Error
+	}
+	if err == nil {
+		_, err = (&Notification{}).DeleteUserNotifications(tx, uid)
+	}
+	if err == nil {
+		_, err = (&NotificationPreference{}).DeleteUserPreferences(tx, uid)
+	}
+	if err == nil {
+		_, err = (&Like{}).DeleteUserLikes(tx, uid)
+	}
+	if err == nil {
+		_, err = (&PollVote{}).DeleteUserVotes(tx, uid)
+	}
+	if err == nil {
+		_, err = (&Follow{}).DeleteUserFollows(tx, uid)
+	}
+	if err == nil {
+		_, err = (&Block{}).DeleteUserBlocksAndMutes(tx, uid)
+	}
+	if err == nil {
+		_, err = (&UsernameChange{}).DeleteUserUsernameChanges(tx, uid)
+	}
+	if err == nil {
+		_, err = (&EmailChange{}).DeleteUserEmailChanges(tx, uid)
+	}
+	if err == nil {
+		err = tx.Debug().Model(&DataExport{}).Where("user_id = ?", uid).Delete(&DataExport{}).Error
+	}
+	if err == nil {
+		err = tx.Debug().Model(&ResetPassword{}).Where("email = ?", user.Email).Delete(&ResetPassword{}).Error
+	}
+	if err == nil {
+		_, err = user.DeleteAUser(tx, uid)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
@@ -0,0 +1,123 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/fileupload"
+)
+
+// The kind of content an attachment belongs to
+const (
+	AttachmentPost    = "post"
+	AttachmentComment = "comment"
+)
+
+// Attachment is a file uploaded by a user to one of their posts or comments
+type Attachment struct {
+	ID           uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	ResourceType string    `gorm:"size:20;not null;index:idx_attachment_resource" json:"resource_type"`
+	ResourceID   uint64    `gorm:"not null;index:idx_attachment_resource" json:"resource_id"`
+	UserID       uint32    `gorm:"not null;index" json:"user_id"`
+	Path         string    `gorm:"size:255;not null" json:"-"`
+	URL          string    `gorm:"-" json:"url"`
+	FileName     string    `gorm:"size:255;not null" json:"file_name"`
+	ContentType  string    `gorm:"size:100;not null" json:"content_type"`
+	Size         int64     `gorm:"not null" json:"size"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+func (a *Attachment) AfterFind() (err error) {
+	a.URL = fileupload.URL(a.Path)
+	return nil
+}
+
+func (a *Attachment) SaveAttachment(db *gorm.DB) (*Attachment, error) {
+	err := db.Debug().Model(&Attachment{}).Create(&a).Error
+	if err != nil {
+		return &Attachment{}, err
+	}
+	a.URL = fileupload.URL(a.Path)
+	return a, nil
+}
+
+var ErrQuotaExceeded = errors.New("attachment quota exceeded")
+
+// SaveAttachments saves the attachments of one upload together, or none of them.
+// The row of the user is locked while the quota is checked, so uploads made at the same time are counted one after the other
+func (a *Attachment) SaveAttachments(db *gorm.DB, uid uint32, quota int64, attachments []Attachment) (*[]Attachment, error) {
+	tx := db.Begin()
+	err := tx.Debug().Set("gorm:query_option", "FOR UPDATE").Model(&User{}).Where("id = ?", uid).Take(&User{}).Error
+	if err != nil {
+		tx.Rollback()
+		return &[]Attachment{}, err
+	}
+	used, err := a.UserQuotaUsed(tx, uid)
+	if err != nil {
+		tx.Rollback()
+		return &[]Attachment{}, err
+	}
+	for i, _ := range attachments {
+		used += attachments[i].Size
+	}
+	if used > quota {
+		tx.Rollback()
+		return &[]Attachment{}, ErrQuotaExceeded
+	}
+	for i, _ := range attachments {
+		err = tx.Debug().Model(&Attachment{}).Create(&attachments[i]).Error
+		if err != nil {
+			tx.Rollback()
+			return &[]Attachment{}, err
+		}
+		attachments[i].URL = fileupload.URL(attachments[i].Path)
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return &[]Attachment{}, err
+	}
+	return &attachments, nil
+}
+
+func (a *Attachment) FindAttachments(db *gorm.DB, resourceType string, rid uint64) (*[]Attachment, error) {
+	attachments := []Attachment{}
+	err := db.Debug().Model(&Attachment{}).Where("resource_type = ? AND resource_id = ?", resourceType, rid).Order("id").Find(&attachments).Error
+	if err != nil {
+		return &[]Attachment{}, err
+	}
+	return &attachments, nil
+}
+
+func (a *Attachment) DeleteAttachment(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&Attachment{}).Where("id = ?", a.ID).Take(&Attachment{}).Delete(&Attachment{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// UserQuotaUsed is the number of bytes the user has uploaded and not removed yet
+func (a *Attachment) UserQuotaUsed(db *gorm.DB, uid uint32) (int64, error) {
+	var used struct {
+		Total int64
+	}
+	err := db.Debug().Model(&Attachment{}).Select("COALESCE(SUM(size), 0) AS total").Where("user_id = ?", uid).Scan(&used).Error
+	if err != nil {
+		return 0, err
+	}
+	return used.Total, nil
+}
+
+// FindOrphanedAttachments gives the attachments whose post or comment is gone for good.
+// Posts and comments in the trash still exist, so their attachments are kept until they are purged
+func (a *Attachment) FindOrphanedAttachments(db *gorm.DB) (*[]Attachment, error) {
+	attachments := []Attachment{}
+	posts := db.Unscoped().Model(&Post{}).Select("id").QueryExpr()
+	comments := db.Unscoped().Model(&Comment{}).Select("id").QueryExpr()
+	err := db.Debug().Model(&Attachment{}).Where("(resource_type = ? AND resource_id NOT IN (?)) OR (resource_type = ? AND resource_id NOT IN (?))", AttachmentPost, posts, AttachmentComment, comments).Limit(500).Find(&attachments).Error
+	if err != nil {
+		return &[]Attachment{}, err
+	}
+	return &attachments, nil
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Block stops a user from commenting on and liking the posts of the blocker
+type Block struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	BlockerID uint32    `gorm:"not null;unique_index:idx_block_pair" json:"blocker_id"`
+	BlockedID uint32    `gorm:"not null;unique_index:idx_block_pair;index" json:"blocked_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Mute hides the posts and comments of a user from the muter, the muted user is not told
+type Mute struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	MuterID   uint32    `gorm:"not null;unique_index:idx_mute_pair" json:"muter_id"`
+	MutedID   uint32    `gorm:"not null;unique_index:idx_mute_pair" json:"muted_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// SaveBlock blocks the user, and removes the follows between the two users.
+// Blocking a user twice keeps the first block
+func (b *Block) SaveBlock(db *gorm.DB) (*Block, error) {
+	if b.BlockerID == b.BlockedID {
+		return &Block{}, errors.New("self block")
+	}
+	err := db.Debug().Model(&Block{}).Create(&b).Error
+	if err != nil {
+		if !isUniqueViolation(err) {
+			return &Block{}, err
+		}
+		err = db.Debug().Model(&Block{}).Where("blocker_id = ? AND blocked_id = ?", b.BlockerID, b.BlockedID).Take(&b).Error
+		if err != nil {
+			return &Block{}, err
+		}
+	}
+	err = db.Debug().Model(&Follow{}).Where("(follower_id = ? AND following_id = ?) OR (follower_id = ? AND following_id = ?)", b.BlockerID, b.BlockedID, b.BlockedID, b.BlockerID).Delete(&Follow{}).Error
+	if err != nil {
+		return &Block{}, err
+	}
+	return b, nil
+}
+
+func (b *Block) DeleteBlock(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&Block{}).Where("blocker_id = ? AND blocked_id = ?", b.BlockerID, b.BlockedID).Delete(&Block{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// IsBlocked tells if the blocker has blocked the user
+func (b *Block) IsBlocked(db *gorm.DB, blockerID, uid uint32) (bool, error) {
+	var count int
+	err := db.Debug().Model(&Block{}).Where("blocker_id = ? AND blocked_id = ?", blockerID, uid).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// FindBlockedUsers gives the users the user has blocked, the latest first
+func (b *Block) FindBlockedUsers(db *gorm.DB, uid uint32) (*[]User, error) {
+	users := []User{}
+	err := db.Debug().Model(&User{}).Joins("JOIN blocks ON blocks.blocked_id = users.id").Where("blocks.blocker_id = ?", uid).Order("blocks.created_at desc").Find(&users).Error
+	if err != nil {
+		return &[]User{}, err
+	}
+	return &users, nil
+}
+
+// SaveMute mutes the user. Muting a user twice keeps the first mute
+func (m *Mute) SaveMute(db *gorm.DB) (*Mute, error) {
+	if m.MuterID == m.MutedID {
+		return &Mute{}, errors.New("self mute")
+	}
+	err := db.Debug().Model(&Mute{}).Create(&m).Error
+	if err != nil {
+		if !isUniqueViolation(err) {
+			return &Mute{}, err
+		}
+		err = db.Debug().Model(&Mute{}).Where("muter_id = ? AND muted_id = ?", m.MuterID, m.MutedID).Take(&m).Error
+		if err != nil {
+			return &Mute{}, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Mute) DeleteMute(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&Mute{}).Where("muter_id = ? AND muted_id = ?", m.MuterID, m.MutedID).Delete(&Mute{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// FindMutedUsers gives the users the user has muted, the latest first
+func (m *Mute) FindMutedUsers(db *gorm.DB, uid uint32) (*[]User, error) {
+	users := []User{}
+	err := db.Debug().Model(&User{}).Joins("JOIN mutes ON mutes.muted_id = users.id").Where("mutes.muter_id = ?", uid).Order("mutes.created_at desc").Find(&users).Error
+	if err != nil {
+		return &[]User{}, err
+	}
+	return &users, nil
+}
+
+// WithoutMutedAuthors leaves out the posts of the users the viewer muted
+func WithoutMutedAuthors(viewerID uint32) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		muted := db.New().Model(&Mute{}).Select("muted_id").Where("muter_id = ?", viewerID).QueryExpr()
+		return db.Where("author_id NOT IN (?)", muted)
+	}
+}
+
+// WithoutMutedUsers leaves out the comments of the users the viewer muted
+func WithoutMutedUsers(viewerID uint32) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		muted := db.New().Model(&Mute{}).Select("muted_id").Where("muter_id = ?", viewerID).QueryExpr()
+		return db.Where("user_id NOT IN (?)", muted)
+	}
+}
+
+// When a user is deleted, the blocks and mutes from and to the user go too
+func (b *Block) DeleteUserBlocksAndMutes(db *gorm.DB, uid uint32) (int64, error) {
+	err := db.Debug().Model(&Mute{}).Where("muter_id = ? OR muted_id = ?", uid, uid).Delete(&Mute{}).Error
+	if err != nil {
+		return 0, err
+	}
+	db = db.Debug().Model(&Block{}).Where("blocker_id = ? OR blocked_id = ?", uid, uid).Delete(&Block{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
@@ -3,27 +3,34 @@ package models
 import (
 	"errors"
 	"fmt"
-	"html"
 	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/utils/markdown"
 )
 
 type Comment struct {
-	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
-	UserID    uint32    `gorm:"not null" json:"user_id"`
-	PostID    uint64    `gorm:"not null" json:"post_id"`
-	Body      string    `gorm:"text;not null;" json:"body"`
-	User      User      `json:"user"`
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID          uint64       `gorm:"primary_key;auto_increment" json:"id"`
+	UserID      uint32       `gorm:"not null" json:"user_id"`
+	PostID      uint64       `gorm:"not null" json:"post_id"`
+	Body        string       `gorm:"text;not null;" json:"body,omitempty"`
+	BodyHTML    string       `gorm:"type:text" json:"body_html,omitempty"`
+	User        User         `json:"user"`
+	Attachments []Attachment `gorm:"-" json:"attachments,omitempty"`
+	Mentions    []Mention    `gorm:"-" json:"mentions,omitempty"`
+	CreatedAt   time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt   *time.Time   `sql:"index" json:"deleted_at"`
 }
 
+// The body is kept as the Markdown the user wrote, it is only made safe when rendered to HTML
 func (c *Comment) Prepare() {
 	c.ID = 0
-	c.Body = html.EscapeString(strings.TrimSpace(c.Body))
+	c.Body = strings.TrimSpace(c.Body)
 	c.User = User{}
+	c.Attachments = nil
+	c.Mentions = nil
 	c.CreatedAt = time.Now()
 	c.UpdatedAt = time.Now()
 }
@@ -47,7 +54,18 @@ func (c *Comment) Validate(action string) map[string]string {
 	return errorMessages
 }
 
+// Format keeps only the representation of the body the client asked for
+func (c *Comment) Format(format string) {
+	switch format {
+	case FormatRaw:
+		c.BodyHTML = ""
+	case FormatHTML:
+		c.Body = ""
+	}
+}
+
 func (c *Comment) SaveComment(db *gorm.DB) (*Comment, error) {
+	c.BodyHTML = markdown.Render(c.Body)
 	err := db.Debug().Create(&c).Error
 	if err != nil {
 		return &Comment{}, err
@@ -57,14 +75,21 @@ func (c *Comment) SaveComment(db *gorm.DB) (*Comment, error) {
 		if err != nil {
 			return &Comment{}, err
 		}
+		c.syncMentions(db)
+		// The comment is saved even when the notifications cannot be
+		err = NotifyComment(db, c)
+		if err != nil {
+			fmt.Println("cannot notify the comment: ", err)
+		}
+		OnEvent(EventCommentCreated, c)
 	}
 	return c, nil
 }
 
-func (c *Comment) GetComments(db *gorm.DB, pid uint64) (*[]Comment, error) {
+func (c *Comment) GetComments(db *gorm.DB, pid uint64, scopes ...func(*gorm.DB) *gorm.DB) (*[]Comment, error) {
 
 	comments := []Comment{}
-	err := db.Debug().Model(&Comment{}).Where("post_id = ?", pid).Order("created_at desc").Find(&comments).Error
+	err := db.Debug().Model(&Comment{}).Where("post_id = ?", pid).Order("created_at desc").Scopes(scopes...).Find(&comments).Error
 	if err != nil {
 		return &[]Comment{}, err
 	}
@@ -83,7 +108,8 @@ func (c *Comment) UpdateAComment(db *gorm.DB) (*Comment, error) {
 
 	var err error
 
-	err = db.Debug().Model(&Comment{}).Where("id = ?", c.ID).Updates(Comment{Body: c.Body, UpdatedAt: time.Now()}).Error
+	c.BodyHTML = markdown.Render(c.Body)
+	err = db.Debug().Model(&Comment{}).Where("id = ?", c.ID).Updates(Comment{Body: c.Body, BodyHTML: c.BodyHTML, UpdatedAt: time.Now()}).Error
 	if err != nil {
 		return &Comment{}, err
 	}
@@ -94,24 +120,28 @@ func (c *Comment) UpdateAComment(db *gorm.DB) (*Comment, error) {
 		if err != nil {
 			return &Comment{}, err
 		}
+		c.syncMentions(db)
+		OnEvent(EventCommentUpdated, c)
 	}
 	return c, nil
 }
 
 func (c *Comment) DeleteAComment(db *gorm.DB) (int64, error) {
 
-	db = db.Debug().Model(&Comment{}).Where("id = ?", c.ID).Take(&Comment{}).Delete(&Comment{})
+	deleted := Comment{}
+	db = db.Debug().Model(&Comment{}).Where("id = ?", c.ID).Take(&deleted).Delete(&Comment{})
 
 	if db.Error != nil {
 		return 0, db.Error
 	}
+	OnEvent(EventCommentDeleted, &deleted)
 	return db.RowsAffected, nil
 }
 
 //When a user is deleted, we also delete the comments that the user had
 func (c *Comment) DeleteUserComments(db *gorm.DB, uid uint32) (int64, error) {
 	comments := []Comment{}
-	db = db.Debug().Model(&Comment{}).Where("user_id = ?", uid).Find(&comments).Delete(&comments)
+	db = db.Debug().Unscoped().Model(&Comment{}).Where("user_id = ?", uid).Find(&comments).Delete(&comments)
 	if db.Error != nil {
 		return 0, db.Error
 	}
@@ -121,7 +151,56 @@ func (c *Comment) DeleteUserComments(db *gorm.DB, uid uint32) (int64, error) {
 //When a post is deleted, we also delete the comments that the post had
 func (c *Comment) DeletePostComments(db *gorm.DB, pid uint64) (int64, error) {
 	comments := []Comment{}
-	db = db.Debug().Model(&Comment{}).Where("post_id = ?", pid).Find(&comments).Delete(&comments)
+	db = db.Debug().Unscoped().Model(&Comment{}).Where("post_id = ?", pid).Find(&comments).Delete(&comments)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+//The comments the user deleted, which are still in the trash
+func (c *Comment) FindDeletedComments(db *gorm.DB, uid uint32) (*[]Comment, error) {
+
+	comments := []Comment{}
+	err := db.Debug().Unscoped().Model(&Comment{}).Where("user_id = ? AND deleted_at IS NOT NULL", uid).Order("deleted_at desc").Find(&comments).Error
+	if err != nil {
+		return &[]Comment{}, err
+	}
+	return &comments, nil
+}
+
+func (c *Comment) RestoreAComment(db *gorm.DB) (*Comment, error) {
+
+	var err error
+
+	err = db.Debug().Unscoped().Model(&Comment{}).Where("id = ? AND deleted_at IS NOT NULL", c.ID).Take(&Comment{}).UpdateColumn("deleted_at", gorm.Expr("NULL")).Error
+	if err != nil {
+		return &Comment{}, err
+	}
+	err = db.Debug().Model(&Comment{}).Where("id = ?", c.ID).Take(&c).Error
+	if err != nil {
+		return &Comment{}, err
+	}
+	err = db.Debug().Model(&User{}).Where("id = ?", c.UserID).Take(&c.User).Error
+	if err != nil {
+		return &Comment{}, err
+	}
+	return c, nil
+}
+
+//Comments that stayed in the trash beyond the retention period are removed for good
+func (c *Comment) PurgeDeletedComments(db *gorm.DB, before time.Time) (int64, error) {
+
+	expired := db.Unscoped().Model(&Comment{}).Select("id").Where("deleted_at IS NOT NULL AND deleted_at < ?", before).QueryExpr()
+	err := db.Debug().Model(&Revision{}).Where("resource_type = ? AND resource_id IN (?)", RevisionComment, expired).Delete(&Revision{}).Error
+	if err != nil {
+		return 0, err
+	}
+	err = db.Debug().Model(&Mention{}).Where("comment_id IN (?)", expired).Delete(&Mention{}).Error
+	if err != nil {
+		return 0, err
+	}
+	db = db.Debug().Unscoped().Model(&Comment{}).Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&Comment{})
 	if db.Error != nil {
 		return 0, db.Error
 	}
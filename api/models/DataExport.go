@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// The states of a data export
+const (
+	ExportPending  = "pending"
+	ExportBuilding = "building"
+	ExportReady    = "ready"
+	ExportFailed   = "failed"
+)
+
+// How long the archive of an export can be downloaded
+const ExportLifetime = 7 * 24 * time.Hour
+
+// An export still building after that was left by an instance that stopped, another one can claim it
+const exportBuildTimeout = time.Hour
+
+// DataExport is a user asking for an archive of their data. The archive is built in the background
+type DataExport struct {
+	ID        uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32     `gorm:"not null;index" json:"user_id"`
+	Status    string     `gorm:"size:20;not null;default:'pending';index" json:"status"`
+	Path      string     `gorm:"size:255" json:"-"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// DownloadPath is the path of the API route that gives the archive, the link sent to the user is signed for it
+func (de *DataExport) DownloadPath() string {
+	return fmt.Sprintf("/api/v1/exports/%d/download", de.ID)
+}
+
+// SaveDataExport asks for an export. While one is pending or building, asking again gives that one
+func (de *DataExport) SaveDataExport(db *gorm.DB) (*DataExport, error) {
+	err := db.Debug().Model(&DataExport{}).Where("user_id = ? AND status IN (?)", de.UserID, []string{ExportPending, ExportBuilding}).Take(&de).Error
+	if err == nil {
+		return de, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return &DataExport{}, err
+	}
+	de.Status = ExportPending
+	err = db.Debug().Model(&DataExport{}).Create(&de).Error
+	if err != nil {
+		return &DataExport{}, err
+	}
+	return de, nil
+}
+
+func (de *DataExport) FindDataExportByID(db *gorm.DB, id uint64) (*DataExport, error) {
+	err := db.Debug().Model(&DataExport{}).Where("id = ?", id).Take(&de).Error
+	if err != nil {
+		return &DataExport{}, err
+	}
+	return de, nil
+}
+
+// FindPendingExports gives the exports waiting for their archive, the oldest first.
+// Those left building for too long are waiting again
+func (de *DataExport) FindPendingExports(db *gorm.DB) (*[]DataExport, error) {
+	exports := []DataExport{}
+	err := db.Debug().Model(&DataExport{}).Where("status = ? OR (status = ? AND updated_at < ?)", ExportPending, ExportBuilding, time.Now().Add(-exportBuildTimeout)).Order("created_at asc").Find(&exports).Error
+	if err != nil {
+		return &[]DataExport{}, err
+	}
+	return &exports, nil
+}
+
+// Claim marks the export as building, with an update that only applies while it is still waiting.
+// It is false when another instance claimed it first, so every archive is built and emailed once
+func (de *DataExport) Claim(db *gorm.DB) (bool, error) {
+	now := time.Now()
+	db = db.Debug().Model(&DataExport{}).Where("id = ? AND (status = ? OR (status = ? AND updated_at < ?))", de.ID, ExportPending, ExportBuilding, now.Add(-exportBuildTimeout)).UpdateColumns(
+		map[string]interface{}{
+			"status":     ExportBuilding,
+			"updated_at": now,
+		},
+	)
+	if db.Error != nil {
+		return false, db.Error
+	}
+	if db.RowsAffected == 0 {
+		return false, nil
+	}
+	de.Status = ExportBuilding
+	de.UpdatedAt = now
+	return true, nil
+}
+
+// MarkReady records where the archive is and until when it can be downloaded
+func (de *DataExport) MarkReady(db *gorm.DB, path string, expiresAt time.Time) error {
+	de.Status = ExportReady
+	de.Path = path
+	de.ExpiresAt = &expiresAt
+	return db.Debug().Model(&DataExport{}).Where("id = ?", de.ID).UpdateColumns(
+		map[string]interface{}{
+			"status":     de.Status,
+			"path":       de.Path,
+			"expires_at": de.ExpiresAt,
+			"updated_at": time.Now(),
+		},
+	).Error
+}
+
+func (de *DataExport) MarkFailed(db *gorm.DB) error {
+	de.Status = ExportFailed
+	return db.Debug().Model(&DataExport{}).Where("id = ?", de.ID).UpdateColumns(
+		map[string]interface{}{
+			"status":     de.Status,
+			"updated_at": time.Now(),
+		},
+	).Error
+}
+
+// FindExpiredExports gives the exports whose archive cannot be downloaded anymore
+func (de *DataExport) FindExpiredExports(db *gorm.DB, now time.Time) (*[]DataExport, error) {
+	exports := []DataExport{}
+	err := db.Debug().Model(&DataExport{}).Where("status = ? AND expires_at < ?", ExportReady, now).Find(&exports).Error
+	if err != nil {
+		return &[]DataExport{}, err
+	}
+	return &exports, nil
+}
+
+func (de *DataExport) DeleteDataExport(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&DataExport{}).Where("id = ?", de.ID).Delete(&DataExport{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
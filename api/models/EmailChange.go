@@ -0,0 +1,170 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// EmailChange is a change of email address waiting to be confirmed from the new address.
+// The old address gets a link to revert it, which works before and after the confirmation
+type EmailChange struct {
+	ID           uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID       uint32     `gorm:"not null;index" json:"user_id"`
+	OldEmail     string     `gorm:"size:100;not null" json:"old_email"`
+	NewEmail     string     `gorm:"size:100;not null" json:"new_email"`
+	ConfirmToken string     `gorm:"size:255;not null;unique_index" json:"-"`
+	RevertToken  string     `gorm:"size:255;not null;unique_index" json:"-"`
+	ConfirmedAt  *time.Time `json:"confirmed_at"`
+	RevertedAt   *time.Time `json:"reverted_at"`
+	CreatedAt    time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+const (
+	// How long the link sent to the new address can be used
+	EmailConfirmLifetime = 24 * time.Hour
+	// How long the link sent to the old address can be used
+	EmailRevertLifetime = 7 * 24 * time.Hour
+)
+
+var (
+	ErrEmailChangeExpired = errors.New("email change link expired")
+	ErrEmailTaken         = errors.New("email taken")
+)
+
+// SaveEmailChange records the change. A change the user asked for before and did not confirm is dropped
+func (ec *EmailChange) SaveEmailChange(db *gorm.DB) (*EmailChange, error) {
+	err := db.Debug().Model(&EmailChange{}).Where("user_id = ? AND confirmed_at IS NULL AND reverted_at IS NULL", ec.UserID).Delete(&EmailChange{}).Error
+	if err != nil {
+		return &EmailChange{}, err
+	}
+	ec.CreatedAt = time.Now()
+	err = db.Debug().Model(&EmailChange{}).Create(&ec).Error
+	if err != nil {
+		return &EmailChange{}, err
+	}
+	return ec, nil
+}
+
+// ConfirmEmailChange gives the user the new address of the change the token belongs to
+func (ec *EmailChange) ConfirmEmailChange(db *gorm.DB, token string) (*User, error) {
+	err := db.Debug().Model(&EmailChange{}).Where("confirm_token = ? AND confirmed_at IS NULL AND reverted_at IS NULL", token).Take(&ec).Error
+	if err != nil {
+		return &User{}, err
+	}
+	if time.Since(ec.CreatedAt) > EmailConfirmLifetime {
+		return &User{}, ErrEmailChangeExpired
+	}
+	// Someone else may have taken the address since the change was asked for
+	var count int
+	err = db.Debug().Model(&User{}).Where("email = ? AND id <> ?", ec.NewEmail, ec.UserID).Count(&count).Error
+	if err != nil {
+		return &User{}, err
+	}
+	if count > 0 {
+		return &User{}, ErrEmailTaken
+	}
+	now := time.Now()
+	tx := db.Begin()
+	err = tx.Debug().Model(&User{}).Where("id = ?", ec.UserID).UpdateColumns(
+		map[string]interface{}{
+			"email":      ec.NewEmail,
+			"updated_at": now,
+		},
+	).Error
+	if err != nil {
+		tx.Rollback()
+		if isUniqueViolation(err) {
+			return &User{}, ErrEmailTaken
+		}
+		return &User{}, err
+	}
+	err = tx.Debug().Model(&EmailChange{}).Where("id = ?", ec.ID).UpdateColumn("confirmed_at", now).Error
+	if err != nil {
+		tx.Rollback()
+		return &User{}, err
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return &User{}, err
+	}
+	user := User{}
+	err = db.Debug().Model(&User{}).Where("id = ?", ec.UserID).Take(&user).Error
+	if err != nil {
+		return &User{}, err
+	}
+	return &user, nil
+}
+
+// RevertEmailChange cancels the change the token belongs to, and gives back the old address if it was confirmed
+func (ec *EmailChange) RevertEmailChange(db *gorm.DB, token string) (*User, error) {
+	err := db.Debug().Model(&EmailChange{}).Where("revert_token = ? AND reverted_at IS NULL", token).Take(&ec).Error
+	if err != nil {
+		return &User{}, err
+	}
+	if time.Since(ec.CreatedAt) > EmailRevertLifetime {
+		return &User{}, ErrEmailChangeExpired
+	}
+	// Someone else may have taken the old address since the change was confirmed
+	if ec.ConfirmedAt != nil {
+		var count int
+		err = db.Debug().Model(&User{}).Where("email = ? AND id <> ?", ec.OldEmail, ec.UserID).Count(&count).Error
+		if err != nil {
+			return &User{}, err
+		}
+		if count > 0 {
+			return &User{}, ErrEmailTaken
+		}
+	}
+	now := time.Now()
+	tx := db.Begin()
+	if ec.ConfirmedAt != nil {
+		err = tx.Debug().Model(&User{}).Where("id = ?", ec.UserID).UpdateColumns(
+			map[string]interface{}{
+				"email":      ec.OldEmail,
+				"updated_at": now,
+			},
+		).Error
+		if err != nil {
+			tx.Rollback()
+			if isUniqueViolation(err) {
+				return &User{}, ErrEmailTaken
+			}
+			return &User{}, err
+		}
+	}
+	err = tx.Debug().Model(&EmailChange{}).Where("id = ?", ec.ID).UpdateColumn("reverted_at", now).Error
+	if err != nil {
+		tx.Rollback()
+		return &User{}, err
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return &User{}, err
+	}
+	user := User{}
+	err = db.Debug().Model(&User{}).Where("id = ?", ec.UserID).Take(&user).Error
+	if err != nil {
+		return &User{}, err
+	}
+	return &user, nil
+}
+
+// DeleteEmailChange drops a change whose emails could not be sent
+func (ec *EmailChange) DeleteEmailChange(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&EmailChange{}).Where("id = ?", ec.ID).Delete(&EmailChange{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// When a user is deleted, the changes of their email address go too
+func (ec *EmailChange) DeleteUserEmailChanges(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Model(&EmailChange{}).Where("user_id = ?", uid).Delete(&EmailChange{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Follow is a user following another one, whose posts then show in the follower's feed
+type Follow struct {
+	ID          uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	FollowerID  uint32    `gorm:"not null;unique_index:idx_follow_pair" json:"follower_id"`
+	FollowingID uint32    `gorm:"not null;unique_index:idx_follow_pair;index" json:"following_id"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// CategoryFollow is a user following a category, the posts of the category show in the user's feed
+type CategoryFollow struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_category_follow" json:"user_id"`
+	Category  string    `gorm:"size:100;not null;unique_index:idx_category_follow" json:"category"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// SaveFollow follows the user. Following a user twice keeps the first follow
+func (f *Follow) SaveFollow(db *gorm.DB) (*Follow, error) {
+	if f.FollowerID == f.FollowingID {
+		return &Follow{}, errors.New("self follow")
+	}
+	err := db.Debug().Model(&Follow{}).Create(&f).Error
+	if err != nil {
+		if !isUniqueViolation(err) {
+			return &Follow{}, err
+		}
+		err = db.Debug().Model(&Follow{}).Where("follower_id = ? AND following_id = ?", f.FollowerID, f.FollowingID).Take(&f).Error
+		if err != nil {
+			return &Follow{}, err
+		}
+		return f, nil
+	}
+	// The follow is saved even when the notification cannot be
+	err = NotifyFollow(db, f)
+	if err != nil {
+		fmt.Println("cannot notify the follow: ", err)
+	}
+	return f, nil
+}
+
+func (f *Follow) DeleteFollow(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&Follow{}).Where("follower_id = ? AND following_id = ?", f.FollowerID, f.FollowingID).Delete(&Follow{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// FindFollowers gives a page of the users following the user, the latest first
+func (f *Follow) FindFollowers(db *gorm.DB, uid uint32, page, perPage int) (*[]User, error) {
+	users := []User{}
+	err := db.Debug().Model(&User{}).Joins("JOIN follows ON follows.follower_id = users.id").Where("follows.following_id = ?", uid).Order("follows.created_at desc").Offset((page - 1) * perPage).Limit(perPage).Find(&users).Error
+	if err != nil {
+		return &[]User{}, err
+	}
+	return &users, nil
+}
+
+// FindFollowing gives a page of the users the user follows, the latest first
+func (f *Follow) FindFollowing(db *gorm.DB, uid uint32, page, perPage int) (*[]User, error) {
+	users := []User{}
+	err := db.Debug().Model(&User{}).Joins("JOIN follows ON follows.following_id = users.id").Where("follows.follower_id = ?", uid).Order("follows.created_at desc").Offset((page - 1) * perPage).Limit(perPage).Find(&users).Error
+	if err != nil {
+		return &[]User{}, err
+	}
+	return &users, nil
+}
+
+// When a user is deleted, the follows from and to the user go too
+func (f *Follow) DeleteUserFollows(db *gorm.DB, uid uint32) (int64, error) {
+	err := db.Debug().Model(&CategoryFollow{}).Where("user_id = ?", uid).Delete(&CategoryFollow{}).Error
+	if err != nil {
+		return 0, err
+	}
+	db = db.Debug().Model(&Follow{}).Where("follower_id = ? OR following_id = ?", uid, uid).Delete(&Follow{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// SaveCategoryFollow follows the category. Following a category twice keeps the first follow
+func (cf *CategoryFollow) SaveCategoryFollow(db *gorm.DB) (*CategoryFollow, error) {
+	cf.Category = strings.ToLower(strings.TrimSpace(cf.Category))
+	err := db.Debug().Model(&CategoryFollow{}).Create(&cf).Error
+	if err != nil {
+		if !isUniqueViolation(err) {
+			return &CategoryFollow{}, err
+		}
+		err = db.Debug().Model(&CategoryFollow{}).Where("user_id = ? AND category = ?", cf.UserID, cf.Category).Take(&cf).Error
+		if err != nil {
+			return &CategoryFollow{}, err
+		}
+	}
+	return cf, nil
+}
+
+func (cf *CategoryFollow) DeleteCategoryFollow(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&CategoryFollow{}).Where("user_id = ? AND category = ?", cf.UserID, strings.ToLower(strings.TrimSpace(cf.Category))).Delete(&CategoryFollow{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// FeedCursor marks where a page of the feed ended: the publication time and the id of its last post
+type FeedCursor struct {
+	PublishAt time.Time
+	ID        uint64
+}
+
+func (fc FeedCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d_%d", fc.PublishAt.UnixNano(), fc.ID)))
+}
+
+func ParseFeedCursor(cursor string) (FeedCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return FeedCursor{}, err
+	}
+	var nanoseconds int64
+	var id uint64
+	_, err = fmt.Sscanf(string(decoded), "%d_%d", &nanoseconds, &id)
+	if err != nil {
+		return FeedCursor{}, err
+	}
+	return FeedCursor{PublishAt: time.Unix(0, nanoseconds), ID: id}, nil
+}
+
+// FindFeed gives the posts of the followed users and categories, the latest first, after the cursor when one is given.
+// Muted users are left out, even in the followed categories.
+// The next cursor is empty when there are no more posts
+func (f *Follow) FindFeed(db *gorm.DB, uid uint32, cursor *FeedCursor, limit int) (*[]Post, string, error) {
+	following := db.Model(&Follow{}).Select("following_id").Where("follower_id = ?", uid).QueryExpr()
+	categories := db.Model(&CategoryFollow{}).Select("category").Where("user_id = ?", uid).QueryExpr()
+	feed := func(db *gorm.DB) *gorm.DB {
+		// One more post than asked tells if there is a next page
+		db = db.Where("author_id IN (?) OR category IN (?)", following, categories).Order("publish_at desc, id desc", true).Limit(limit + 1)
+		if cursor != nil {
+			db = db.Where("publish_at < ? OR (publish_at = ? AND id < ?)", cursor.PublishAt, cursor.PublishAt, cursor.ID)
+		}
+		return db
+	}
+	posts, err := (&Post{}).FindAllPosts(db, feed, WithoutMutedAuthors(uid))
+	if err != nil {
+		return &[]Post{}, "", err
+	}
+	next := ""
+	if len(*posts) > limit {
+		*posts = (*posts)[:limit]
+		last := (*posts)[limit-1]
+		if last.PublishAt != nil {
+			next = FeedCursor{PublishAt: *last.PublishAt, ID: last.ID}.String()
+		}
+	}
+	return posts, next, nil
+}
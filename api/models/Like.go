@@ -27,6 +27,12 @@ func (l *Like) SaveLike(db *gorm.DB) (*Like, error) {
 			if err != nil {
 				return &Like{}, err
 			}
+			// The like is saved even when the notification cannot be
+			err = NotifyLike(db, l)
+			if err != nil {
+				fmt.Println("cannot notify the like: ", err)
+			}
+			OnEvent(EventLikeCreated, l)
 		}
 	} else {
 		// The user has liked it before, so create a custom error message
@@ -51,6 +57,7 @@ func (l *Like) DeleteLike(db *gorm.DB) (*Like, error) {
 			fmt.Println("cant delete like: ", db.Error)
 			return &Like{}, db.Error
 		}
+		OnEvent(EventLikeDeleted, deletedLike)
 	}
 	return deletedLike, nil
 }
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/utils/mentions"
+)
+
+// Mention is a user mentioned with @username in a post, or in one of its comments when CommentID is not 0.
+// The username is kept as written, so that clients can find it in the text and link it.
+// A mention edited out is only marked removed, so the user is not told again when it comes back
+type Mention struct {
+	ID         uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	PostID     uint64     `gorm:"not null;unique_index:idx_mention" json:"post_id"`
+	CommentID  uint64     `gorm:"not null;default:0;unique_index:idx_mention" json:"comment_id"`
+	UserID     uint32     `gorm:"not null;unique_index:idx_mention;index" json:"user_id"`
+	AuthorID   uint32     `gorm:"not null" json:"author_id"`
+	Username   string     `gorm:"size:255;not null" json:"username"`
+	NotifiedAt *time.Time `json:"-"`
+	RemovedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// SaveMentions keeps the mentions of the post, or of its comment, in step with the text.
+// Usernames someone recently left still lead to them, unknown users and closed accounts are not mentioned
+func (m *Mention) SaveMentions(db *gorm.DB, authorID uint32, postID, commentID uint64, text string) (*[]Mention, error) {
+	resolved := []Mention{}
+	found := map[uint32]bool{}
+	for _, username := range mentions.Parse(text) {
+		user, err := (&User{}).FindUserByUsername(db, username)
+		if err != nil {
+			if gorm.IsRecordNotFoundError(err) {
+				continue
+			}
+			return &[]Mention{}, err
+		}
+		if user.DeactivatedAt != nil || found[user.ID] {
+			continue
+		}
+		found[user.ID] = true
+		resolved = append(resolved, Mention{PostID: postID, CommentID: commentID, UserID: user.ID, AuthorID: authorID, Username: username})
+	}
+
+	existing := []Mention{}
+	err := db.Debug().Model(&Mention{}).Where("post_id = ? AND comment_id = ?", postID, commentID).Find(&existing).Error
+	if err != nil {
+		return &[]Mention{}, err
+	}
+	kept := map[uint32]*Mention{}
+	for i, _ := range existing {
+		if !found[existing[i].UserID] {
+			if existing[i].RemovedAt != nil {
+				continue
+			}
+			err = db.Debug().Model(&Mention{}).Where("id = ?", existing[i].ID).UpdateColumn("removed_at", time.Now()).Error
+			if err != nil {
+				return &[]Mention{}, err
+			}
+			continue
+		}
+		kept[existing[i].UserID] = &existing[i]
+	}
+	for i, _ := range resolved {
+		if previous, ok := kept[resolved[i].UserID]; ok {
+			// A mention that comes back keeps the time its user was told
+			if previous.RemovedAt != nil || previous.Username != resolved[i].Username {
+				err = db.Debug().Model(&Mention{}).Where("id = ?", previous.ID).UpdateColumns(
+					map[string]interface{}{
+						"removed_at": gorm.Expr("NULL"),
+						"username":   resolved[i].Username,
+					},
+				).Error
+				if err != nil {
+					return &[]Mention{}, err
+				}
+			}
+			continue
+		}
+		resolved[i].CreatedAt = time.Now()
+		err = db.Debug().Model(&Mention{}).Create(&resolved[i]).Error
+		if err != nil && !isUniqueViolation(err) {
+			return &[]Mention{}, err
+		}
+	}
+	return m.FindMentions(db, postID, commentID)
+}
+
+// FindMentions gives the mentions of the post, or of its comment
+func (m *Mention) FindMentions(db *gorm.DB, postID, commentID uint64) (*[]Mention, error) {
+	found := []Mention{}
+	err := db.Debug().Model(&Mention{}).Where("post_id = ? AND comment_id = ? AND removed_at IS NULL", postID, commentID).Order("id asc").Find(&found).Error
+	if err != nil {
+		return &[]Mention{}, err
+	}
+	return &found, nil
+}
+
+// FindPostsMentions gives each of the listed posts its mentions, with one query
+func (m *Mention) FindPostsMentions(db *gorm.DB, posts *[]Post) error {
+	if len(*posts) == 0 {
+		return nil
+	}
+	ids := make([]uint64, len(*posts))
+	for i, _ := range *posts {
+		ids[i] = (*posts)[i].ID
+	}
+	found := []Mention{}
+	err := db.Debug().Model(&Mention{}).Where("post_id IN (?) AND comment_id = 0 AND removed_at IS NULL", ids).Order("id asc").Find(&found).Error
+	if err != nil {
+		return err
+	}
+	byPost := map[uint64][]Mention{}
+	for i, _ := range found {
+		byPost[found[i].PostID] = append(byPost[found[i].PostID], found[i])
+	}
+	for i, _ := range *posts {
+		(*posts)[i].Mentions = byPost[(*posts)[i].ID]
+	}
+	return nil
+}
+
+// NotifyMentions tells the mentioned users who were not told yet, so an edit only notifies the users it adds
+func (m *Mention) NotifyMentions(db *gorm.DB, postID, commentID uint64) error {
+	pending := []Mention{}
+	err := db.Debug().Model(&Mention{}).Where("post_id = ? AND comment_id = ? AND notified_at IS NULL AND removed_at IS NULL", postID, commentID).Find(&pending).Error
+	if err != nil {
+		return err
+	}
+	for i, _ := range pending {
+		err = NotifyMention(db, pending[i].AuthorID, pending[i].UserID, postID, commentID)
+		if err != nil {
+			return err
+		}
+		err = db.Debug().Model(&Mention{}).Where("id = ?", pending[i].ID).UpdateColumn("notified_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// When a post is removed for good, its mentions and the mentions of its comments go too
+func (m *Mention) DeletePostMentions(db *gorm.DB, pid uint64) (int64, error) {
+	db = db.Debug().Model(&Mention{}).Where("post_id = ?", pid).Delete(&Mention{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// syncMentions saves the mentions of the content, and tells the mentioned users once the post is published.
+// The post is saved even when its mentions cannot be
+func (p *Post) syncMentions(db *gorm.DB) {
+	mention := Mention{}
+	found, err := mention.SaveMentions(db, p.AuthorID, p.ID, 0, p.Content)
+	if err != nil {
+		fmt.Println("cannot save the mentions of the post: ", err)
+		return
+	}
+	p.Mentions = *found
+	if p.Status != PostPublished {
+		return
+	}
+	err = mention.NotifyMentions(db, p.ID, 0)
+	if err != nil {
+		fmt.Println("cannot notify the mentions of the post: ", err)
+	}
+}
+
+// syncMentions saves the mentions of the body, and tells the mentioned users.
+// The comment is saved even when its mentions cannot be
+func (c *Comment) syncMentions(db *gorm.DB) {
+	mention := Mention{}
+	found, err := mention.SaveMentions(db, c.UserID, c.PostID, c.ID, c.Body)
+	if err != nil {
+		fmt.Println("cannot save the mentions of the comment: ", err)
+		return
+	}
+	c.Mentions = *found
+	err = mention.NotifyMentions(db, c.PostID, c.ID)
+	if err != nil {
+		fmt.Println("cannot notify the mentions of the comment: ", err)
+	}
+}
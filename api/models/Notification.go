@@ -0,0 +1,363 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// The events a user is notified of
+const (
+	// Someone liked a post of the user
+	NotificationLike = "like"
+	// Someone commented on a post of the user
+	NotificationComment = "comment"
+	// Someone commented on a post the user commented on
+	NotificationReply = "reply"
+	// Someone mentioned the user in a post or a comment
+	NotificationMention = "mention"
+	// Someone followed the user
+	NotificationFollow = "follow"
+)
+
+// Notification tells the user about what others did. The events of the same kind on the same post are
+// grouped while the notification is unread: its actor is the latest one, and ActorCount says how many there are
+type Notification struct {
+	ID         uint64     `gorm:"primary_key;auto_increment" json:"id"`
+	UserID     uint32     `gorm:"not null;index" json:"user_id"`
+	Type       string     `gorm:"size:20;not null" json:"type"`
+	GroupKey   string     `gorm:"size:100;not null;index" json:"-"`
+	ActorID    uint32     `gorm:"not null" json:"actor_id"`
+	Actor      User       `json:"actor"`
+	ActorCount int        `gorm:"not null;default:1" json:"actor_count"`
+	PostID     uint64     `json:"post_id"`
+	CommentID  uint64     `json:"comment_id"`
+	ReadAt     *time.Time `json:"read_at"`
+	// When the notification was last emailed, or skipped because the user turned the emails off
+	EmailedAt *time.Time `json:"-"`
+	CreatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// NotificationActor is a user in the group of a notification, so that nobody is counted twice
+type NotificationActor struct {
+	ID             uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	NotificationID uint64    `gorm:"not null;unique_index:idx_notification_actor" json:"notification_id"`
+	ActorID        uint32    `gorm:"not null;unique_index:idx_notification_actor;index" json:"actor_id"`
+	CreatedAt      time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// notify adds the actor to the unread notification of the same group, or creates it.
+// Users are not notified of what they did, nor of what the users they blocked or muted did,
+// and closed accounts are not notified at all
+func notify(db *gorm.DB, n *Notification) error {
+	if n.UserID == n.ActorID {
+		return nil
+	}
+	var active, blocks, mutes int
+	err := db.Debug().Model(&User{}).Where("id = ? AND deactivated_at IS NULL", n.UserID).Count(&active).Error
+	if err != nil {
+		return err
+	}
+	if active == 0 {
+		return nil
+	}
+	err = db.Debug().Model(&Block{}).Where("blocker_id = ? AND blocked_id = ?", n.UserID, n.ActorID).Count(&blocks).Error
+	if err != nil {
+		return err
+	}
+	err = db.Debug().Model(&Mute{}).Where("muter_id = ? AND muted_id = ?", n.UserID, n.ActorID).Count(&mutes).Error
+	if err != nil {
+		return err
+	}
+	if blocks > 0 || mutes > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	group := Notification{}
+	err = db.Debug().Model(&Notification{}).Where("user_id = ? AND group_key = ? AND read_at IS NULL", n.UserID, n.GroupKey).Take(&group).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+	if err == nil {
+		// An actor already in the group only moves the notification up
+		updates := map[string]interface{}{
+			"actor_id":   n.ActorID,
+			"comment_id": n.CommentID,
+			"updated_at": now,
+		}
+		actor := NotificationActor{NotificationID: group.ID, ActorID: n.ActorID, CreatedAt: now}
+		err = db.Debug().Model(&NotificationActor{}).Create(&actor).Error
+		if err == nil {
+			updates["actor_count"] = gorm.Expr("actor_count + 1")
+		} else if !isUniqueViolation(err) {
+			return err
+		}
+		err = db.Debug().Model(&Notification{}).Where("id = ?", group.ID).UpdateColumns(updates).Error
+		if err != nil {
+			return err
+		}
+		return emitNotification(db, group.ID)
+	}
+
+	n.ActorCount = 1
+	n.CreatedAt = now
+	n.UpdatedAt = now
+	err = db.Debug().Model(&Notification{}).Create(&n).Error
+	if err != nil {
+		return err
+	}
+	actor := NotificationActor{NotificationID: n.ID, ActorID: n.ActorID, CreatedAt: now}
+	err = db.Debug().Model(&NotificationActor{}).Create(&actor).Error
+	if err != nil {
+		return err
+	}
+	return emitNotification(db, n.ID)
+}
+
+// emitNotification tells about the notification as it is after the write, with its latest actor
+func emitNotification(db *gorm.DB, id uint64) error {
+	notification := Notification{}
+	err := db.Debug().Model(&Notification{}).Where("id = ?", id).Take(&notification).Error
+	if err != nil {
+		return err
+	}
+	err = db.Debug().Model(&User{}).Where("id = ?", notification.ActorID).Take(&notification.Actor).Error
+	if err != nil {
+		return err
+	}
+	OnEvent(EventNotification, &notification)
+	return nil
+}
+
+// NotifyLike tells the author of the post about the like
+func NotifyLike(db *gorm.DB, like *Like) error {
+	post := Post{}
+	err := db.Debug().Model(&Post{}).Where("id = ?", like.PostID).Take(&post).Error
+	if err != nil {
+		return err
+	}
+	return notify(db, &Notification{
+		UserID:   post.AuthorID,
+		Type:     NotificationLike,
+		GroupKey: fmt.Sprintf("%s:%d", NotificationLike, post.ID),
+		ActorID:  like.UserID,
+		PostID:   post.ID,
+	})
+}
+
+// NotifyComment tells the author of the post about the comment, and the users who commented on the post before
+func NotifyComment(db *gorm.DB, comment *Comment) error {
+	post := Post{}
+	err := db.Debug().Model(&Post{}).Where("id = ?", comment.PostID).Take(&post).Error
+	if err != nil {
+		return err
+	}
+	err = notify(db, &Notification{
+		UserID:    post.AuthorID,
+		Type:      NotificationComment,
+		GroupKey:  fmt.Sprintf("%s:%d", NotificationComment, post.ID),
+		ActorID:   comment.UserID,
+		PostID:    post.ID,
+		CommentID: comment.ID,
+	})
+	if err != nil {
+		return err
+	}
+	var commenters []uint32
+	err = db.Debug().Model(&Comment{}).Where("post_id = ? AND user_id NOT IN (?)", post.ID, []uint32{post.AuthorID, comment.UserID}).Pluck("DISTINCT user_id", &commenters).Error
+	if err != nil {
+		return err
+	}
+	for _, commenter := range commenters {
+		err = notify(db, &Notification{
+			UserID:    commenter,
+			Type:      NotificationReply,
+			GroupKey:  fmt.Sprintf("%s:%d", NotificationReply, post.ID),
+			ActorID:   comment.UserID,
+			PostID:    post.ID,
+			CommentID: comment.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyMention tells the user they were mentioned in the post, or in the comment when commentID is not 0.
+// Every mention is a notification of its own. Blocks go both ways: a user the actor blocked is not told either
+func NotifyMention(db *gorm.DB, actorID, uid uint32, postID, commentID uint64) error {
+	blocked, err := (&Block{}).IsBlocked(db, actorID, uid)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return nil
+	}
+	groupKey := fmt.Sprintf("%s:post:%d", NotificationMention, postID)
+	if commentID != 0 {
+		groupKey = fmt.Sprintf("%s:comment:%d", NotificationMention, commentID)
+	}
+	return notify(db, &Notification{
+		UserID:    uid,
+		Type:      NotificationMention,
+		GroupKey:  groupKey,
+		ActorID:   actorID,
+		PostID:    postID,
+		CommentID: commentID,
+	})
+}
+
+// NotifyFollow tells the user about the new follower
+func NotifyFollow(db *gorm.DB, follow *Follow) error {
+	return notify(db, &Notification{
+		UserID:   follow.FollowingID,
+		Type:     NotificationFollow,
+		GroupKey: NotificationFollow,
+		ActorID:  follow.FollowerID,
+	})
+}
+
+// FindNotifications gives a page of the notifications of the user, the latest first
+func (n *Notification) FindNotifications(db *gorm.DB, uid uint32, unreadOnly bool, page, perPage int) (*[]Notification, error) {
+	notifications := []Notification{}
+	query := db.Debug().Model(&Notification{}).Where("user_id = ?", uid)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Order("updated_at desc, id desc").Offset((page - 1) * perPage).Limit(perPage).Find(&notifications).Error
+	if err != nil {
+		return &[]Notification{}, err
+	}
+	for i, _ := range notifications {
+		err = db.Debug().Model(&User{}).Where("id = ?", notifications[i].ActorID).Take(&notifications[i].Actor).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return &[]Notification{}, err
+		}
+	}
+	return &notifications, nil
+}
+
+func (n *Notification) CountUnread(db *gorm.DB, uid uint32) (int, error) {
+	var count int
+	err := db.Debug().Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", uid).Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// MarkRead marks the notification of the user read, a notification read before keeps its time
+func (n *Notification) MarkRead(db *gorm.DB, uid uint32, id uint64) error {
+	err := db.Debug().Model(&Notification{}).Where("id = ? AND user_id = ?", id, uid).Take(&n).Error
+	if err != nil {
+		return err
+	}
+	if n.ReadAt != nil {
+		return nil
+	}
+	now := time.Now()
+	err = db.Debug().Model(&Notification{}).Where("id = ?", id).UpdateColumn("read_at", now).Error
+	if err != nil {
+		return err
+	}
+	n.ReadAt = &now
+	return nil
+}
+
+// MarkAllRead marks every unread notification of the user read
+func (n *Notification) MarkAllRead(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", uid).UpdateColumn("read_at", time.Now())
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// FindUsersToEmail gives the users with unread notifications not emailed since they last changed
+func (n *Notification) FindUsersToEmail(db *gorm.DB) ([]uint32, error) {
+	var users []uint32
+	err := db.Debug().Model(&Notification{}).Where("read_at IS NULL AND (emailed_at IS NULL OR emailed_at < updated_at)").Pluck("DISTINCT user_id", &users).Error
+	if err != nil {
+		return []uint32{}, err
+	}
+	return users, nil
+}
+
+// FindUnemailed gives the unread notifications of the user not emailed since they last changed, the oldest first
+func (n *Notification) FindUnemailed(db *gorm.DB, uid uint32) (*[]Notification, error) {
+	notifications := []Notification{}
+	err := db.Debug().Model(&Notification{}).Where("user_id = ? AND read_at IS NULL AND (emailed_at IS NULL OR emailed_at < updated_at)", uid).Order("updated_at asc, id asc").Find(&notifications).Error
+	if err != nil {
+		return &[]Notification{}, err
+	}
+	for i, _ := range notifications {
+		err = db.Debug().Model(&User{}).Where("id = ?", notifications[i].ActorID).Take(&notifications[i].Actor).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return &[]Notification{}, err
+		}
+	}
+	return &notifications, nil
+}
+
+// MarkEmailed records that the notification was emailed as it was read.
+// A new actor joining the group after that changes it again, and it is emailed again
+func (n *Notification) MarkEmailed(db *gorm.DB) error {
+	return db.Debug().Model(&Notification{}).Where("id = ?", n.ID).UpdateColumn("emailed_at", n.UpdatedAt).Error
+}
+
+// When a user is deleted, their notifications go, and they leave the groups of the notifications of the others.
+// A group is counted without them and shows its latest other actor, a group nobody else is in goes too
+func (n *Notification) DeleteUserNotifications(db *gorm.DB, uid uint32) (int64, error) {
+	notifications := db.New().Model(&Notification{}).Select("id").Where("user_id = ?", uid).QueryExpr()
+	err := db.Debug().Model(&NotificationActor{}).Where("notification_id IN (?)", notifications).Delete(&NotificationActor{}).Error
+	if err != nil {
+		return 0, err
+	}
+	deleted := db.Debug().Model(&Notification{}).Where("user_id = ?", uid).Delete(&Notification{})
+	if deleted.Error != nil {
+		return 0, deleted.Error
+	}
+
+	var groups []uint64
+	err = db.Debug().Model(&NotificationActor{}).Where("actor_id = ?", uid).Pluck("notification_id", &groups).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(groups) > 0 {
+		err = db.Debug().Model(&NotificationActor{}).Where("actor_id = ?", uid).Delete(&NotificationActor{}).Error
+		if err != nil {
+			return 0, err
+		}
+		err = db.Debug().Model(&Notification{}).Where("id IN (?)", groups).UpdateColumn("actor_count", gorm.Expr("actor_count - 1")).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	// The groups the user was in are left with the other actors, or with none
+	left := db.Debug().Model(&Notification{}).Where("actor_id = ?", uid)
+	if len(groups) > 0 {
+		left = left.Or("id IN (?)", groups)
+	}
+	affected := []Notification{}
+	err = left.Find(&affected).Error
+	if err != nil {
+		return 0, err
+	}
+	for i, _ := range affected {
+		latest := NotificationActor{}
+		err = db.Debug().Model(&NotificationActor{}).Where("notification_id = ?", affected[i].ID).Order("id desc").Take(&latest).Error
+		if gorm.IsRecordNotFoundError(err) {
+			err = db.Debug().Model(&Notification{}).Where("id = ?", affected[i].ID).Delete(&Notification{}).Error
+		} else if err == nil && affected[i].ActorID == uid {
+			err = db.Debug().Model(&Notification{}).Where("id = ?", affected[i].ID).UpdateColumn("actor_id", latest.ActorID).Error
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return deleted.RowsAffected, nil
+}
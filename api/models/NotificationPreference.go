@@ -0,0 +1,184 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// How often the user is emailed about a kind of notification
+const (
+	// An email for every notification
+	EmailImmediate = "immediate"
+	// A digest of the notifications of the day
+	EmailDaily = "daily"
+	// A digest of the notifications of the week
+	EmailWeekly = "weekly"
+	// No email at all
+	EmailOff = "off"
+)
+
+// The notifications go in the daily digest until the user chooses otherwise
+const DefaultEmailFrequency = EmailDaily
+
+// UnsubscribeAll is the kind of the unsubscribe links that stop every notification email
+const UnsubscribeAll = "all"
+
+// NotificationTypes are the kinds of notifications the user chooses the emails of
+var NotificationTypes = []string{NotificationLike, NotificationComment, NotificationReply, NotificationMention, NotificationFollow}
+
+// NotificationPreference is how often the user is emailed about a kind of notification.
+// The kinds without one use DefaultEmailFrequency
+type NotificationPreference struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_notification_preference" json:"user_id"`
+	Type      string    `gorm:"size:20;not null;unique_index:idx_notification_preference" json:"type"`
+	Frequency string    `gorm:"size:20;not null" json:"frequency"`
+	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+// EmailDigest is when the user was last sent the digest of a frequency
+type EmailDigest struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_email_digest" json:"user_id"`
+	Frequency string    `gorm:"size:20;not null;unique_index:idx_email_digest" json:"frequency"`
+	SentAt    time.Time `gorm:"not null" json:"sent_at"`
+}
+
+// DigestPeriod is how long apart the digests of the frequency are sent
+func DigestPeriod(frequency string) time.Duration {
+	if frequency == EmailWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+func isNotificationType(kind string) bool {
+	for _, notificationType := range NotificationTypes {
+		if kind == notificationType {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidatePreferences checks the kinds of notifications and the frequencies the user chose
+func ValidatePreferences(preferences map[string]string) map[string]string {
+	var errorMessages = make(map[string]string)
+	var err error
+
+	for kind, frequency := range preferences {
+		if !isNotificationType(kind) {
+			err = errors.New("Type should be like, comment, reply, mention or follow")
+			errorMessages["Invalid_type"] = err.Error()
+		}
+		switch frequency {
+		case EmailImmediate, EmailDaily, EmailWeekly, EmailOff:
+		default:
+			err = errors.New("Frequency should be immediate, daily, weekly or off")
+			errorMessages["Invalid_frequency"] = err.Error()
+		}
+	}
+	return errorMessages
+}
+
+// FindPreferences gives the email frequency of every kind of notification for the user
+func (np *NotificationPreference) FindPreferences(db *gorm.DB, uid uint32) (map[string]string, error) {
+	saved := []NotificationPreference{}
+	err := db.Debug().Model(&NotificationPreference{}).Where("user_id = ?", uid).Find(&saved).Error
+	if err != nil {
+		return map[string]string{}, err
+	}
+	preferences := map[string]string{}
+	for _, kind := range NotificationTypes {
+		preferences[kind] = DefaultEmailFrequency
+	}
+	for i, _ := range saved {
+		preferences[saved[i].Type] = saved[i].Frequency
+	}
+	return preferences, nil
+}
+
+// SavePreferences changes the email frequencies of the kinds given, the others stay as they are
+func (np *NotificationPreference) SavePreferences(db *gorm.DB, uid uint32, preferences map[string]string) (map[string]string, error) {
+	for kind, frequency := range preferences {
+		err := savePreference(db, uid, kind, frequency)
+		if err != nil {
+			return map[string]string{}, err
+		}
+	}
+	return np.FindPreferences(db, uid)
+}
+
+func savePreference(db *gorm.DB, uid uint32, kind, frequency string) error {
+	updates := map[string]interface{}{"frequency": frequency, "updated_at": time.Now()}
+	updated := db.Debug().Model(&NotificationPreference{}).Where("user_id = ? AND type = ?", uid, kind).UpdateColumns(updates)
+	if updated.Error != nil {
+		return updated.Error
+	}
+	if updated.RowsAffected > 0 {
+		return nil
+	}
+	preference := NotificationPreference{UserID: uid, Type: kind, Frequency: frequency, UpdatedAt: time.Now()}
+	err := db.Debug().Model(&NotificationPreference{}).Create(&preference).Error
+	if err != nil && isUniqueViolation(err) {
+		// Saved at the same time by another request, the latest wins
+		return db.Debug().Model(&NotificationPreference{}).Where("user_id = ? AND type = ?", uid, kind).UpdateColumns(updates).Error
+	}
+	return err
+}
+
+// Unsubscribe turns off the emails of the kind of notification, or of all of them with UnsubscribeAll
+func (np *NotificationPreference) Unsubscribe(db *gorm.DB, uid uint32, kind string) (map[string]string, error) {
+	preferences := map[string]string{}
+	if kind == UnsubscribeAll {
+		for _, notificationType := range NotificationTypes {
+			preferences[notificationType] = EmailOff
+		}
+	} else if isNotificationType(kind) {
+		preferences[kind] = EmailOff
+	} else {
+		return map[string]string{}, errors.New("Invalid notification type")
+	}
+	return np.SavePreferences(db, uid, preferences)
+}
+
+// DigestDue tells if the digest of the frequency was not sent to the user within its period
+func (ed *EmailDigest) DigestDue(db *gorm.DB, uid uint32, frequency string, now time.Time) (bool, error) {
+	last := EmailDigest{}
+	err := db.Debug().Model(&EmailDigest{}).Where("user_id = ? AND frequency = ?", uid, frequency).Take(&last).Error
+	if err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return !now.Before(last.SentAt.Add(DigestPeriod(frequency))), nil
+}
+
+// SaveDigestSent records that the digest of the frequency was sent to the user
+func (ed *EmailDigest) SaveDigestSent(db *gorm.DB, uid uint32, frequency string, now time.Time) error {
+	updated := db.Debug().Model(&EmailDigest{}).Where("user_id = ? AND frequency = ?", uid, frequency).UpdateColumn("sent_at", now)
+	if updated.Error != nil {
+		return updated.Error
+	}
+	if updated.RowsAffected > 0 {
+		return nil
+	}
+	digest := EmailDigest{UserID: uid, Frequency: frequency, SentAt: now}
+	return db.Debug().Model(&EmailDigest{}).Create(&digest).Error
+}
+
+// When a user is deleted, their email preferences and digests go
+func (np *NotificationPreference) DeleteUserPreferences(db *gorm.DB, uid uint32) (int64, error) {
+	err := db.Debug().Model(&EmailDigest{}).Where("user_id = ?", uid).Delete(&EmailDigest{}).Error
+	if err != nil {
+		return 0, err
+	}
+	db = db.Debug().Model(&NotificationPreference{}).Where("user_id = ?", uid).Delete(&NotificationPreference{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
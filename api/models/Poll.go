@@ -0,0 +1,222 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+)
+
+// A poll has at least two options and at most maxPollOptions
+const maxPollOptions = 10
+
+// The text of an option fits the column
+const maxPollOptionLength = 255
+
+// ErrDoubleVote is returned when a user votes on a poll they already voted on
+var ErrDoubleVote = errors.New("double vote")
+
+// Poll is attached to a post when it is created. Voters of an anonymous poll are never shown
+type Poll struct {
+	ID          uint64       `gorm:"primary_key;auto_increment" json:"id"`
+	PostID      uint64       `gorm:"not null;unique_index" json:"post_id"`
+	Multiple    bool         `gorm:"not null;default:false" json:"multiple"`
+	Anonymous   bool         `gorm:"not null;default:false" json:"anonymous"`
+	ClosesAt    *time.Time   `json:"closes_at"`
+	Options     []PollOption `gorm:"-" json:"options"`
+	Closed      bool         `gorm:"-" json:"closed"`
+	TotalVoters int          `gorm:"-" json:"total_voters"`
+	CreatedAt   time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+type PollOption struct {
+	ID       uint64 `gorm:"primary_key;auto_increment" json:"id"`
+	PollID   uint64 `gorm:"not null;index" json:"poll_id"`
+	Text     string `gorm:"size:255;not null" json:"text"`
+	Position int    `gorm:"not null" json:"position"`
+	Votes    int    `gorm:"-" json:"votes"`
+	Voters   []User `gorm:"-" json:"voters,omitempty"`
+}
+
+// PollVote is the single vote of a user on a poll. The unique index is what stops a second one,
+// so two requests sent at the same time cannot both get in
+type PollVote struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	PollID    uint64    `gorm:"not null;unique_index:idx_poll_vote_user" json:"poll_id"`
+	UserID    uint32    `gorm:"not null;unique_index:idx_poll_vote_user" json:"user_id"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// PollChoice is an option picked in a vote, a vote on a multiple choice poll has many
+type PollChoice struct {
+	ID       uint64 `gorm:"primary_key;auto_increment" json:"id"`
+	VoteID   uint64 `gorm:"not null;unique_index:idx_poll_choice_option" json:"vote_id"`
+	OptionID uint64 `gorm:"not null;unique_index:idx_poll_choice_option;index" json:"option_id"`
+}
+
+func (p *Poll) Prepare() {
+	for i, _ := range p.Options {
+		p.Options[i].ID = 0
+		p.Options[i].Text = strings.TrimSpace(p.Options[i].Text)
+		p.Options[i].Position = i + 1
+	}
+}
+
+func (p *Poll) Validate() map[string]string {
+
+	var errorMessages = make(map[string]string)
+
+	if len(p.Options) < 2 || len(p.Options) > maxPollOptions {
+		errorMessages["Invalid_poll_options"] = "A poll should have between 2 and 10 options"
+	}
+	for _, option := range p.Options {
+		if option.Text == "" {
+			errorMessages["Required_poll_option"] = "Required Option Text"
+		}
+		if utf8.RuneCountInString(option.Text) > maxPollOptionLength {
+			errorMessages["Invalid_poll_option"] = "Option Text should be at most 255 characters"
+		}
+	}
+	if p.ClosesAt != nil && !p.ClosesAt.After(time.Now()) {
+		errorMessages["Invalid_closes_at"] = "The poll should close in the future"
+	}
+	return errorMessages
+}
+
+func (p *Poll) IsClosed() bool {
+	return p.ClosesAt != nil && !p.ClosesAt.After(time.Now())
+}
+
+// SavePoll saves the poll and its options together
+func (p *Poll) SavePoll(db *gorm.DB) (*Poll, error) {
+	tx := db.Begin()
+	err := p.createWithOptions(tx)
+	if err != nil {
+		tx.Rollback()
+		return &Poll{}, err
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return &Poll{}, err
+	}
+	return p, nil
+}
+
+// createWithOptions saves the poll and its options in the transaction, which the caller commits
+func (p *Poll) createWithOptions(tx *gorm.DB) error {
+	err := tx.Debug().Model(&Poll{}).Create(&p).Error
+	if err != nil {
+		return err
+	}
+	for i, _ := range p.Options {
+		p.Options[i].PollID = p.ID
+		err = tx.Debug().Model(&PollOption{}).Create(&p.Options[i]).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindPostPoll gives the poll of the post with its results
+func (p *Poll) FindPostPoll(db *gorm.DB, pid uint64) (*Poll, error) {
+	var err error
+	err = db.Debug().Model(&Poll{}).Where("post_id = ?", pid).Take(&p).Error
+	if err != nil {
+		return &Poll{}, err
+	}
+	err = db.Debug().Model(&PollOption{}).Where("poll_id = ?", p.ID).Order("position").Find(&p.Options).Error
+	if err != nil {
+		return &Poll{}, err
+	}
+	err = db.Debug().Model(&PollVote{}).Where("poll_id = ?", p.ID).Count(&p.TotalVoters).Error
+	if err != nil {
+		return &Poll{}, err
+	}
+	for i, _ := range p.Options {
+		err = db.Debug().Model(&PollChoice{}).Where("option_id = ?", p.Options[i].ID).Count(&p.Options[i].Votes).Error
+		if err != nil {
+			return &Poll{}, err
+		}
+		if p.Anonymous {
+			continue
+		}
+		votes := db.Model(&PollVote{}).Select("poll_votes.user_id").Joins("JOIN poll_choices ON poll_choices.vote_id = poll_votes.id").Where("poll_choices.option_id = ?", p.Options[i].ID).QueryExpr()
+		err = db.Debug().Model(&User{}).Where("id IN (?)", votes).Find(&p.Options[i].Voters).Error
+		if err != nil {
+			return &Poll{}, err
+		}
+	}
+	p.Closed = p.IsClosed()
+	return p, nil
+}
+
+// SaveVote records the options the user picked. Whether they are options of this poll is checked by the caller
+func (v *PollVote) SaveVote(db *gorm.DB, optionIDs []uint64) (*PollVote, error) {
+	tx := db.Begin()
+	err := tx.Debug().Model(&PollVote{}).Create(&v).Error
+	if err != nil {
+		tx.Rollback()
+		if isUniqueViolation(err) {
+			return &PollVote{}, ErrDoubleVote
+		}
+		return &PollVote{}, err
+	}
+	for _, optionID := range optionIDs {
+		choice := PollChoice{VoteID: v.ID, OptionID: optionID}
+		err = tx.Debug().Model(&PollChoice{}).Create(&choice).Error
+		if err != nil {
+			tx.Rollback()
+			return &PollVote{}, err
+		}
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return &PollVote{}, err
+	}
+	return v, nil
+}
+
+// When a post is removed for good, its poll and the votes on it go with it
+func (p *Poll) DeletePostPoll(db *gorm.DB, pid uint64) (int64, error) {
+	polls := db.Model(&Poll{}).Select("id").Where("post_id = ?", pid).QueryExpr()
+	votes := db.Model(&PollVote{}).Select("id").Where("poll_id IN (?)", polls).QueryExpr()
+	err := db.Debug().Model(&PollChoice{}).Where("vote_id IN (?)", votes).Delete(&PollChoice{}).Error
+	if err != nil {
+		return 0, err
+	}
+	err = db.Debug().Model(&PollVote{}).Where("poll_id IN (?)", polls).Delete(&PollVote{}).Error
+	if err != nil {
+		return 0, err
+	}
+	err = db.Debug().Model(&PollOption{}).Where("poll_id IN (?)", polls).Delete(&PollOption{}).Error
+	if err != nil {
+		return 0, err
+	}
+	db = db.Debug().Model(&Poll{}).Where("post_id = ?", pid).Delete(&Poll{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// When a user is deleted, the votes of the user are deleted too
+func (v *PollVote) DeleteUserVotes(db *gorm.DB, uid uint32) (int64, error) {
+	votes := db.Model(&PollVote{}).Select("id").Where("user_id = ?", uid).QueryExpr()
+	err := db.Debug().Model(&PollChoice{}).Where("vote_id IN (?)", votes).Delete(&PollChoice{}).Error
+	if err != nil {
+		return 0, err
+	}
+	db = db.Debug().Model(&PollVote{}).Where("user_id = ?", uid).Delete(&PollVote{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// isUniqueViolation tells if the error is a unique constraint failing, on postgres or mysql
+func isUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "duplicate key value") || strings.Contains(err.Error(), "Duplicate entry")
+}
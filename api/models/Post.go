@@ -2,26 +2,64 @@ package models
 
 import (
 	"errors"
-	"html"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/utils/markdown"
 )
 
+// The representations of the content a client can ask for
+const (
+	FormatRaw  = "raw"
+	FormatHTML = "html"
+)
+
+// A post is only visible to everyone once it is published. Scheduled posts are published at PublishAt
+const (
+	PostDraft     = "draft"
+	PostScheduled = "scheduled"
+	PostPublished = "published"
+)
+
+// Moderators pin posts to the top of the list of all posts or of their category,
+// until PinnedUntil when the post is an announcement. Locked posts cannot be changed anymore
 type Post struct {
-	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
-	Title     string    `gorm:"size:255;not null;unique" json:"title"`
-	Content   string    `gorm:"text;not null;" json:"content"`
-	Author    User      `json:"author"`
-	AuthorID  uint32    `gorm:"not null" json:"author_id"`
-	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID               uint64       `gorm:"primary_key;auto_increment" json:"id"`
+	Title            string       `gorm:"size:255;not null" json:"title"`
+	Slug             string       `gorm:"size:255;unique_index" json:"slug"`
+	Content          string       `gorm:"text;not null;" json:"content,omitempty"`
+	ContentHTML      string       `gorm:"type:text" json:"content_html,omitempty"`
+	Author           User         `json:"author"`
+	AuthorID         uint32       `gorm:"not null" json:"author_id"`
+	Status           string       `gorm:"size:20;not null;default:'published';index" json:"status"`
+	PublishAt        *time.Time   `gorm:"index" json:"publish_at"`
+	Category         string       `gorm:"size:100;index" json:"category"`
+	PinnedGlobally   bool         `gorm:"not null;default:false" json:"pinned_globally"`
+	PinnedInCategory bool         `gorm:"not null;default:false" json:"pinned_in_category"`
+	PinnedUntil      *time.Time   `json:"pinned_until"`
+	Locked           bool         `gorm:"not null;default:false" json:"locked"`
+	Poll             *Poll        `gorm:"-" json:"poll,omitempty"`
+	Attachments      []Attachment `gorm:"-" json:"attachments,omitempty"`
+	Mentions         []Mention    `gorm:"-" json:"mentions,omitempty"`
+	CreatedAt        time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt        time.Time    `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt        *time.Time   `sql:"index" json:"deleted_at"`
 }
 
+// The content is kept as the Markdown the author wrote, it is only made safe when rendered to HTML
 func (p *Post) Prepare() {
-	p.Title = html.EscapeString(strings.TrimSpace(p.Title))
-	p.Content = html.EscapeString(strings.TrimSpace(p.Content))
+	p.Title = strings.TrimSpace(p.Title)
+	p.Content = strings.TrimSpace(p.Content)
+	p.Slug = ""
+	p.Category = strings.ToLower(strings.TrimSpace(p.Category))
+	p.PinnedGlobally = false
+	p.PinnedInCategory = false
+	p.PinnedUntil = nil
+	p.Locked = false
+	p.Attachments = nil
+	p.Mentions = nil
 	p.Author = User{}
 	p.CreatedAt = time.Now()
 	p.UpdatedAt = time.Now()
@@ -47,12 +85,87 @@ func (p *Post) Validate() map[string]string {
 		err = errors.New("Required Author")
 		errorMessages["Required_author"] = err.Error()
 	}
+	switch p.Status {
+	case "", PostDraft, PostPublished:
+		// Only the publication of a scheduled post can be in the future
+		if p.PublishAt != nil && p.PublishAt.After(time.Now()) {
+			err = errors.New("Only scheduled posts can have a publish time in the future")
+			errorMessages["Invalid_publish_at"] = err.Error()
+		}
+	case PostScheduled:
+		if p.PublishAt == nil || !p.PublishAt.After(time.Now()) {
+			err = errors.New("Scheduled posts need a publish time in the future")
+			errorMessages["Invalid_publish_at"] = err.Error()
+		}
+	default:
+		err = errors.New("Status should be draft, scheduled or published")
+		errorMessages["Invalid_status"] = err.Error()
+	}
 	return errorMessages
 }
 
+// PublishedPosts limits a query to the posts everyone can see
+func PublishedPosts(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", PostPublished)
+}
+
+// A post without a status is published right away. A published post keeps the time it was first published at,
+// given as publishedAt, or gets the current time: what the client sent is ignored, so nobody can date a post
+// to stay at the top of the lists
+func (p *Post) setPublication(publishedAt *time.Time) {
+	if p.Status == "" {
+		p.Status = PostPublished
+	}
+	switch p.Status {
+	case PostPublished:
+		if publishedAt == nil {
+			now := time.Now()
+			publishedAt = &now
+		}
+		p.PublishAt = publishedAt
+	case PostDraft:
+		p.PublishAt = nil
+	}
+}
+
+// Every post gets a slug made from its title when it is created
+func (p *Post) BeforeCreate(db *gorm.DB) error {
+	p.setPublication(nil)
+	if p.Slug != "" {
+		return nil
+	}
+	slug, err := uniqueSlug(db, p.Title, p.ID)
+	if err != nil {
+		return err
+	}
+	p.Slug = slug
+	return nil
+}
+
+// Format keeps only the representation of the content the client asked for
+func (p *Post) Format(format string) {
+	switch format {
+	case FormatRaw:
+		p.ContentHTML = ""
+	case FormatHTML:
+		p.Content = ""
+	}
+}
+
+// SavePost saves the new post with its first revision and its poll, if it has one. They are written together,
+// so a post is never left without them, and the post is only published once all of it is saved
 func (p *Post) SavePost(db *gorm.DB) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Create(&p).Error
+	p.ContentHTML = markdown.Render(p.Content)
+	for attempt := 1; ; attempt++ {
+		err = p.createWithHistory(db)
+		// Another post took the slug since it was picked, BeforeCreate picks the next one
+		if err != nil && isUniqueViolation(err) && attempt < slugAttempts {
+			p.Slug = ""
+			continue
+		}
+		break
+	}
 	if err != nil {
 		return &Post{}, err
 	}
@@ -61,14 +174,20 @@ func (p *Post) SavePost(db *gorm.DB) (*Post, error) {
 		if err != nil {
 			return &Post{}, err
 		}
+		p.syncMentions(db)
+		if p.Status == PostPublished {
+			OnEvent(EventPostPublished, p)
+		}
 	}
 	return p, nil
 }
 
-func (p *Post) FindAllPosts(db *gorm.DB) (*[]Post, error) {
+// FindAllPosts gives the latest published posts, pinned ones first.
+// The scopes given come last, so they can narrow the posts down or order them differently
+func (p *Post) FindAllPosts(db *gorm.DB, scopes ...func(*gorm.DB) *gorm.DB) (*[]Post, error) {
 	var err error
 	posts := []Post{}
-	err = db.Debug().Model(&Post{}).Limit(100).Order("created_at desc").Find(&posts).Error
+	err = db.Debug().Model(&Post{}).Scopes(PublishedPosts).Limit(100).Order("pinned_globally desc").Order("publish_at desc").Scopes(scopes...).Find(&posts).Error
 	if err != nil {
 		return &[]Post{}, err
 	}
@@ -85,7 +204,7 @@ func (p *Post) FindAllPosts(db *gorm.DB) (*[]Post, error) {
 
 func (p *Post) FindPostByID(db *gorm.DB, pid uint64) (*Post, error) {
 	var err error
-	err = db.Debug().Model(&Post{}).Where("id = ?", pid).Take(&p).Error
+	err = db.Debug().Model(&Post{}).Scopes(PublishedPosts).Where("id = ?", pid).Take(&p).Error
 	if err != nil {
 		return &Post{}, err
 	}
@@ -102,7 +221,115 @@ func (p *Post) UpdateAPost(db *gorm.DB) (*Post, error) {
 
 	var err error
 
-	err = db.Debug().Model(&Post{}).Where("id = ?", p.ID).Updates(Post{Title: p.Title, Content: p.Content, UpdatedAt: time.Now()}).Error
+	p.ContentHTML = markdown.Render(p.Content)
+	current := Post{}
+	err = db.Debug().Unscoped().Model(&Post{}).Where("id = ?", p.ID).Take(&current).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	if p.Status != "" {
+		var publishedAt *time.Time
+		if current.Status == PostPublished {
+			publishedAt = current.PublishAt
+		}
+		p.setPublication(publishedAt)
+	} else {
+		// The publication is only changed with the status
+		p.PublishAt = nil
+	}
+	for attempt := 1; ; attempt++ {
+		p.Slug, err = p.updatedSlug(db, &current)
+		if err != nil {
+			return &Post{}, err
+		}
+		err = db.Debug().Model(&Post{}).Where("id = ?", p.ID).Updates(Post{Title: p.Title, Slug: p.Slug, Content: p.Content, ContentHTML: p.ContentHTML, Status: p.Status, PublishAt: p.PublishAt, Category: p.Category, UpdatedAt: time.Now()}).Error
+		// Another post took the slug since it was picked, the next one is tried
+		if err != nil && isUniqueViolation(err) && attempt < slugAttempts {
+			continue
+		}
+		break
+	}
+	if err != nil {
+		return &Post{}, err
+	}
+	err = p.keepOldSlug(db, current.Slug)
+	if err != nil {
+		return &Post{}, err
+	}
+	// The status is only changed when given, so the post is read back as it is now
+	err = db.Debug().Model(&Post{}).Where("id = ?", p.ID).Take(&p).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	if p.ID != 0 {
+		err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+		if err != nil {
+			return &Post{}, err
+		}
+		p.syncMentions(db)
+	}
+	return p, nil
+}
+
+// createWithHistory saves the post, the post as first written as its first revision, and its poll, in one transaction
+func (p *Post) createWithHistory(db *gorm.DB) error {
+	// A failed attempt may have given the post an id
+	p.ID = 0
+	tx := db.Begin()
+	err := tx.Debug().Model(&Post{}).Create(&p).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	revision := Revision{
+		ResourceType: RevisionPost,
+		ResourceID:   p.ID,
+		EditorID:     p.AuthorID,
+		Title:        p.Title,
+		Content:      p.Content,
+		Number:       1,
+		CreatedAt:    time.Now(),
+	}
+	err = tx.Debug().Model(&Revision{}).Create(&revision).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if p.Poll != nil {
+		p.Poll.PostID = p.ID
+		err = p.Poll.createWithOptions(tx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
+// When the title changes, the post gets a new slug
+func (p *Post) updatedSlug(db *gorm.DB, current *Post) (string, error) {
+	if current.Slug != "" && sameSlugBase(current.Slug, p.Title) {
+		return current.Slug, nil
+	}
+	return uniqueSlug(db, p.Title, p.ID)
+}
+
+// keepOldSlug keeps the former slug of the post to redirect old links, once the post has its new one
+func (p *Post) keepOldSlug(db *gorm.DB, oldSlug string) error {
+	if oldSlug == "" || oldSlug == p.Slug {
+		return nil
+	}
+	// The post may get back a slug it had before, which is then no longer an old one
+	err := db.Debug().Model(&PostSlug{}).Where("post_id = ? AND slug = ?", p.ID, p.Slug).Delete(&PostSlug{}).Error
+	if err != nil {
+		return err
+	}
+	return db.Debug().Model(&PostSlug{}).Create(&PostSlug{PostID: p.ID, Slug: oldSlug, CreatedAt: time.Now()}).Error
+}
+
+func (p *Post) FindPostBySlug(db *gorm.DB, slug string) (*Post, error) {
+	var err error
+	err = db.Debug().Model(&Post{}).Scopes(PublishedPosts).Where("slug = ?", slug).Take(&p).Error
 	if err != nil {
 		return &Post{}, err
 	}
@@ -128,7 +355,7 @@ func (p *Post) FindUserPosts(db *gorm.DB, uid uint32) (*[]Post, error) {
 
 	var err error
 	posts := []Post{}
-	err = db.Debug().Model(&Post{}).Where("author_id = ?", uid).Limit(100).Order("created_at desc").Find(&posts).Error
+	err = db.Debug().Model(&Post{}).Scopes(PublishedPosts).Where("author_id = ?", uid).Limit(100).Order("publish_at desc").Find(&posts).Error
 	if err != nil {
 		return &[]Post{}, err
 	}
@@ -146,7 +373,200 @@ func (p *Post) FindUserPosts(db *gorm.DB, uid uint32) (*[]Post, error) {
 //When a user is deleted, we also delete the post that the user had
 func (c *Post) DeleteUserPosts(db *gorm.DB, uid uint32) (int64, error) {
 	posts := []Post{}
-	db = db.Debug().Model(&Post{}).Where("author_id = ?", uid).Find(&posts).Delete(&posts)
+	db = db.Debug().Unscoped().Model(&Post{}).Where("author_id = ?", uid).Find(&posts).Delete(&posts)
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+//The posts the user deleted, which are still in the trash
+func (p *Post) FindDeletedPosts(db *gorm.DB, uid uint32) (*[]Post, error) {
+	var err error
+	posts := []Post{}
+	err = db.Debug().Unscoped().Model(&Post{}).Where("author_id = ? AND deleted_at IS NOT NULL", uid).Order("deleted_at desc").Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+	return &posts, nil
+}
+
+func (p *Post) RestoreAPost(db *gorm.DB) (*Post, error) {
+
+	var err error
+
+	err = db.Debug().Unscoped().Model(&Post{}).Where("id = ? AND deleted_at IS NOT NULL", p.ID).Take(&Post{}).UpdateColumn("deleted_at", gorm.Expr("NULL")).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	// The restored post may be a draft, so it is not looked up as a published one
+	err = db.Debug().Model(&Post{}).Where("id = ?", p.ID).Take(&p).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	err = db.Debug().Model(&User{}).Where("id = ?", p.AuthorID).Take(&p.Author).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	return p, nil
+}
+
+//Posts that stayed in the trash beyond the retention period are removed for good, alongside their likes, comments and polls
+func (p *Post) PurgeDeletedPosts(db *gorm.DB, before time.Time) (int64, error) {
+	posts := []Post{}
+	err := db.Debug().Unscoped().Model(&Post{}).Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Find(&posts).Error
+	if err != nil {
+		return 0, err
+	}
+	comment := Comment{}
+	like := Like{}
+	revision := Revision{}
+	postSlug := PostSlug{}
+	poll := Poll{}
+	mention := Mention{}
+	for i, _ := range posts {
+		_, err = revision.DeletePostRevisions(db, posts[i].ID)
+		if err != nil {
+			return 0, err
+		}
+		_, err = postSlug.DeletePostSlugs(db, posts[i].ID)
+		if err != nil {
+			return 0, err
+		}
+		_, err = comment.DeletePostComments(db, posts[i].ID)
+		if err != nil {
+			return 0, err
+		}
+		_, err = like.DeletePostLikes(db, posts[i].ID)
+		if err != nil {
+			return 0, err
+		}
+		_, err = poll.DeletePostPoll(db, posts[i].ID)
+		if err != nil {
+			return 0, err
+		}
+		_, err = mention.DeletePostMentions(db, posts[i].ID)
+		if err != nil {
+			return 0, err
+		}
+	}
+	db = db.Debug().Unscoped().Model(&Post{}).Where("deleted_at IS NOT NULL AND deleted_at < ?", before).Delete(&Post{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
+
+// The drafts and scheduled posts of the author, which only the author can see
+func (p *Post) FindUserDrafts(db *gorm.DB, uid uint32) (*[]Post, error) {
+	var err error
+	posts := []Post{}
+	err = db.Debug().Model(&Post{}).Where("author_id = ? AND status IN (?)", uid, []string{PostDraft, PostScheduled}).Order("updated_at desc").Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+	return &posts, nil
+}
+
+// PublishDuePosts publishes the scheduled posts whose time has come, and picks up what was missed while the API was down.
+// Each post is published with an update that only applies while it is still scheduled, so it is safe to run
+// from many instances at once: only the instance that published a post tells about it
+func (p *Post) PublishDuePosts(db *gorm.DB) (int64, error) {
+	posts := []Post{}
+	err := db.Debug().Model(&Post{}).Where("status = ? AND publish_at <= ?", PostScheduled, time.Now()).Find(&posts).Error
+	if err != nil {
+		return 0, err
+	}
+	var published int64
+	for i, _ := range posts {
+		update := db.Debug().Model(&Post{}).Where("id = ? AND status = ?", posts[i].ID, PostScheduled).UpdateColumns(
+			map[string]interface{}{
+				"status":     PostPublished,
+				"updated_at": time.Now(),
+			},
+		)
+		if update.Error != nil {
+			return published, update.Error
+		}
+		if update.RowsAffected == 0 {
+			continue
+		}
+		published++
+		posts[i].Status = PostPublished
+		// The users mentioned in a scheduled post are told when it comes out
+		err = (&Mention{}).NotifyMentions(db, posts[i].ID, 0)
+		if err != nil {
+			fmt.Println("cannot notify the mentions of the published post: ", err)
+		}
+		err = db.Debug().Model(&User{}).Where("id = ?", posts[i].AuthorID).Take(&posts[i].Author).Error
+		if err != nil {
+			fmt.Println("cannot find the author of the published post: ", err)
+			continue
+		}
+		OnEvent(EventPostPublished, &posts[i])
+	}
+	return published, nil
+}
+
+// The posts of a category, with the ones pinned globally or in the category first
+func (p *Post) FindCategoryPosts(db *gorm.DB, category string, scopes ...func(*gorm.DB) *gorm.DB) (*[]Post, error) {
+	var err error
+	posts := []Post{}
+	err = db.Debug().Model(&Post{}).Scopes(PublishedPosts).Where("category = ?", category).Limit(100).Order("(pinned_globally OR pinned_in_category) desc").Order("publish_at desc").Scopes(scopes...).Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+	if len(posts) > 0 {
+		for i, _ := range posts {
+			err := db.Debug().Model(&User{}).Where("id = ?", posts[i].AuthorID).Take(&posts[i].Author).Error
+			if err != nil {
+				return &[]Post{}, err
+			}
+		}
+	}
+	return &posts, nil
+}
+
+// Pin pins the post globally or in its category. A post pinned until a given time is an announcement
+func (p *Post) Pin(db *gorm.DB, globally, inCategory bool, until *time.Time) (*Post, error) {
+	err := db.Debug().Model(&Post{}).Where("id = ?", p.ID).Take(&Post{}).UpdateColumns(
+		map[string]interface{}{
+			"pinned_globally":    globally,
+			"pinned_in_category": inCategory,
+			"pinned_until":       until,
+		},
+	).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	return p.FindPostByID(db, p.ID)
+}
+
+func (p *Post) Unpin(db *gorm.DB) (*Post, error) {
+	return p.Pin(db, false, false, nil)
+}
+
+func (p *Post) SetLocked(db *gorm.DB, locked bool) (*Post, error) {
+	err := db.Debug().Model(&Post{}).Where("id = ?", p.ID).Take(&Post{}).UpdateColumns(
+		map[string]interface{}{
+			"locked": locked,
+		},
+	).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	return p.FindPostByID(db, p.ID)
+}
+
+// UnpinExpiredAnnouncements unpins the announcements whose time is over
+func (p *Post) UnpinExpiredAnnouncements(db *gorm.DB) (int64, error) {
+	db = db.Debug().Model(&Post{}).Where("pinned_until IS NOT NULL AND pinned_until <= ?", time.Now()).UpdateColumns(
+		map[string]interface{}{
+			"pinned_globally":    false,
+			"pinned_in_category": false,
+			"pinned_until":       gorm.Expr("NULL"),
+		},
+	)
 	if db.Error != nil {
 		return 0, db.Error
 	}
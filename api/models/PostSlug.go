@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// PostSlug keeps the slugs a post had before its title was edited, so old links still lead to it
+type PostSlug struct {
+	ID        uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	PostID    uint64    `gorm:"not null;index" json:"post_id"`
+	Slug      string    `gorm:"size:255;not null;unique_index" json:"slug"`
+	CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// Leave room for the collision suffix within the size of the column
+const maxSlugLength = 200
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slugify turns a title into the lowercase, dash separated form used in urls
+func Slugify(title string) string {
+	slug := nonSlugChars.ReplaceAllString(strings.ToLower(title), "-")
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+	}
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		slug = "post"
+	}
+	return slug
+}
+
+// How many slugs are tried when other posts keep taking the one picked
+const slugAttempts = 5
+
+// uniqueSlug gives the slug of the title, with "-2", "-3"... added when other posts already use it.
+// A slug is taken when another post uses it now or used it before, they are all read at once
+func uniqueSlug(db *gorm.DB, title string, pid uint64) (string, error) {
+	base := Slugify(title)
+	var taken []string
+	err := db.Debug().Unscoped().Model(&Post{}).Where("(slug = ? OR slug LIKE ?) AND id <> ?", base, base+"-%", pid).Pluck("slug", &taken).Error
+	if err != nil {
+		return "", err
+	}
+	var takenBefore []string
+	err = db.Debug().Model(&PostSlug{}).Where("(slug = ? OR slug LIKE ?) AND post_id <> ?", base, base+"-%", pid).Pluck("slug", &takenBefore).Error
+	if err != nil {
+		return "", err
+	}
+	used := map[string]bool{}
+	for _, slug := range append(taken, takenBefore...) {
+		used[slug] = true
+	}
+	slug := base
+	for i := 2; used[slug]; i++ {
+		slug = fmt.Sprintf("%s-%d", base, i)
+	}
+	return slug, nil
+}
+
+// sameSlugBase tells if the slug was generated from the title, with or without a collision suffix
+func sameSlugBase(slug, title string) bool {
+	base := Slugify(title)
+	if slug == base {
+		return true
+	}
+	suffix := strings.TrimPrefix(slug, base+"-")
+	if suffix == slug {
+		return false
+	}
+	_, err := strconv.Atoi(suffix)
+	return err == nil
+}
+
+// FindPostBySlug gives the post an old slug belonged to
+func (ps *PostSlug) FindPostBySlug(db *gorm.DB, slug string) (*Post, error) {
+	var err error
+	err = db.Debug().Model(&PostSlug{}).Where("slug = ?", slug).Take(&ps).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	post := Post{}
+	return post.FindPostByID(db, ps.PostID)
+}
+
+// When a post is removed for good, its old slugs can be used again
+func (ps *PostSlug) DeletePostSlugs(db *gorm.DB, pid uint64) (int64, error) {
+	db = db.Debug().Model(&PostSlug{}).Where("post_id = ?", pid).Delete(&PostSlug{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// How many of the latest posts and comments a profile shows
+const profileActivityLimit = 5
+
+// Profile is the public view of a user. The email and the activity are only filled
+// when the user chose to show them, or when the viewer is the user or an admin
+type Profile struct {
+	ID             uint32            `json:"id"`
+	Username       string            `json:"username"`
+	Bio            string            `json:"bio"`
+	AvatarPath     string            `json:"avatar_path"`
+	AvatarURLs     map[string]string `json:"avatar_urls"`
+	JoinedAt       time.Time         `json:"joined_at"`
+	Email          string            `json:"email,omitempty"`
+	PostCount      *int              `json:"post_count,omitempty"`
+	CommentCount   *int              `json:"comment_count,omitempty"`
+	RecentPosts    []ProfilePost     `json:"recent_posts,omitempty"`
+	RecentComments []ProfileComment  `json:"recent_comments,omitempty"`
+}
+
+type ProfilePost struct {
+	ID        uint64     `json:"id"`
+	Title     string     `json:"title"`
+	Slug      string     `json:"slug"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+type ProfileComment struct {
+	ID        uint64    `json:"id"`
+	PostID    uint64    `json:"post_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FindProfile gives the profile of the user with the username, as the viewer sees it.
+// The viewer is nil for visitors who are not logged in
+func (u *User) FindProfile(db *gorm.DB, username string, viewer *User) (*Profile, error) {
+	user := User{}
+	// Closed accounts have no profile. The username is compared without case, as the old usernames are
+	err := db.Debug().Model(&User{}).Where("LOWER(username) = LOWER(?) AND deactivated_at IS NULL", username).Order("id asc").Take(&user).Error
+	if err != nil {
+		return &Profile{}, err
+	}
+	profile := Profile{
+		ID:         user.ID,
+		Username:   user.Username,
+		Bio:        user.Bio,
+		AvatarPath: user.AvatarPath,
+		AvatarURLs: user.AvatarURLs,
+		JoinedAt:   user.CreatedAt,
+	}
+	full := viewer != nil && (viewer.ID == user.ID || viewer.IsAdmin())
+	if full || user.ShowEmail {
+		profile.Email = user.Email
+	}
+	if !full && !user.ShowActivity {
+		return &profile, nil
+	}
+
+	// Only the published posts, and the comments on them, are counted
+	var postCount, commentCount int
+	err = db.Debug().Model(&Post{}).Scopes(PublishedPosts).Where("author_id = ?", user.ID).Count(&postCount).Error
+	if err != nil {
+		return &Profile{}, err
+	}
+	published := db.New().Model(&Post{}).Scopes(PublishedPosts).Select("id").QueryExpr()
+	err = db.Debug().Model(&Comment{}).Where("user_id = ? AND post_id IN (?)", user.ID, published).Count(&commentCount).Error
+	if err != nil {
+		return &Profile{}, err
+	}
+	profile.PostCount = &postCount
+	profile.CommentCount = &commentCount
+
+	profile.RecentPosts = []ProfilePost{}
+	err = db.Debug().Model(&Post{}).Scopes(PublishedPosts).Where("author_id = ?", user.ID).Order("publish_at desc").Limit(profileActivityLimit).Select("id, title, slug, publish_at").Scan(&profile.RecentPosts).Error
+	if err != nil {
+		return &Profile{}, err
+	}
+	profile.RecentComments = []ProfileComment{}
+	err = db.Debug().Model(&Comment{}).Where("user_id = ? AND post_id IN (?)", user.ID, published).Order("created_at desc").Limit(profileActivityLimit).Select("id, post_id, body, created_at").Scan(&profile.RecentComments).Error
+	if err != nil {
+		return &Profile{}, err
+	}
+	return &profile, nil
+}
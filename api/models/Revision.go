@@ -0,0 +1,192 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// The kind of content a revision belongs to
+const (
+	RevisionPost    = "post"
+	RevisionComment = "comment"
+)
+
+// Revision is a snapshot of a post or a comment, stored every time it is written
+type Revision struct {
+	ID           uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	ResourceType string    `gorm:"size:20;not null;index:idx_revision_resource;unique_index:idx_revision_number" json:"resource_type"`
+	ResourceID   uint64    `gorm:"not null;index:idx_revision_resource;unique_index:idx_revision_number" json:"resource_id"`
+	Number       int       `gorm:"not null;unique_index:idx_revision_number" json:"number"`
+	EditorID     uint32    `gorm:"not null" json:"editor_id"`
+	Editor       User      `json:"editor"`
+	Title        string    `gorm:"size:255" json:"title"`
+	Content      string    `gorm:"text;not null;" json:"content"`
+	Note         string    `gorm:"size:255" json:"note"`
+	CreatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// How many times a revision is numbered again when another one took its number meanwhile
+const revisionNumberAttempts = 5
+
+func (r *Revision) saveRevision(db *gorm.DB) (*Revision, error) {
+	var err error
+	for attempt := 0; attempt < revisionNumberAttempts; attempt++ {
+		err = r.numberAndCreate(db)
+		if err == nil {
+			return r, nil
+		}
+		if !isUniqueViolation(err) {
+			return &Revision{}, err
+		}
+	}
+	return &Revision{}, err
+}
+
+// numberAndCreate gives the revision the number after the latest one and saves it, in one transaction.
+// The post or comment is locked meanwhile, and the unique index catches what the lock does not
+func (r *Revision) numberAndCreate(db *gorm.DB) error {
+	var err error
+	var latest Revision
+
+	tx := db.Begin()
+	if r.ResourceType == RevisionPost {
+		err = tx.Debug().Unscoped().Set("gorm:query_option", "FOR UPDATE").Model(&Post{}).Where("id = ?", r.ResourceID).Take(&Post{}).Error
+	} else {
+		err = tx.Debug().Unscoped().Set("gorm:query_option", "FOR UPDATE").Model(&Comment{}).Where("id = ?", r.ResourceID).Take(&Comment{}).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	// The revisions are numbered per post or comment, starting from 1
+	err = tx.Debug().Model(&Revision{}).Where("resource_type = ? AND resource_id = ?", r.ResourceType, r.ResourceID).Order("number desc").Take(&latest).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		tx.Rollback()
+		return err
+	}
+	r.ID = 0
+	r.Number = latest.Number + 1
+	r.CreatedAt = time.Now()
+
+	err = tx.Debug().Model(&Revision{}).Create(&r).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func (r *Revision) SavePostRevision(db *gorm.DB, p *Post, editorID uint32, note string) (*Revision, error) {
+	r.ResourceType = RevisionPost
+	r.ResourceID = p.ID
+	r.EditorID = editorID
+	r.Title = p.Title
+	r.Content = p.Content
+	r.Note = note
+	return r.saveRevision(db)
+}
+
+func (r *Revision) SaveCommentRevision(db *gorm.DB, c *Comment, editorID uint32, note string) (*Revision, error) {
+	r.ResourceType = RevisionComment
+	r.ResourceID = c.ID
+	r.EditorID = editorID
+	r.Content = c.Body
+	r.Note = note
+	return r.saveRevision(db)
+}
+
+// Content written before revisions existed has no history, so the current state becomes the first revision
+func (r *Revision) EnsurePostHistory(db *gorm.DB, p *Post) error {
+	var count int
+	err := db.Debug().Model(&Revision{}).Where("resource_type = ? AND resource_id = ?", RevisionPost, p.ID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		_, err = (&Revision{}).SavePostRevision(db, p, p.AuthorID, "")
+	}
+	return err
+}
+
+func (r *Revision) EnsureCommentHistory(db *gorm.DB, c *Comment) error {
+	var count int
+	err := db.Debug().Model(&Revision{}).Where("resource_type = ? AND resource_id = ?", RevisionComment, c.ID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		_, err = (&Revision{}).SaveCommentRevision(db, c, c.UserID, "")
+	}
+	return err
+}
+
+func (r *Revision) FindRevisions(db *gorm.DB, resourceType string, rid uint64) (*[]Revision, error) {
+	var err error
+	revisions := []Revision{}
+	err = db.Debug().Model(&Revision{}).Where("resource_type = ? AND resource_id = ?", resourceType, rid).Order("number desc").Find(&revisions).Error
+	if err != nil {
+		return &[]Revision{}, err
+	}
+	if len(revisions) > 0 {
+		for i, _ := range revisions {
+			err := db.Debug().Model(&User{}).Where("id = ?", revisions[i].EditorID).Take(&revisions[i].Editor).Error
+			if err != nil {
+				return &[]Revision{}, err
+			}
+		}
+	}
+	return &revisions, nil
+}
+
+func (r *Revision) FindRevision(db *gorm.DB, resourceType string, rid uint64, number int) (*Revision, error) {
+	var err error
+	err = db.Debug().Model(&Revision{}).Where("resource_type = ? AND resource_id = ? AND number = ?", resourceType, rid, number).Take(&r).Error
+	if err != nil {
+		return &Revision{}, err
+	}
+	err = db.Debug().Model(&User{}).Where("id = ?", r.EditorID).Take(&r.Editor).Error
+	if err != nil {
+		return &Revision{}, err
+	}
+	return r, nil
+}
+
+func (r *Revision) text() string {
+	if r.ResourceType == RevisionPost {
+		return r.Title + "\n\n" + r.Content + "\n"
+	}
+	return r.Content + "\n"
+}
+
+// Diff gives the unified diff that turns this revision into the other one
+func (r *Revision) Diff(other *Revision) (string, error) {
+	if r.ResourceType != other.ResourceType || r.ResourceID != other.ResourceID {
+		return "", errors.New("revisions of different content")
+	}
+	diff := difflib.UnifiedDiff{
+		A:        difflib.SplitLines(r.text()),
+		B:        difflib.SplitLines(other.text()),
+		FromFile: fmt.Sprintf("revision %d", r.Number),
+		ToFile:   fmt.Sprintf("revision %d", other.Number),
+		Context:  3,
+	}
+	return difflib.GetUnifiedDiffString(diff)
+}
+
+func (r *Revision) RollbackNote() string {
+	return fmt.Sprintf("Rolled back to revision %d", r.Number)
+}
+
+// When a post is removed for good, its history and the history of its comments go with it
+func (r *Revision) DeletePostRevisions(db *gorm.DB, pid uint64) (int64, error) {
+	comments := db.Unscoped().Model(&Comment{}).Select("id").Where("post_id = ?", pid).QueryExpr()
+	db = db.Debug().Model(&Revision{}).Where("(resource_type = ? AND resource_id = ?) OR (resource_type = ? AND resource_id IN (?))", RevisionPost, pid, RevisionComment, comments).Delete(&Revision{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
@@ -4,24 +4,49 @@ import (
 	"errors"
 	"html"
 	"log"
-	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/victorsteven/forum/api/fileupload"
 	"github.com/victorsteven/forum/api/security"
+	"github.com/victorsteven/forum/api/utils/identicon"
 
 	"github.com/badoux/checkmail"
 	"github.com/jinzhu/gorm"
 )
 
+// The roles a user can have. Moderators and admins can moderate the content of other users
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+// A locale is a language, and maybe its region
+var localePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+
 type User struct {
-	ID         uint32    `gorm:"primary_key;auto_increment" json:"id"`
-	Username   string    `gorm:"size:255;not null;unique" json:"username"`
-	Email      string    `gorm:"size:100;not null;unique" json:"email"`
-	Password   string    `gorm:"size:100;not null;" json:"password"`
-	AvatarPath string    `gorm:"size:255;null;" json:"avatar_path"`
-	CreatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	ID         uint32            `gorm:"primary_key;auto_increment" json:"id"`
+	Username   string            `gorm:"size:255;not null;unique" json:"username"`
+	Email      string            `gorm:"size:100;not null;unique" json:"email"`
+	Password   string            `gorm:"size:100;not null;" json:"password"`
+	AvatarPath string            `gorm:"size:255;null;" json:"avatar_path"`
+	AvatarURLs map[string]string `gorm:"-" json:"avatar_urls"`
+	Role       string            `gorm:"size:20;not null;default:'user'" json:"role"`
+	Bio        string            `gorm:"size:500" json:"bio"`
+	// What the others can see on the profile of the user
+	ShowEmail    bool `gorm:"not null;default:false" json:"show_email"`
+	ShowActivity bool `gorm:"not null;default:true" json:"show_activity"`
+	// The language the emails are written in, like en or fr-CA
+	Locale string `gorm:"size:10;not null;default:'en'" json:"locale"`
+	// The accounts the app keeps for itself, like the deleted user. Nobody can log in as them
+	SystemAccount bool `gorm:"not null;default:false;index" json:"-"`
+	// Set while the account is closed, the user gets it back by logging in
+	DeactivatedAt *time.Time `json:"deactivated_at"`
+	CreatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
 
 func (u *User) BeforeSave() error {
@@ -36,6 +61,7 @@ func (u *User) BeforeSave() error {
 func (u *User) Prepare() {
 	u.Username = html.EscapeString(strings.TrimSpace(u.Username))
 	u.Email = html.EscapeString(strings.TrimSpace(u.Email))
+	u.Locale = strings.TrimSpace(u.Locale)
 	u.CreatedAt = time.Now()
 	u.UpdatedAt = time.Now()
 }
@@ -45,18 +71,43 @@ func (u *User) AfterFind() (err error) {
 		return err
 	}
 	if u.AvatarPath != "" {
-		u.AvatarPath = os.Getenv("DO_SPACES_URL") + u.AvatarPath
+		u.AvatarURLs = fileupload.AvatarURLs(u.AvatarPath)
+		u.AvatarPath = fileupload.URL(u.AvatarPath)
+	} else if u.Username != "" {
+		// Users without an avatar of their own get one generated from their username
+		u.AvatarPath = identicon.URL(u.Username)
+		u.AvatarURLs = map[string]string{}
+		for _, size := range fileupload.AvatarSizes {
+			u.AvatarURLs[strconv.Itoa(size)] = u.AvatarPath + "?size=" + strconv.Itoa(size)
+		}
 	}
 	//dont return the user password
 	// u.Password = ""
 	return nil
 }
 
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator || u.Role == RoleAdmin
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
 func (u *User) Validate(action string) map[string]string {
 	var errorMessages = make(map[string]string)
 	var err error
 
 	switch strings.ToLower(action) {
+	case "profile":
+		if len(u.Bio) > 500 {
+			err = errors.New("Bio should be at most 500 characters")
+			errorMessages["Invalid_bio"] = err.Error()
+		}
+		if u.Locale != "" && !localePattern.MatchString(u.Locale) {
+			err = errors.New("Locale should be a language code like en or fr-CA")
+			errorMessages["Invalid_locale"] = err.Error()
+		}
 	case "update":
 		if u.Email == "" {
 			err = errors.New("Required Email")
@@ -68,6 +119,10 @@ func (u *User) Validate(action string) map[string]string {
 				errorMessages["Invalid_email"] = err.Error()
 			}
 		}
+		if strings.EqualFold(u.Email, DeletedUserEmail) {
+			err = errors.New("This email is reserved")
+			errorMessages["Reserved_email"] = err.Error()
+		}
 
 	case "login":
 		if u.Password == "" {
@@ -100,6 +155,12 @@ func (u *User) Validate(action string) map[string]string {
 			err = errors.New("Required Username")
 			errorMessages["Required_username"] = err.Error()
 		}
+		// A new username follows the same rules as a changed one
+		if u.Username != "" {
+			for key, message := range ValidateUsername(u.Username) {
+				errorMessages[key] = message
+			}
+		}
 		if u.Password == "" {
 			err = errors.New("Required Password")
 			errorMessages["Required_password"] = err.Error()
@@ -119,6 +180,14 @@ func (u *User) Validate(action string) map[string]string {
 				errorMessages["Invalid_email"] = err.Error()
 			}
 		}
+		if strings.EqualFold(u.Email, DeletedUserEmail) {
+			err = errors.New("This email is reserved")
+			errorMessages["Reserved_email"] = err.Error()
+		}
+		if u.Locale != "" && !localePattern.MatchString(u.Locale) {
+			err = errors.New("Locale should be a language code like en or fr-CA")
+			errorMessages["Invalid_locale"] = err.Error()
+		}
 	}
 	return errorMessages
 }
@@ -126,6 +195,15 @@ func (u *User) Validate(action string) map[string]string {
 func (u *User) SaveUser(db *gorm.DB) (*User, error) {
 
 	var err error
+	// A username someone recently left still leads to them, so it cannot be taken yet
+	var count int
+	err = db.Debug().Model(&UsernameChange{}).Where("LOWER(old_username) = LOWER(?) AND created_at > ?", u.Username, time.Now().Add(-UsernameGracePeriod)).Count(&count).Error
+	if err != nil {
+		return &User{}, err
+	}
+	if count > 0 {
+		return &User{}, ErrUsernameTaken
+	}
 	err = db.Debug().Create(&u).Error
 	if err != nil {
 		return &User{}, err
@@ -210,6 +288,28 @@ func (u *User) UpdateAUserAvatar(db *gorm.DB, uid uint32) (*User, error) {
 	return u, nil
 }
 
+// UpdateAUserProfile saves the bio and the visibility settings of the user
+func (u *User) UpdateAUserProfile(db *gorm.DB, uid uint32) (*User, error) {
+	db = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&User{}).UpdateColumns(
+		map[string]interface{}{
+			"bio":           u.Bio,
+			"show_email":    u.ShowEmail,
+			"show_activity": u.ShowActivity,
+			"locale":        u.Locale,
+			"updated_at":    time.Now(),
+		},
+	)
+	if db.Error != nil {
+		return &User{}, db.Error
+	}
+	// This is the display the updated user
+	err := db.Debug().Model(&User{}).Where("id = ?", uid).Take(&u).Error
+	if err != nil {
+		return &User{}, err
+	}
+	return u, nil
+}
+
 func (u *User) DeleteAUser(db *gorm.DB, uid uint32) (int64, error) {
 
 	db = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&User{}).Delete(&User{})
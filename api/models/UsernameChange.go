@@ -0,0 +1,169 @@
+package models
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/utils/profanity"
+)
+
+// UsernameChange keeps the usernames a user had. For a grace period the old username
+// still leads to the user, and nobody else can take it
+type UsernameChange struct {
+	ID          uint64    `gorm:"primary_key;auto_increment" json:"id"`
+	UserID      uint32    `gorm:"not null;index" json:"user_id"`
+	OldUsername string    `gorm:"size:255;not null;index" json:"old_username"`
+	NewUsername string    `gorm:"size:255;not null" json:"new_username"`
+	CreatedAt   time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+const (
+	// How long a user waits between two changes of their username
+	UsernameCooldown = 30 * 24 * time.Hour
+	// How long an old username keeps leading to the user
+	UsernameGracePeriod = 90 * 24 * time.Hour
+)
+
+var (
+	ErrUsernameCooldown = errors.New("username changed too recently")
+	ErrUsernameTaken    = errors.New("username taken")
+)
+
+var usernameFormat = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+// Names that could pass for the staff or for a part of the API
+var reservedUsernames = map[string]bool{
+	"admin":         true,
+	"administrator": true,
+	"anonymous":     true,
+	"api":           true,
+	"deleted":       true,
+	"me":            true,
+	"moderator":     true,
+	"mod":           true,
+	"null":          true,
+	"root":          true,
+	"staff":         true,
+	"support":       true,
+	"system":        true,
+}
+
+// ValidateUsername checks a new username: its format, and that it is neither reserved nor offensive
+func ValidateUsername(username string) map[string]string {
+	var errorMessages = make(map[string]string)
+	if !usernameFormat.MatchString(username) {
+		errorMessages["Invalid_username"] = "Username should be 3 to 30 letters, digits or underscores"
+		return errorMessages
+	}
+	if reservedUsernames[strings.ToLower(username)] {
+		errorMessages["Reserved_username"] = "This username is reserved"
+	}
+	if profanity.Contains(username) {
+		errorMessages["Offensive_username"] = "This username is not allowed"
+	}
+	return errorMessages
+}
+
+// ChangeUsername renames the user and records the old username
+func (u *User) ChangeUsername(db *gorm.DB, uid uint32, username string) (*User, error) {
+	user := User{}
+	err := db.Debug().Model(&User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		return &User{}, err
+	}
+	if user.Username == username {
+		return &user, nil
+	}
+	var count int
+	err = db.Debug().Model(&UsernameChange{}).Where("user_id = ? AND created_at > ?", uid, time.Now().Add(-UsernameCooldown)).Count(&count).Error
+	if err != nil {
+		return &User{}, err
+	}
+	if count > 0 {
+		return &User{}, ErrUsernameCooldown
+	}
+	// The username must be free, also from the users who recently left it. A user can take back their own
+	err = db.Debug().Model(&User{}).Where("LOWER(username) = LOWER(?) AND id <> ?", username, uid).Count(&count).Error
+	if err != nil {
+		return &User{}, err
+	}
+	if count > 0 {
+		return &User{}, ErrUsernameTaken
+	}
+	err = db.Debug().Model(&UsernameChange{}).Where("LOWER(old_username) = LOWER(?) AND user_id <> ? AND created_at > ?", username, uid, time.Now().Add(-UsernameGracePeriod)).Count(&count).Error
+	if err != nil {
+		return &User{}, err
+	}
+	if count > 0 {
+		return &User{}, ErrUsernameTaken
+	}
+
+	tx := db.Begin()
+	err = tx.Debug().Model(&User{}).Where("id = ?", uid).UpdateColumns(
+		map[string]interface{}{
+			"username":   username,
+			"updated_at": time.Now(),
+		},
+	).Error
+	if err != nil {
+		tx.Rollback()
+		if isUniqueViolation(err) {
+			return &User{}, ErrUsernameTaken
+		}
+		return &User{}, err
+	}
+	change := UsernameChange{UserID: uid, OldUsername: user.Username, NewUsername: username, CreatedAt: time.Now()}
+	err = tx.Debug().Model(&UsernameChange{}).Create(&change).Error
+	if err != nil {
+		tx.Rollback()
+		return &User{}, err
+	}
+	err = tx.Commit().Error
+	if err != nil {
+		return &User{}, err
+	}
+	err = db.Debug().Model(&User{}).Where("id = ?", uid).Take(&u).Error
+	if err != nil {
+		return &User{}, err
+	}
+	return u, nil
+}
+
+// FindUserByOldUsername gives the user who left the username during the grace period
+func (uc *UsernameChange) FindUserByOldUsername(db *gorm.DB, username string) (*User, error) {
+	err := db.Debug().Model(&UsernameChange{}).Where("LOWER(old_username) = LOWER(?) AND created_at > ?", username, time.Now().Add(-UsernameGracePeriod)).Order("created_at desc").Take(&uc).Error
+	if err != nil {
+		return &User{}, err
+	}
+	user := User{}
+	err = db.Debug().Model(&User{}).Where("id = ?", uc.UserID).Take(&user).Error
+	if err != nil {
+		return &User{}, err
+	}
+	return &user, nil
+}
+
+// FindUserByUsername gives the user with the username, or the one who left it during the grace period.
+// Both are compared without case, so @pet is user Pet. @mentions written before a rename are resolved with it
+func (u *User) FindUserByUsername(db *gorm.DB, username string) (*User, error) {
+	err := db.Debug().Model(&User{}).Where("LOWER(username) = LOWER(?)", username).Order("id asc").Take(&u).Error
+	if err == nil {
+		return u, nil
+	}
+	if !gorm.IsRecordNotFoundError(err) {
+		return &User{}, err
+	}
+	return (&UsernameChange{}).FindUserByOldUsername(db, username)
+}
+
+// When a user is deleted, their old usernames are freed
+func (uc *UsernameChange) DeleteUserUsernameChanges(db *gorm.DB, uid uint32) (int64, error) {
+	db = db.Debug().Model(&UsernameChange{}).Where("user_id = ?", uid).Delete(&UsernameChange{})
+	if db.Error != nil {
+		return 0, db.Error
+	}
+	return db.RowsAffected, nil
+}
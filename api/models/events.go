@@ -0,0 +1,16 @@
+package models
+
+// The writes of the models that others can be told about as they happen
+const (
+	EventPostPublished  = "post.published"
+	EventCommentCreated = "comment.created"
+	EventCommentUpdated = "comment.updated"
+	EventCommentDeleted = "comment.deleted"
+	EventLikeCreated    = "like.created"
+	EventLikeDeleted    = "like.deleted"
+	EventNotification   = "notification"
+)
+
+// OnEvent is called after each of the writes above with the model written.
+// It does nothing until something listens, like the real-time updates
+var OnEvent = func(event string, value interface{}) {}
@@ -0,0 +1,40 @@
+// Package realtime passes the changes of the forum to the clients that subscribed to them, as they happen
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Message is an event published on a topic. The data is already JSON, so a broker can carry it as it is
+type Message struct {
+	Event string `json:"event"`
+	// The user who caused the event, so that subscribers can leave out the users they muted
+	ActorID uint32          `json:"actor_id"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Broker passes the messages published on a topic to the subscribers of the topic.
+// The Hub only reaches the subscribers of this instance, when the API runs on many of them
+// a broker like Redis or NATS is set as Default instead
+type Broker interface {
+	Publish(topic string, message Message) error
+	// Subscribe gives the messages of the topic until the returned function is called
+	Subscribe(topic string) (<-chan Message, func(), error)
+}
+
+// Default is the broker the events of the models are published to
+var Default Broker = NewHub()
+
+// The topic of the new posts
+const PostsTopic = "posts"
+
+// PostTopic is the topic of the comments and likes of the post
+func PostTopic(pid uint64) string {
+	return fmt.Sprintf("post:%d", pid)
+}
+
+// NotificationsTopic is the topic of the notifications of the user
+func NotificationsTopic(uid uint32) string {
+	return fmt.Sprintf("user:%d:notifications", uid)
+}
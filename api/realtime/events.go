@@ -0,0 +1,47 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+)
+
+// Listen publishes the events of the models to the Default broker
+func Listen() {
+	models.OnEvent = Publish
+}
+
+// Publish sends the event of the model to the topic it belongs to.
+// The models are mapped to the responses of the API first, so nothing secret is published
+func Publish(event string, value interface{}) {
+	var topic string
+	var actorID uint32
+	var data interface{}
+	switch v := value.(type) {
+	case *models.Post:
+		topic, actorID, data = PostsTopic, v.AuthorID, responses.NewPost(v)
+	case *models.Comment:
+		topic, actorID, data = PostTopic(v.PostID), v.UserID, responses.NewComment(v)
+		// A deleted comment is only told by its id
+		if event == models.EventCommentDeleted {
+			data = map[string]uint64{"id": v.ID, "post_id": v.PostID}
+		}
+	case *models.Like:
+		topic, actorID, data = PostTopic(v.PostID), v.UserID, v
+	case *models.Notification:
+		topic, actorID, data = NotificationsTopic(v.UserID), v.ActorID, responses.NewNotification(v)
+	default:
+		return
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		fmt.Println("cannot encode the event: ", err)
+		return
+	}
+	err = Default.Publish(topic, Message{Event: event, ActorID: actorID, Data: encoded})
+	if err != nil {
+		fmt.Println("cannot publish the event: ", err)
+	}
+}
@@ -0,0 +1,55 @@
+package realtime
+
+import (
+	"sync"
+)
+
+// How many messages a subscriber can be behind before it misses some
+const subscriberBuffer = 64
+
+// Hub is the broker of a single instance, the messages never leave the process
+type Hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[chan Message]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{topics: map[string]map[chan Message]bool{}}
+}
+
+// Publish never waits on a subscriber: one too slow to keep up misses the message rather than holding up the write
+func (h *Hub) Publish(topic string, message Message) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for subscriber := range h.topics[topic] {
+		select {
+		case subscriber <- message:
+		default:
+		}
+	}
+	return nil
+}
+
+func (h *Hub) Subscribe(topic string) (<-chan Message, func(), error) {
+	subscriber := make(chan Message, subscriberBuffer)
+	h.mu.Lock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = map[chan Message]bool{}
+	}
+	h.topics[topic][subscriber] = true
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.topics[topic], subscriber)
+			if len(h.topics[topic]) == 0 {
+				delete(h.topics, topic)
+			}
+			h.mu.Unlock()
+			close(subscriber)
+		})
+	}
+	return subscriber, unsubscribe, nil
+}
@@ -0,0 +1,68 @@
+package responses
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/victorsteven/forum/api/models"
+)
+
+// Notification is a notification of the authenticated user, with the text the client shows for it
+type Notification struct {
+	ID         uint64     `json:"id"`
+	Type       string     `json:"type"`
+	Text       string     `json:"text"`
+	Actor      Author     `json:"actor"`
+	ActorCount int        `json:"actor_count"`
+	PostID     uint64     `json:"post_id,omitempty"`
+	CommentID  uint64     `json:"comment_id,omitempty"`
+	Read       bool       `json:"read"`
+	ReadAt     *time.Time `json:"read_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// What the actors of each type of notification did
+var notificationActions = map[string]string{
+	models.NotificationLike:    "liked your post",
+	models.NotificationComment: "commented on your post",
+	models.NotificationReply:   "replied to a post you commented on",
+	models.NotificationMention: "mentioned you",
+	models.NotificationFollow:  "followed you",
+}
+
+// NotificationText tells who did what, like "steven and 4 others liked your post"
+func NotificationText(notificationType, actor string, actorCount int) string {
+	who := actor
+	switch {
+	case actorCount == 2:
+		who = actor + " and 1 other"
+	case actorCount > 2:
+		who = fmt.Sprintf("%s and %d others", actor, actorCount-1)
+	}
+	return who + " " + notificationActions[notificationType]
+}
+
+func NewNotification(n *models.Notification) Notification {
+	return Notification{
+		ID:         n.ID,
+		Type:       n.Type,
+		Text:       NotificationText(n.Type, n.Actor.Username, n.ActorCount),
+		Actor:      NewAuthor(&n.Actor),
+		ActorCount: n.ActorCount,
+		PostID:     n.PostID,
+		CommentID:  n.CommentID,
+		Read:       n.ReadAt != nil,
+		ReadAt:     n.ReadAt,
+		CreatedAt:  n.CreatedAt,
+		UpdatedAt:  n.UpdatedAt,
+	}
+}
+
+func NewNotifications(notifications []models.Notification) []Notification {
+	result := make([]Notification, len(notifications))
+	for i, _ := range notifications {
+		result[i] = NewNotification(&notifications[i])
+	}
+	return result
+}
@@ -0,0 +1,55 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/victorsteven/forum/api/models"
+)
+
+type Poll struct {
+	ID          uint64       `json:"id"`
+	PostID      uint64       `json:"post_id"`
+	Multiple    bool         `json:"multiple"`
+	Anonymous   bool         `json:"anonymous"`
+	ClosesAt    *time.Time   `json:"closes_at"`
+	Options     []PollOption `json:"options"`
+	Closed      bool         `json:"closed"`
+	TotalVoters int          `json:"total_voters"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type PollOption struct {
+	ID       uint64   `json:"id"`
+	PollID   uint64   `json:"poll_id"`
+	Text     string   `json:"text"`
+	Position int      `json:"position"`
+	Votes    int      `json:"votes"`
+	Voters   []Author `json:"voters,omitempty"`
+}
+
+func NewPoll(p *models.Poll) Poll {
+	poll := Poll{
+		ID:          p.ID,
+		PostID:      p.PostID,
+		Multiple:    p.Multiple,
+		Anonymous:   p.Anonymous,
+		ClosesAt:    p.ClosesAt,
+		Options:     make([]PollOption, len(p.Options)),
+		Closed:      p.Closed,
+		TotalVoters: p.TotalVoters,
+		CreatedAt:   p.CreatedAt,
+	}
+	for i, option := range p.Options {
+		poll.Options[i] = PollOption{
+			ID:       option.ID,
+			PollID:   option.PollID,
+			Text:     option.Text,
+			Position: option.Position,
+			Votes:    option.Votes,
+		}
+		if len(option.Voters) > 0 {
+			poll.Options[i].Voters = NewAuthors(option.Voters)
+		}
+	}
+	return poll
+}
@@ -0,0 +1,158 @@
+package responses
+
+import (
+	"time"
+
+	"github.com/victorsteven/forum/api/models"
+)
+
+type Post struct {
+	ID               uint64              `json:"id"`
+	Title            string              `json:"title"`
+	Slug             string              `json:"slug"`
+	Content          string              `json:"content,omitempty"`
+	ContentHTML      string              `json:"content_html,omitempty"`
+	Author           Author              `json:"author"`
+	AuthorID         uint32              `json:"author_id"`
+	Status           string              `json:"status"`
+	PublishAt        *time.Time          `json:"publish_at"`
+	Category         string              `json:"category"`
+	PinnedGlobally   bool                `json:"pinned_globally"`
+	PinnedInCategory bool                `json:"pinned_in_category"`
+	PinnedUntil      *time.Time          `json:"pinned_until"`
+	Locked           bool                `json:"locked"`
+	Poll             *Poll               `json:"poll,omitempty"`
+	Attachments      []models.Attachment `json:"attachments,omitempty"`
+	Mentions         []Mention           `json:"mentions,omitempty"`
+	CreatedAt        time.Time           `json:"created_at"`
+	UpdatedAt        time.Time           `json:"updated_at"`
+	DeletedAt        *time.Time          `json:"deleted_at"`
+}
+
+type Comment struct {
+	ID          uint64              `json:"id"`
+	UserID      uint32              `json:"user_id"`
+	PostID      uint64              `json:"post_id"`
+	Body        string              `json:"body,omitempty"`
+	BodyHTML    string              `json:"body_html,omitempty"`
+	User        Author              `json:"user"`
+	Attachments []models.Attachment `json:"attachments,omitempty"`
+	Mentions    []Mention           `json:"mentions,omitempty"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	DeletedAt   *time.Time          `json:"deleted_at"`
+}
+
+// Mention is a user mentioned in a post or a comment, the username is the one written in the text
+type Mention struct {
+	UserID   uint32 `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type Revision struct {
+	ID           uint64    `json:"id"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   uint64    `json:"resource_id"`
+	Number       int       `json:"number"`
+	EditorID     uint32    `json:"editor_id"`
+	Editor       Author    `json:"editor"`
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func NewPost(p *models.Post) Post {
+	post := Post{
+		ID:               p.ID,
+		Title:            p.Title,
+		Slug:             p.Slug,
+		Content:          p.Content,
+		ContentHTML:      p.ContentHTML,
+		Author:           NewAuthor(&p.Author),
+		AuthorID:         p.AuthorID,
+		Status:           p.Status,
+		PublishAt:        p.PublishAt,
+		Category:         p.Category,
+		PinnedGlobally:   p.PinnedGlobally,
+		PinnedInCategory: p.PinnedInCategory,
+		PinnedUntil:      p.PinnedUntil,
+		Locked:           p.Locked,
+		Attachments:      p.Attachments,
+		Mentions:         NewMentions(p.Mentions),
+		CreatedAt:        p.CreatedAt,
+		UpdatedAt:        p.UpdatedAt,
+		DeletedAt:        p.DeletedAt,
+	}
+	if p.Poll != nil {
+		poll := NewPoll(p.Poll)
+		post.Poll = &poll
+	}
+	return post
+}
+
+func NewPosts(posts []models.Post) []Post {
+	result := make([]Post, len(posts))
+	for i, _ := range posts {
+		result[i] = NewPost(&posts[i])
+	}
+	return result
+}
+
+func NewComment(c *models.Comment) Comment {
+	return Comment{
+		ID:          c.ID,
+		UserID:      c.UserID,
+		PostID:      c.PostID,
+		Body:        c.Body,
+		BodyHTML:    c.BodyHTML,
+		User:        NewAuthor(&c.User),
+		Attachments: c.Attachments,
+		Mentions:    NewMentions(c.Mentions),
+		CreatedAt:   c.CreatedAt,
+		UpdatedAt:   c.UpdatedAt,
+		DeletedAt:   c.DeletedAt,
+	}
+}
+
+func NewComments(comments []models.Comment) []Comment {
+	result := make([]Comment, len(comments))
+	for i, _ := range comments {
+		result[i] = NewComment(&comments[i])
+	}
+	return result
+}
+
+func NewRevision(r *models.Revision) Revision {
+	return Revision{
+		ID:           r.ID,
+		ResourceType: r.ResourceType,
+		ResourceID:   r.ResourceID,
+		Number:       r.Number,
+		EditorID:     r.EditorID,
+		Editor:       NewAuthor(&r.Editor),
+		Title:        r.Title,
+		Content:      r.Content,
+		Note:         r.Note,
+		CreatedAt:    r.CreatedAt,
+	}
+}
+
+func NewRevisions(revisions []models.Revision) []Revision {
+	result := make([]Revision, len(revisions))
+	for i, _ := range revisions {
+		result[i] = NewRevision(&revisions[i])
+	}
+	return result
+}
+
+func NewMentions(mentions []models.Mention) []Mention {
+	if len(mentions) == 0 {
+		return nil
+	}
+	result := make([]Mention, len(mentions))
+	for i, _ := range mentions {
+		result[i] = Mention{UserID: mentions[i].UserID, Username: mentions[i].Username}
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+// Package responses holds what the API sends back. The models are mapped to these types
+// so that fields like the password hash never leave the API
+package responses
+
+import (
+	"time"
+
+	"github.com/victorsteven/forum/api/models"
+)
+
+// User is the account of the authenticated user, as only they see it
+type User struct {
+	ID           uint32            `json:"id"`
+	Username     string            `json:"username"`
+	Email        string            `json:"email"`
+	AvatarPath   string            `json:"avatar_path"`
+	AvatarURLs   map[string]string `json:"avatar_urls"`
+	Role         string            `json:"role"`
+	Bio          string            `json:"bio"`
+	ShowEmail    bool              `json:"show_email"`
+	ShowActivity bool              `json:"show_activity"`
+	Locale       string            `json:"locale"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// Author is what everyone can see of a user: on posts, comments, revisions and in lists of users
+type Author struct {
+	ID         uint32            `json:"id"`
+	Username   string            `json:"username"`
+	AvatarPath string            `json:"avatar_path"`
+	AvatarURLs map[string]string `json:"avatar_urls"`
+}
+
+func NewUser(u *models.User) User {
+	return User{
+		ID:           u.ID,
+		Username:     u.Username,
+		Email:        u.Email,
+		AvatarPath:   u.AvatarPath,
+		AvatarURLs:   u.AvatarURLs,
+		Role:         u.Role,
+		Bio:          u.Bio,
+		ShowEmail:    u.ShowEmail,
+		ShowActivity: u.ShowActivity,
+		Locale:       u.Locale,
+		CreatedAt:    u.CreatedAt,
+		UpdatedAt:    u.UpdatedAt,
+	}
+}
+
+func NewUsers(users []models.User) []User {
+	result := make([]User, len(users))
+	for i, _ := range users {
+		result[i] = NewUser(&users[i])
+	}
+	return result
+}
+
+func NewAuthor(u *models.User) Author {
+	return Author{
+		ID:         u.ID,
+		Username:   u.Username,
+		AvatarPath: u.AvatarPath,
+		AvatarURLs: u.AvatarURLs,
+	}
+}
+
+func NewAuthors(users []models.User) []Author {
+	result := make([]Author, len(users))
+	for i, _ := range users {
+		result[i] = NewAuthor(&users[i])
+	}
+	return result
+}
@@ -12,6 +12,7 @@ var users = []models.User{
 		Username: "steven",
 		Email:    "steven@example.com",
 		Password: "password",
+		Role:     models.RoleAdmin,
 	},
 	models.User{
 		Username: "martin",
@@ -33,11 +34,11 @@ var posts = []models.Post{
 
 func Load(db *gorm.DB) {
 
-	err := db.Debug().DropTableIfExists(&models.Post{}, &models.User{}, &models.Like{}, &models.Comment{}).Error
+	err := db.Debug().DropTableIfExists(&models.Post{}, &models.User{}, &models.Like{}, &models.Comment{}, &models.Revision{}, &models.PostSlug{}, &models.Poll{}, &models.PollOption{}, &models.PollVote{}, &models.PollChoice{}, &models.Attachment{}, &models.Follow{}, &models.CategoryFollow{}, &models.Block{}, &models.Mute{}, &models.UsernameChange{}, &models.EmailChange{}, &models.DataExport{}, &models.AccountClosure{}, &models.Notification{}, &models.NotificationActor{}, &models.Mention{}, &models.NotificationPreference{}, &models.EmailDigest{}).Error
 	if err != nil {
 		log.Fatalf("cannot drop table: %v", err)
 	}
-	err = db.Debug().AutoMigrate(&models.User{}, &models.Post{}).Error
+	err = db.Debug().AutoMigrate(&models.User{}, &models.Post{}, &models.Revision{}, &models.PostSlug{}).Error
 	if err != nil {
 		log.Fatalf("cannot migrate table: %v", err)
 	}
@@ -59,4 +60,9 @@ func Load(db *gorm.DB) {
 			log.Fatalf("cannot seed posts table: %v", err)
 		}
 	}
+
+	_, err = models.CreateDeletedUser(db)
+	if err != nil {
+		log.Fatalf("cannot seed the deleted user: %v", err)
+	}
 }
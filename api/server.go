@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/victorsteven/forum/api/controllers"
+	"github.com/victorsteven/forum/api/workers"
 )
 
 var server = controllers.Server{}
@@ -33,6 +35,27 @@ func Run() {
 	// This is for testing, when done, do well to comment
 	// seed.Load(server.DB)
 
+	// Deleted posts and comments are removed for good after the retention period
+	workers.StartTrashPurger(server.DB, workers.TrashRetention(), time.Hour)
+
+	// Scheduled posts are published when they are due
+	workers.StartPublisher(server.DB, time.Minute)
+
+	// Announcements are unpinned when their time is over
+	workers.StartAnnouncementExpirer(server.DB, time.Minute)
+
+	// Files attached to posts and comments that were removed for good are deleted from the storage
+	workers.StartAttachmentCleaner(server.DB, time.Hour)
+
+	// Archives of the users' data are built when asked for, and deleted once their link expired
+	workers.StartDataExporter(server.DB, time.Minute)
+
+	// Closed accounts are purged when their grace period is over
+	workers.StartAccountCloser(server.DB, time.Hour)
+
+	// Notifications are emailed as the users chose, right away or in a daily or weekly digest
+	workers.StartNotificationMailer(server.DB, time.Minute)
+
 	apiPort := fmt.Sprintf(":%s", os.Getenv("API_PORT"))
 	fmt.Printf("Listening to port %s", apiPort)
 
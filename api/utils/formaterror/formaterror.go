@@ -33,6 +33,10 @@ func FormatError(errString string) map[string]string {
 		errorMessages["Double_like"] = "You cannot like this post twice"
 	}
 
+	if strings.Contains(errString, "double vote") {
+		errorMessages["Double_vote"] = "You cannot vote on this poll twice"
+	}
+
 	if len(errorMessages) > 0 {
 		return errorMessages
 	}
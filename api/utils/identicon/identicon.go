@@ -0,0 +1,71 @@
+package identicon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/png"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The identicon is a grid of cells, mirrored around its middle column
+const grid = 5
+
+var background = color.NRGBA{240, 240, 240, 255}
+
+func hash(username string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(strings.ToLower(username)))
+}
+
+// Render draws the identicon of the username as a size x size PNG.
+// The same username always gives the same image
+func Render(username string, size int) ([]byte, error) {
+	sum := hash(username)
+
+	// The color comes from the first bytes of the hash, kept away from too light or too dark
+	foreground := color.NRGBA{64 + sum[0]%160, 64 + sum[1]%160, 64 + sum[2]%160, 255}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	padding := size / 10
+	inner := size - 2*padding
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.SetNRGBA(x, y, background)
+			if x < padding || y < padding || x >= padding+inner || y >= padding+inner {
+				continue
+			}
+			column := (x - padding) * grid / inner
+			row := (y - padding) * grid / inner
+			if column > grid/2 {
+				column = grid - 1 - column
+			}
+			// Each cell of the left half and the middle is on when its bit of the hash is set
+			bit := row*(grid/2+1) + column
+			if sum[3+bit/8]&(1<<uint(bit%8)) != 0 {
+				img.SetNRGBA(x, y, foreground)
+			}
+		}
+	}
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, img)
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// ETag identifies the identicon of the username in the given size
+func ETag(username string, size int) string {
+	sum := hash(username)
+	return `"` + hex.EncodeToString(sum[:8]) + "-" + strconv.Itoa(size) + `"`
+}
+
+// URL is the address the identicon of the username is served at, API_URL is put in front when it is set
+func URL(username string) string {
+	return os.Getenv("API_URL") + "/api/v1/avatars/" + url.PathEscape(username) + ".png"
+}
@@ -0,0 +1,29 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday/v2"
+)
+
+var policy = newPolicy()
+
+// Only the elements and attributes commonly produced by Markdown are allowed through
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.RequireNoFollowOnLinks(true)
+	// fenced code blocks keep their language, so clients can highlight them
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[a-zA-Z0-9_+-]+$`)).OnElements("code")
+	return p
+}
+
+// Render turns the Markdown written by a user into sanitized HTML
+func Render(source string) string {
+	unsafe := blackfriday.Run([]byte(source))
+	safe := policy.SanitizeBytes(unsafe)
+	// The sanitizer adds rel="nofollow" to every link, user generated links are also marked "ugc".
+	// Quotes written by the user are escaped by the sanitizer, so only the attribute it added can match.
+	return strings.Replace(string(safe), `rel="nofollow"`, `rel="nofollow ugc"`, -1)
+}
@@ -0,0 +1,54 @@
+// Package mentions finds the @username mentions in the Markdown written by a user
+package mentions
+
+import (
+	"regexp"
+	"strings"
+)
+
+// No more users than this are mentioned in a single text, the others are ignored
+const MaxMentions = 20
+
+var (
+	mention = regexp.MustCompile(`@([A-Za-z0-9_]+)`)
+	// Code is shown as written, so what looks like a mention in it is not one
+	fencedCode = regexp.MustCompile("(?s)```.*?```")
+	inlineCode = regexp.MustCompile("`[^`\n]*`")
+)
+
+// Parse gives the usernames mentioned in the text, once each and in the order they come.
+// An @ right after a letter, a digit or some punctuation is part of something else, like an email address
+func Parse(text string) []string {
+	text = fencedCode.ReplaceAllString(text, "")
+	text = inlineCode.ReplaceAllString(text, "")
+
+	usernames := []string{}
+	seen := map[string]bool{}
+	for _, match := range mention.FindAllStringSubmatchIndex(text, -1) {
+		if match[0] > 0 && !isBoundary(text[match[0]-1]) {
+			continue
+		}
+		username := text[match[2]:match[3]]
+		// The usernames have 3 to 30 characters
+		if len(username) < 3 || len(username) > 30 {
+			continue
+		}
+		if seen[strings.ToLower(username)] {
+			continue
+		}
+		seen[strings.ToLower(username)] = true
+		usernames = append(usernames, username)
+		if len(usernames) == MaxMentions {
+			break
+		}
+	}
+	return usernames
+}
+
+func isBoundary(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return false
+	}
+	return !strings.ContainsRune("_.@/-+=", rune(c))
+}
@@ -0,0 +1,48 @@
+// Package profanity tells if a name contains offensive words, even when they are
+// written with digits or symbols in place of letters
+package profanity
+
+import "strings"
+
+// Words are matched anywhere in the name, so only the ones rarely found inside harmless words are listed
+var words = []string{
+	"asshole",
+	"bastard",
+	"bitch",
+	"cunt",
+	"faggot",
+	"fuck",
+	"nigger",
+	"pussy",
+	"retard",
+	"shit",
+	"slut",
+	"whore",
+}
+
+// The characters commonly used for letters to get around a filter
+var lookalikes = strings.NewReplacer(
+	"0", "o",
+	"1", "i",
+	"3", "e",
+	"4", "a",
+	"5", "s",
+	"7", "t",
+	"@", "a",
+	"$", "s",
+	"!", "i",
+	"_", "",
+	"-", "",
+	".", "",
+)
+
+// Contains tells if the text has one of the words in it
+func Contains(text string) bool {
+	normalized := lookalikes.Replace(strings.ToLower(text))
+	for _, word := range words {
+		if strings.Contains(normalized, word) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package workers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// PurgeClosedAccounts ends the closures whose grace period is over.
+// A deleted account loses its avatar and data exports, and is anonymized. A deactivated account stays,
+// but its follows and data exports are removed, so a user who comes back after the grace period starts without them
+func PurgeClosedAccounts(db *gorm.DB, now time.Time) (int, error) {
+	closure := models.AccountClosure{}
+	closures, err := closure.FindDueClosures(db, now)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for i, _ := range *closures {
+		current := &(*closures)[i]
+		user := models.User{}
+		err = db.Debug().Model(&models.User{}).Where("id = ?", current.UserID).Take(&user).Error
+		if err != nil && !gorm.IsRecordNotFoundError(err) {
+			return purged, err
+		}
+		if err == nil {
+			err = purgeAccount(db, &user, current.Mode)
+			if err != nil {
+				fmt.Println("cannot purge the closed account: ", err)
+				continue
+			}
+		}
+		err = current.MarkPurged(db)
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func purgeAccount(db *gorm.DB, user *models.User, mode string) error {
+	exports := []models.DataExport{}
+	err := db.Debug().Model(&models.DataExport{}).Where("user_id = ?", user.ID).Find(&exports).Error
+	if err != nil {
+		return err
+	}
+	for i, _ := range exports {
+		if exports[i].Path == "" {
+			continue
+		}
+		err = deleteExportArchive(exports[i].Path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if mode == models.ClosureDelete {
+		// AfterFind gave the avatar as a url, the files are deleted from the stored path
+		var avatarPaths []string
+		err = db.Debug().Model(&models.User{}).Where("id = ?", user.ID).Pluck("avatar_path", &avatarPaths).Error
+		if err != nil {
+			return err
+		}
+		if len(avatarPaths) > 0 && avatarPaths[0] != "" {
+			// An avatar left in the storage does not keep the account from being anonymized
+			err = fileupload.DeleteAvatar(avatarPaths[0])
+			if err != nil {
+				fmt.Println("cannot delete the avatar of the closed account: ", err)
+			}
+		}
+		return models.AnonymizeUser(db, user.ID)
+	}
+
+	follow := models.Follow{}
+	_, err = follow.DeleteUserFollows(db, user.ID)
+	if err != nil {
+		return err
+	}
+	return db.Debug().Model(&models.DataExport{}).Where("user_id = ?", user.ID).Delete(&models.DataExport{}).Error
+}
+
+// StartAccountCloser purges the closed accounts every interval
+func StartAccountCloser(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			purged, err := PurgeClosedAccounts(db, time.Now())
+			if err != nil {
+				fmt.Println("cannot purge the closed accounts: ", err)
+			} else if purged > 0 {
+				fmt.Printf("Purged %d closed accounts\n", purged)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,27 @@
+package workers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// StartAnnouncementExpirer unpins the announcements whose pin has ended every interval
+func StartAnnouncementExpirer(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		post := models.Post{}
+		for {
+			unpinned, err := post.UnpinExpiredAnnouncements(db)
+			if err != nil {
+				fmt.Println("cannot unpin the expired announcements: ", err)
+			} else if unpinned > 0 {
+				fmt.Printf("Unpinned %d expired announcements\n", unpinned)
+			}
+			<-ticker.C
+		}
+	}()
+}
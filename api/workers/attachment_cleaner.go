@@ -0,0 +1,51 @@
+package workers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// CleanOrphanedAttachments deletes the files whose post or comment is gone, then their records.
+// A file that cannot be deleted keeps its record, so it is tried again on the next run
+func CleanOrphanedAttachments(db *gorm.DB) (int, error) {
+	attachment := models.Attachment{}
+	attachments, err := attachment.FindOrphanedAttachments(db)
+	if err != nil {
+		return 0, err
+	}
+	cleaned := 0
+	for i, _ := range *attachments {
+		err = fileupload.FileUpload.DeleteFile((*attachments)[i].Path)
+		if err != nil {
+			fmt.Println("cannot delete the attachment file: ", err)
+			continue
+		}
+		_, err = (*attachments)[i].DeleteAttachment(db)
+		if err != nil {
+			return cleaned, err
+		}
+		cleaned++
+	}
+	return cleaned, nil
+}
+
+// StartAttachmentCleaner cleans the orphaned attachments every interval
+func StartAttachmentCleaner(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			cleaned, err := CleanOrphanedAttachments(db)
+			if err != nil {
+				fmt.Println("cannot clean the orphaned attachments: ", err)
+			} else if cleaned > 0 {
+				fmt.Printf("Deleted %d orphaned attachments\n", cleaned)
+			}
+			<-ticker.C
+		}
+	}()
+}
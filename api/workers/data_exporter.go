@@ -0,0 +1,125 @@
+package workers
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/twinj/uuid"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/export"
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/mailer"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// RunDataExports builds the archive of every pending export, stores it, and emails its signed link to the user.
+// Each export is claimed first, so with many instances only one builds it.
+// An export whose archive cannot be built is marked failed, the user can ask again
+func RunDataExports(db *gorm.DB) (int, error) {
+	dataExport := models.DataExport{}
+	exports, err := dataExport.FindPendingExports(db)
+	if err != nil {
+		return 0, err
+	}
+	done := 0
+	for i, _ := range *exports {
+		current := &(*exports)[i]
+		claimed, err := current.Claim(db)
+		if err != nil {
+			return done, err
+		}
+		if !claimed {
+			continue
+		}
+		archive, err := export.BuildArchive(db, current.UserID)
+		if err == nil {
+			// The archive is only given out by the signed link, never by the storage itself
+			archivePath := fmt.Sprintf("exports/%d-%s.zip", current.ID, uuid.NewV4().String())
+			err = fileupload.PrivateStore.Put(archivePath, archive, "application/zip")
+			if err == nil {
+				err = current.MarkReady(db, archivePath, time.Now().Add(models.ExportLifetime))
+			}
+		}
+		if err != nil {
+			fmt.Println("cannot build the data export: ", err)
+			err = current.MarkFailed(db)
+			if err != nil {
+				return done, err
+			}
+			continue
+		}
+		done++
+
+		user := models.User{}
+		err = db.Debug().Model(&models.User{}).Where("id = ?", current.UserID).Take(&user).Error
+		if err != nil {
+			fmt.Println("cannot find the user of the data export: ", err)
+			continue
+		}
+		downloadPath := current.DownloadPath()
+		link := os.Getenv("API_URL") + downloadPath + "?" + auth.SignURL(downloadPath, *current.ExpiresAt)
+		_, err = mailer.SendMail.SendDataExportReady(user.Email, user.Locale, link)
+		if err != nil {
+			fmt.Println("cannot send the data export email: ", err)
+		}
+	}
+	return done, nil
+}
+
+// CleanExpiredExports deletes the archives that cannot be downloaded anymore, then their exports
+func CleanExpiredExports(db *gorm.DB, now time.Time) (int, error) {
+	dataExport := models.DataExport{}
+	exports, err := dataExport.FindExpiredExports(db, now)
+	if err != nil {
+		return 0, err
+	}
+	cleaned := 0
+	for i, _ := range *exports {
+		err = deleteExportArchive((*exports)[i].Path)
+		if err != nil {
+			fmt.Println("cannot delete the data export archive: ", err)
+			continue
+		}
+		_, err = (*exports)[i].DeleteDataExport(db)
+		if err != nil {
+			return cleaned, err
+		}
+		cleaned++
+	}
+	return cleaned, nil
+}
+
+// deleteExportArchive deletes the archive from the private storage, and from the public one
+// the archives built before the exports were private are in
+func deleteExportArchive(archivePath string) error {
+	err := fileupload.PrivateStore.Delete(archivePath)
+	if err != nil {
+		return err
+	}
+	return fileupload.Store.Delete(archivePath)
+}
+
+// StartDataExporter builds the pending exports and cleans the expired ones every interval
+func StartDataExporter(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			done, err := RunDataExports(db)
+			if err != nil {
+				fmt.Println("cannot run the data exports: ", err)
+			} else if done > 0 {
+				fmt.Printf("Built %d data exports\n", done)
+			}
+			cleaned, err := CleanExpiredExports(db, time.Now())
+			if err != nil {
+				fmt.Println("cannot clean the data exports: ", err)
+			} else if cleaned > 0 {
+				fmt.Printf("Deleted %d expired data exports\n", cleaned)
+			}
+			<-ticker.C
+		}
+	}()
+}
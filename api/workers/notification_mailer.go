@@ -0,0 +1,139 @@
+package workers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/mailer"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// SendNotificationEmails emails the unread notifications of every user as they chose: one email each for the
+// immediate ones, and a digest for the daily and weekly ones once its period is over. It gives how many emails were sent.
+// A notification whose email cannot be sent is tried again on the next run
+func SendNotificationEmails(db *gorm.DB, now time.Time) (int, error) {
+	notification := models.Notification{}
+	users, err := notification.FindUsersToEmail(db)
+	if err != nil {
+		return 0, err
+	}
+	sent := 0
+	for _, uid := range users {
+		count, err := sendUserNotificationEmails(db, uid, now)
+		sent += count
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func sendUserNotificationEmails(db *gorm.DB, uid uint32, now time.Time) (int, error) {
+	user := models.User{}
+	err := db.Debug().Model(&models.User{}).Where("id = ?", uid).Take(&user).Error
+	if err != nil {
+		return 0, err
+	}
+	preferences, err := (&models.NotificationPreference{}).FindPreferences(db, uid)
+	if err != nil {
+		return 0, err
+	}
+	notifications, err := (&models.Notification{}).FindUnemailed(db, uid)
+	if err != nil {
+		return 0, err
+	}
+	digests := map[string][]models.Notification{}
+	sent := 0
+	for i, _ := range *notifications {
+		current := &(*notifications)[i]
+		frequency := preferences[current.Type]
+		// Closed accounts are not emailed, what they had waiting is dropped
+		if user.DeactivatedAt != nil {
+			frequency = models.EmailOff
+		}
+		switch frequency {
+		case models.EmailDaily, models.EmailWeekly:
+			digests[frequency] = append(digests[frequency], *current)
+			continue
+		case models.EmailImmediate:
+			_, err = mailer.SendMail.SendNotificationEmail(user.Email, user.Locale, notificationItem(current), notificationPath(current), auth.UnsubscribeToken(uid, current.Type))
+			if err != nil {
+				fmt.Println("cannot send the notification email: ", err)
+				continue
+			}
+			sent++
+		}
+		err = current.MarkEmailed(db)
+		if err != nil {
+			return sent, err
+		}
+	}
+
+	digest := models.EmailDigest{}
+	for _, frequency := range []string{models.EmailDaily, models.EmailWeekly} {
+		pending := digests[frequency]
+		if len(pending) == 0 {
+			continue
+		}
+		due, err := digest.DigestDue(db, uid, frequency, now)
+		if err != nil {
+			return sent, err
+		}
+		if !due {
+			continue
+		}
+		items := make([]mailer.NotificationItem, len(pending))
+		for i, _ := range pending {
+			items[i] = notificationItem(&pending[i])
+		}
+		_, err = mailer.SendMail.SendNotificationDigest(user.Email, user.Locale, frequency, items, auth.UnsubscribeToken(uid, models.UnsubscribeAll))
+		if err != nil {
+			fmt.Println("cannot send the notification digest: ", err)
+			continue
+		}
+		sent++
+		err = digest.SaveDigestSent(db, uid, frequency, now)
+		if err != nil {
+			return sent, err
+		}
+		for i, _ := range pending {
+			err = pending[i].MarkEmailed(db)
+			if err != nil {
+				return sent, err
+			}
+		}
+	}
+	return sent, nil
+}
+
+// notificationPath is the page of the frontend app the notification is about
+func notificationPath(n *models.Notification) string {
+	if n.PostID == 0 {
+		return "notifications"
+	}
+	return fmt.Sprintf("posts/%d", n.PostID)
+}
+
+// notificationItem is the notification as the mailer tells it, in the locale of the user
+func notificationItem(n *models.Notification) mailer.NotificationItem {
+	return mailer.NotificationItem{Type: n.Type, Actor: n.Actor.Username, Count: n.ActorCount}
+}
+
+// StartNotificationMailer sends the notification emails and digests that are due every interval
+func StartNotificationMailer(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			sent, err := SendNotificationEmails(db, time.Now())
+			if err != nil {
+				fmt.Println("cannot send the notification emails: ", err)
+			} else if sent > 0 {
+				fmt.Printf("Sent %d notification emails\n", sent)
+			}
+			<-ticker.C
+		}
+	}()
+}
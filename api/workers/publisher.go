@@ -0,0 +1,28 @@
+package workers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// StartPublisher publishes the scheduled posts that are due every interval.
+// The schedule lives in the database, so nothing is lost on restart and many instances can run it together
+func StartPublisher(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		post := models.Post{}
+		for {
+			published, err := post.PublishDuePosts(db)
+			if err != nil {
+				fmt.Println("cannot publish the scheduled posts: ", err)
+			} else if published > 0 {
+				fmt.Printf("Published %d scheduled posts\n", published)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -0,0 +1,58 @@
+package workers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/victorsteven/forum/api/models"
+)
+
+// How long deleted posts and comments stay in the trash when TRASH_RETENTION_DAYS is not set
+const defaultTrashRetentionDays = 30
+
+// TrashRetention reads the retention period from TRASH_RETENTION_DAYS
+func TrashRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days < 1 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// PurgeTrash hard-deletes the posts and comments that were deleted before the retention period
+func PurgeTrash(db *gorm.DB, retention time.Duration) error {
+	before := time.Now().Add(-retention)
+
+	post := models.Post{}
+	comment := models.Comment{}
+
+	postsPurged, err := post.PurgeDeletedPosts(db, before)
+	if err != nil {
+		return err
+	}
+	commentsPurged, err := comment.PurgeDeletedComments(db, before)
+	if err != nil {
+		return err
+	}
+	if postsPurged > 0 || commentsPurged > 0 {
+		fmt.Printf("Purged %d posts and %d comments from the trash\n", postsPurged, commentsPurged)
+	}
+	return nil
+}
+
+// StartTrashPurger runs PurgeTrash in the background every interval
+func StartTrashPurger(db *gorm.DB, retention, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := PurgeTrash(db, retention); err != nil {
+				fmt.Println("cannot purge the trash: ", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
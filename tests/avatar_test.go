@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/fileupload"
+)
+
+func TestProcessAvatar(t *testing.T) {
+
+	// A wide image, red on the left and blue on the right
+	img := image.NewRGBA(image.Rect(0, 0, 900, 600))
+	for y := 0; y < 600; y++ {
+		for x := 0; x < 900; x++ {
+			if x < 450 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	var buffer bytes.Buffer
+	err := jpeg.Encode(&buffer, img, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	avatars, errList := fileupload.ProcessAvatar(buffer.Bytes())
+	assert.Nil(t, errList)
+	assert.Equal(t, len(avatars), len(fileupload.AvatarSizes))
+
+	for _, size := range fileupload.AvatarSizes {
+		avatar, err := png.Decode(bytes.NewReader(avatars[size]))
+		if err != nil {
+			t.Errorf("this is the error decoding the avatar: %v\n", err)
+			return
+		}
+		assert.Equal(t, avatar.Bounds().Dx(), size)
+		assert.Equal(t, avatar.Bounds().Dy(), size)
+
+		// The center is kept, so both colors are still there
+		r, _, b, _ := avatar.At(1, size/2).RGBA()
+		assert.True(t, r > b)
+		r, _, b, _ = avatar.At(size-2, size/2).RGBA()
+		assert.True(t, b > r)
+	}
+}
+
+func TestProcessAvatarRefusesTooManyPixels(t *testing.T) {
+
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, image.NewGray(image.Rect(0, 0, 5001, 5001)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, errList := fileupload.ProcessAvatar(buffer.Bytes())
+	assert.Equal(t, errList["Too_large"], "Sorry, this image has too many pixels")
+}
+
+func TestAvatarURLs(t *testing.T) {
+
+	urls := fileupload.AvatarURLs("avatars/abc-512.png")
+	assert.Equal(t, urls["48"], fileupload.URL("avatars/abc-48.png"))
+	assert.Equal(t, urls["512"], fileupload.URL("avatars/abc-512.png"))
+
+	// An avatar uploaded before the sizes existed is used for all of them
+	urls = fileupload.AvatarURLs("old.jpg")
+	assert.Equal(t, urls["128"], fileupload.URL("old.jpg"))
+}
+
+func TestDeleteAvatarRemovesEverySize(t *testing.T) {
+
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 64, 64)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	header, err := attachmentHeader("me.png", buffer.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	avatarPath, errList := fileupload.FileUpload.UploadFile(header)
+	assert.Nil(t, errList)
+	for _, size := range []string{"48", "128", "512"} {
+		_, err = fileupload.Store.Get(strings.TrimSuffix(avatarPath, "512.png") + size + ".png")
+		assert.Nil(t, err)
+	}
+
+	// Replacing the avatar deletes the former one this way
+	err = fileupload.DeleteAvatar(avatarPath)
+	if err != nil {
+		t.Errorf("this is the error deleting the avatar: %v\n", err)
+		return
+	}
+	for _, size := range []string{"48", "128", "512"} {
+		_, err = fileupload.Store.Get(strings.TrimSuffix(avatarPath, "512.png") + size + ".png")
+		assert.NotNil(t, err)
+	}
+}
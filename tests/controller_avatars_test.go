@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAvatar(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	samples := []struct {
+		url        string
+		statusCode int
+		size       int
+	}{
+		{url: "/avatars/steven.png", statusCode: 200, size: 128},
+		{url: "/avatars/steven.png?size=48", statusCode: 200, size: 48},
+		{url: "/avatars/steven.png?size=49", statusCode: 400},
+		{url: "/avatars/steven.jpg", statusCode: 404},
+	}
+	var first []byte
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", v.url, nil)
+		rr := httptest.NewRecorder()
+
+		r := gin.Default()
+		r.GET("/avatars/:file", server.GetAvatar)
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode != 200 {
+			continue
+		}
+		assert.Equal(t, rr.Header().Get("Content-Type"), "image/png")
+		assert.Equal(t, rr.Header().Get("Cache-Control"), "public, max-age=31536000, immutable")
+		avatar, err := png.Decode(bytes.NewReader(rr.Body.Bytes()))
+		if err != nil {
+			t.Errorf("this is the error decoding the avatar: %v\n", err)
+			continue
+		}
+		assert.Equal(t, avatar.Bounds().Dx(), v.size)
+		if first == nil {
+			first = rr.Body.Bytes()
+		}
+	}
+
+	// The same username always gives the same avatar
+	req, _ := http.NewRequest("GET", "/avatars/steven.png", nil)
+	rr := httptest.NewRecorder()
+	r := gin.Default()
+	r.GET("/avatars/:file", server.GetAvatar)
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Body.Bytes(), first)
+
+	// A client holding it already is told it has not changed
+	req, _ = http.NewRequest("GET", "/avatars/steven.png", nil)
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusNotModified)
+}
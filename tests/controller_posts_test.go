@@ -9,9 +9,12 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/middlewares"
+	"github.com/victorsteven/forum/api/models"
 )
 
 func TestCreatePost(t *testing.T) {
@@ -42,6 +45,7 @@ func TestCreatePost(t *testing.T) {
 		statusCode int
 		title      string
 		content    string
+		slug       string
 		tokenGiven string
 	}{
 		{
@@ -50,12 +54,16 @@ func TestCreatePost(t *testing.T) {
 			tokenGiven: tokenString,
 			title:      "The title",
 			content:    "the content",
+			slug:       "the-title",
 		},
 		{
-			// When the post title already exist
+			// When the post title already exist, the slug gets a suffix
 			inputJSON:  `{"title":"The title", "content": "the content"}`,
-			statusCode: 500,
+			statusCode: 201,
 			tokenGiven: tokenString,
+			title:      "The title",
+			content:    "the content",
+			slug:       "the-title-2",
 		},
 		{
 			// When no token is passed
@@ -105,6 +113,7 @@ func TestCreatePost(t *testing.T) {
 			responseMap := responseInterface["response"].(map[string]interface{})
 			assert.Equal(t, responseMap["title"], v.title)
 			assert.Equal(t, responseMap["content"], v.content)
+			assert.Equal(t, responseMap["slug"], v.slug)
 		}
 		if v.statusCode == 401 || v.statusCode == 422 || v.statusCode == 500 {
 			responseMap := responseInterface["error"].(map[string]interface{})
@@ -481,3 +490,186 @@ func TestDeletePost(t *testing.T) {
 		}
 	}
 }
+
+func TestGetPostFormats(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatal(err)
+	}
+	post := models.Post{
+		Title:    "Markdown & friends",
+		Content:  "Read **this** [link](https://example.com) <script>alert(1)</script>",
+		AuthorID: user.ID,
+	}
+	post.Prepare()
+	_, err = post.SavePost(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	postSample := []struct {
+		format      string
+		statusCode  int
+		content     interface{}
+		contentHTML interface{}
+	}{
+		{
+			format:      "",
+			statusCode:  200,
+			content:     post.Content,
+			contentHTML: "<p>Read <strong>this</strong> <a href=\"https://example.com\" rel=\"nofollow ugc\">link</a> </p>\n",
+		},
+		{
+			format:     "raw",
+			statusCode: 200,
+			content:    post.Content,
+		},
+		{
+			format:      "html",
+			statusCode:  200,
+			contentHTML: "<p>Read <strong>this</strong> <a href=\"https://example.com\" rel=\"nofollow ugc\">link</a> </p>\n",
+		},
+		{
+			format:     "pdf",
+			statusCode: 400,
+		},
+	}
+	for _, v := range postSample {
+		req, _ := http.NewRequest("GET", "/posts/"+strconv.Itoa(int(post.ID))+"?format="+v.format, nil)
+		rr := httptest.NewRecorder()
+
+		r := gin.Default()
+		r.GET("/posts/:id", server.GetPost)
+		r.ServeHTTP(rr, req)
+
+		responseInterface := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseInterface)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			responseMap := responseInterface["response"].(map[string]interface{})
+			// The title and the content are returned as written, without HTML entities
+			assert.Equal(t, responseMap["title"], "Markdown & friends")
+			assert.Equal(t, responseMap["content"], v.content)
+			assert.Equal(t, responseMap["content_html"], v.contentHTML)
+		}
+		if v.statusCode == 400 {
+			responseMap := responseInterface["error"].(map[string]interface{})
+			assert.Equal(t, responseMap["Invalid_format"], "Format should be raw or html")
+		}
+	}
+}
+
+func TestGetPostBySlug(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatal(err)
+	}
+	oldSlug := post.Slug
+
+	// Editing the title gives the post a new slug
+	post.Title = "A brand new title"
+	updatedPost, err := post.UpdateAPost(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	assert.Equal(t, updatedPost.Slug, "a-brand-new-title")
+
+	postSample := []struct {
+		slug       string
+		statusCode int
+		location   string
+	}{
+		{
+			slug:       "a-brand-new-title",
+			statusCode: 200,
+		},
+		{
+			slug:       oldSlug,
+			statusCode: 301,
+			location:   "/api/v1/posts_by_slug/a-brand-new-title",
+		},
+		{
+			slug:       "no-such-post",
+			statusCode: 404,
+		},
+	}
+	for _, v := range postSample {
+		req, _ := http.NewRequest("GET", "/posts_by_slug/"+v.slug, nil)
+		rr := httptest.NewRecorder()
+
+		r := gin.Default()
+		r.GET("/posts_by_slug/:slug", server.GetPostBySlug)
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, v.statusCode)
+		if v.statusCode == 200 {
+			responseInterface := make(map[string]interface{})
+			err = json.Unmarshal([]byte(rr.Body.String()), &responseInterface)
+			if err != nil {
+				t.Errorf("Cannot convert to json: %v", err)
+			}
+			responseMap := responseInterface["response"].(map[string]interface{})
+			assert.Equal(t, responseMap["id"], float64(post.ID))
+		}
+		if v.statusCode == 301 {
+			assert.Equal(t, rr.Header().Get("Location"), v.location)
+		}
+	}
+}
+
+func TestAClosedAccountCannotUseItsOldToken(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	tokenInterface, err := server.SignIn(user.Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", tokenInterface["token"])
+
+	r := gin.Default()
+	r.POST("/posts", middlewares.TokenAuthMiddleware(server.DB), server.CreatePost)
+
+	req, _ := http.NewRequest("POST", "/posts", bytes.NewBufferString(`{"title":"The title", "content": "the content"}`))
+	req.Header.Set("Authorization", tokenString)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusCreated)
+
+	// The account is closed, the token it was given before is refused
+	err = server.DB.Model(&models.User{}).Where("id = ?", user.ID).UpdateColumn("deactivated_at", time.Now()).Error
+	if err != nil {
+		t.Errorf("this is the error deactivating the user: %v\n", err)
+		return
+	}
+	req, _ = http.NewRequest("POST", "/posts", bytes.NewBufferString(`{"title":"Another title", "content": "the content"}`))
+	req.Header.Set("Authorization", tokenString)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusUnauthorized)
+}
@@ -0,0 +1,231 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+)
+
+func TestGetUserProfile(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, err := seedUsers()
+	if err != nil {
+		log.Fatalf("Error seeding users: %v\n", err)
+	}
+	post := models.Post{Title: "Hello", Content: "First post", AuthorID: users[0].ID}
+	_, err = post.SavePost(server.DB)
+	if err != nil {
+		log.Fatalf("Error seeding post: %v\n", err)
+	}
+	// The first user keeps the activity private
+	err = server.DB.Model(&models.User{}).Where("id = ?", users[0].ID).UpdateColumn("show_activity", false).Error
+	if err != nil {
+		log.Fatalf("Error updating user: %v\n", err)
+	}
+	tokenInterface, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	ownerToken := fmt.Sprintf("Bearer %v", tokenInterface["token"])
+	tokenInterface, err = server.SignIn(users[1].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	otherToken := fmt.Sprintf("Bearer %v", tokenInterface["token"])
+
+	samples := []struct {
+		username   string
+		tokenGiven string
+		statusCode int
+		email      interface{}
+		postCount  interface{}
+	}{
+		{
+			// Visitors see neither the email nor the activity
+			username:   users[0].Username,
+			tokenGiven: "",
+			statusCode: 200,
+			email:      nil,
+			postCount:  nil,
+		},
+		{
+			username:   users[0].Username,
+			tokenGiven: otherToken,
+			statusCode: 200,
+			email:      nil,
+			postCount:  nil,
+		},
+		{
+			// The owner sees everything
+			username:   users[0].Username,
+			tokenGiven: ownerToken,
+			statusCode: 200,
+			email:      users[0].Email,
+			postCount:  float64(1),
+		},
+		{
+			// The second user shows the activity, which is the default
+			username:   users[1].Username,
+			tokenGiven: "",
+			statusCode: 200,
+			email:      nil,
+			postCount:  float64(0),
+		},
+		{
+			username:   "nobody",
+			tokenGiven: "",
+			statusCode: 404,
+		},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("GET", "/users/"+v.username, nil)
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+
+		r := gin.Default()
+		r.GET("/users/:id", server.GetUserProfile)
+		r.ServeHTTP(rr, req)
+
+		responseInterface := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseInterface)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.statusCode == 200 {
+			responseMap := responseInterface["response"].(map[string]interface{})
+			assert.Equal(t, responseMap["username"], v.username)
+			assert.Equal(t, responseMap["email"], v.email)
+			assert.Equal(t, responseMap["post_count"], v.postCount)
+			assert.Nil(t, responseMap["password"])
+		}
+		if v.statusCode == 404 {
+			responseMap := responseInterface["error"].(map[string]interface{})
+			assert.Equal(t, responseMap["No_user"], "No User Found")
+		}
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	users, err := seedUsers()
+	if err != nil {
+		log.Fatalf("Error seeding users: %v\n", err)
+	}
+	tokenInterface, err := server.SignIn(users[0].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", tokenInterface["token"])
+
+	longBio := string(bytes.Repeat([]byte("a"), 501))
+	samples := []struct {
+		id         string
+		updateJSON string
+		tokenGiven string
+		statusCode int
+	}{
+		{
+			id:         strconv.Itoa(int(users[0].ID)),
+			updateJSON: `{"bio": "I write Go", "show_email": true}`,
+			tokenGiven: tokenString,
+			statusCode: 200,
+		},
+		{
+			id:         strconv.Itoa(int(users[0].ID)),
+			updateJSON: `{"bio": "` + longBio + `"}`,
+			tokenGiven: tokenString,
+			statusCode: 422,
+		},
+		{
+			// Another user's profile
+			id:         strconv.Itoa(int(users[1].ID)),
+			updateJSON: `{"bio": "Not mine"}`,
+			tokenGiven: tokenString,
+			statusCode: 401,
+		},
+		{
+			id:         strconv.Itoa(int(users[0].ID)),
+			updateJSON: `{"bio": "No token"}`,
+			tokenGiven: "",
+			statusCode: 401,
+		},
+	}
+	for _, v := range samples {
+		req, _ := http.NewRequest("PUT", "/users/"+v.id+"/profile", bytes.NewBufferString(v.updateJSON))
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+
+		r := gin.Default()
+		r.PUT("/users/:id/profile", server.UpdateProfile)
+		r.ServeHTTP(rr, req)
+
+		responseInterface := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseInterface)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.statusCode == 200 {
+			responseMap := responseInterface["response"].(map[string]interface{})
+			assert.Equal(t, responseMap["bio"], "I write Go")
+			assert.Equal(t, responseMap["show_email"], true)
+			assert.Equal(t, responseMap["show_activity"], true)
+		}
+		if v.statusCode == 422 {
+			responseMap := responseInterface["error"].(map[string]interface{})
+			assert.Equal(t, responseMap["Invalid_bio"], "Bio should be at most 500 characters")
+		}
+	}
+}
+
+func TestGetUserProfileByOldUsername(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Error seeding user: %v\n", err)
+	}
+	_, err = (&models.User{}).ChangeUsername(server.DB, user.ID, "pet_renamed")
+	if err != nil {
+		log.Fatalf("Error changing the username: %v\n", err)
+	}
+
+	req, _ := http.NewRequest("GET", "/users/"+user.Username, nil)
+	rr := httptest.NewRecorder()
+
+	r := gin.Default()
+	r.GET("/users/:id", server.GetUserProfile)
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusMovedPermanently)
+	assert.Equal(t, rr.Header().Get("Location"), "/api/v1/users/pet_renamed")
+}
@@ -3,25 +3,17 @@ package tests
 import (
 	"bytes"
 	"encoding/json"
-	"github.com/victorsteven/forum/api/mailer"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
-var (
-	sendMailFunc func(ToUser string, FromAdmin string, Token string, Sendgridkey string, AppEnv string) (*mailer.EmailResponse, error)
-)
-type sendMailMock struct {}
-
-func (sm *sendMailMock) SendResetPassword(ToUser string, FromAdmin string, Token string, Sendgridkey string, AppEnv string) (*mailer.EmailResponse, error) {
-	return sendMailFunc(ToUser, FromAdmin, Token, Sendgridkey, AppEnv)
-}
-
 func TestForgotPasswordSuccess(t *testing.T) {
 
 	//In this test, we will simulate sending mail
@@ -36,16 +28,10 @@ func TestForgotPasswordSuccess(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	//Since we are mocking sending the email, we are going to call the fake mail function:
-	mailer.SendMail = &sendMailMock{} //this is where the magic happen, to deceive the app that we are sending real email
-
-	//We send the mail and tell it the response we want
-	sendMailFunc = func(ToUser string, FromAdmin string, Token string, Sendgridkey string, AppEnv string) (*mailer.EmailResponse, error) {
-		return &mailer.EmailResponse{
-			Status:   http.StatusOK,
-			RespBody: "Success, Please click on the link provided in your email",
-		}, nil
-	}
+	//The email is written to a file instead of being sent, so we can read it back
+	mailbox := useMailbox()
+	defer os.RemoveAll(mailbox)
+
 		inputJSON :=  `{"email": "pet@example.com"}` //the seeded user
 		r := gin.Default()
 		r.POST("/password/forgot", server.ForgotPassword)
@@ -66,6 +52,15 @@ func TestForgotPasswordSuccess(t *testing.T) {
 
 		assert.Equal(t, rr.Code, int(status.(float64))) //we convert interface to string.
 		assert.EqualValues(t, "Success, Please click on the link provided in your email", message)
+
+		emails, err := readMailbox(mailbox)
+		if err != nil {
+			t.Errorf("Cannot read the mailbox: %v", err)
+		}
+		assert.Equal(t, len(emails), 1)
+		assert.Equal(t, emails[0].To, "pet@example.com")
+		assert.Equal(t, emails[0].Subject, "Reset Password")
+		assert.True(t, strings.Contains(emails[0].Text, "/resetpassword/"))
 }
 
 
@@ -83,8 +78,9 @@ func TestForgotPasswordFailures(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
-	//Since we are mocking sending the email, we are going to call the fake mail function:
-	mailer.SendMail = &sendMailMock{} //this is where the magic happen, to deceive the app that we are sending real email
+	//No email is sent, but it would only go to a file
+	mailbox := useMailbox()
+	defer os.RemoveAll(mailbox)
 
 	samples := []struct {
 		id         string
@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+)
+
+func TestRollbackPost(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	user, post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatal(err)
+	}
+	moderator, err := seedModerator()
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The post is edited once after it was created
+	revision := models.Revision{}
+	err = revision.EnsurePostHistory(server.DB, &post)
+	if err != nil {
+		log.Fatal(err)
+	}
+	post.Content = "This is the vandalised content"
+	_, err = post.UpdateAPost(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = (&models.Revision{}).SavePostRevision(server.DB, &post, user.ID, "")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tokenInterface, err := server.SignIn(user.Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	authorToken := fmt.Sprintf("Bearer %v", tokenInterface["token"])
+
+	tokenInterface, err = server.SignIn(moderator.Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	moderatorToken := fmt.Sprintf("Bearer %v", tokenInterface["token"])
+
+	samples := []struct {
+		id         string
+		rev        string
+		tokenGiven string
+		statusCode int
+	}{
+		{
+			// Only moderators can roll back, not even the author
+			id:         strconv.Itoa(int(post.ID)),
+			rev:        "1",
+			tokenGiven: authorToken,
+			statusCode: 401,
+		},
+		{
+			id:         strconv.Itoa(int(post.ID)),
+			rev:        "9",
+			tokenGiven: moderatorToken,
+			statusCode: 404,
+		},
+		{
+			id:         strconv.Itoa(int(post.ID)),
+			rev:        "1",
+			tokenGiven: moderatorToken,
+			statusCode: 200,
+		},
+	}
+
+	for _, v := range samples {
+		r := gin.Default()
+		r.POST("/posts/:id/revisions/:rev/rollback", server.RollbackPost)
+		req, err := http.NewRequest(http.MethodPost, "/posts/"+v.id+"/revisions/"+v.rev+"/rollback", nil)
+		if err != nil {
+			t.Errorf("this is the error: %v\n", err)
+		}
+		req.Header.Set("Authorization", v.tokenGiven)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		responseInterface := make(map[string]interface{})
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseInterface)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+		}
+		assert.Equal(t, rr.Code, v.statusCode)
+
+		if v.statusCode == 200 {
+			responseMap := responseInterface["response"].(map[string]interface{})
+			assert.Equal(t, responseMap["content"], "This is the content sam")
+		}
+	}
+	// The rollback is recorded as a new revision
+	revisions, err := revision.FindRevisions(server.DB, models.RevisionPost, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the revisions: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*revisions), 3)
+	assert.Equal(t, (*revisions)[0].Note, "Rolled back to revision 1")
+}
@@ -7,11 +7,13 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strconv"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
 )
 
 func TestCreateUser(t *testing.T) {
@@ -22,6 +24,10 @@ func TestCreateUser(t *testing.T) {
 	if err != nil {
 		log.Fatal(err)
 	}
+	// The new user is welcomed by an email, in their language
+	mailbox := useMailbox()
+	defer os.RemoveAll(mailbox)
+
 	samples := []struct {
 		inputJSON  string
 		statusCode int
@@ -29,7 +35,7 @@ func TestCreateUser(t *testing.T) {
 		email      string
 	}{
 		{
-			inputJSON:  `{"username":"Pet", "email": "pet@example.com", "password": "password"}`,
+			inputJSON:  `{"username":"Pet", "email": "pet@example.com", "password": "password", "locale": "fr"}`,
 			statusCode: 201,
 			username:   "Pet",
 			email:      "pet@example.com",
@@ -58,6 +64,18 @@ func TestCreateUser(t *testing.T) {
 			inputJSON:  `{"username": "Kan", "email": "kan@example.com", "password": ""}`,
 			statusCode: 422,
 		},
+		{
+			inputJSON:  `{"username": "Kan", "email": "kan@example.com", "password": "password", "locale": "french"}`,
+			statusCode: 422,
+		},
+		{
+			inputJSON:  `{"username": "admin", "email": "kan@example.com", "password": "password"}`,
+			statusCode: 422,
+		},
+		{
+			inputJSON:  `{"username": "K@n", "email": "kan@example.com", "password": "password"}`,
+			statusCode: 422,
+		},
 	}
 
 	for _, v := range samples {
@@ -105,8 +123,65 @@ func TestCreateUser(t *testing.T) {
 			if responseMap["Required_password"] != nil {
 				assert.Equal(t, responseMap["Required_password"], "Required Password")
 			}
+			if responseMap["Reserved_username"] != nil {
+				assert.Equal(t, responseMap["Reserved_username"], "This username is reserved")
+			}
+			if responseMap["Invalid_username"] != nil {
+				assert.Equal(t, responseMap["Invalid_username"], "Username should be 3 to 30 letters, digits or underscores")
+			}
+			if responseMap["Invalid_locale"] != nil {
+				assert.Equal(t, responseMap["Invalid_locale"], "Locale should be a language code like en or fr-CA")
+			}
 		}
 	}
+	emails, err := readMailbox(mailbox)
+	if err != nil {
+		t.Errorf("this is the error reading the mailbox: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(emails), 1)
+	assert.Equal(t, emails[0].To, "pet@example.com")
+	assert.Equal(t, emails[0].Subject, "Bienvenue sur SeamFlow")
+}
+
+func TestCreateUserCannotChooseTheRole(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	mailbox := useMailbox()
+	defer os.RemoveAll(mailbox)
+
+	r := gin.Default()
+	r.POST("/users", server.CreateUser)
+	inputJSON := `{"username":"Pet", "email": "pet@example.com", "password": "password", "role": "admin", "deactivated_at": "2020-01-01T00:00:00Z"}`
+	req, err := http.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(inputJSON))
+	if err != nil {
+		t.Errorf("this is the error: %v\n", err)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	responseInterface := make(map[string]interface{})
+	err = json.Unmarshal([]byte(rr.Body.String()), &responseInterface)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	assert.Equal(t, rr.Code, http.StatusCreated)
+	responseMap := responseInterface["response"].(map[string]interface{})
+	assert.Equal(t, responseMap["role"], "user")
+
+	user := models.User{}
+	err = server.DB.Model(models.User{}).Where("email = ?", "pet@example.com").Take(&user).Error
+	if err != nil {
+		t.Errorf("this is the error getting the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, user.Role, models.RoleUser)
+	assert.Nil(t, user.DeactivatedAt)
 }
 
 func TestGetUsers(t *testing.T) {
@@ -246,50 +321,57 @@ func TestUpdateUser(t *testing.T) {
 	token := tokenInterface["token"] //get only the token
 	tokenString := fmt.Sprintf("Bearer %v", token)
 
+	// The new address gets a confirmation link and the old one a notice, written to files
+	mailbox := useMailbox()
+	defer os.RemoveAll(mailbox)
+
 	samples := []struct {
-		id          string
-		updateJSON  string
-		statusCode  int
-		username    string
-		updateEmail string
-		tokenGiven  string
+		id           string
+		updateJSON   string
+		statusCode   int
+		username     string
+		updateEmail  string
+		pendingEmail string
+		tokenGiven   string
 	}{
 		{
 			// In this particular test case, we changed the user's password to "newpassword". Very important to note
 			// Convert int32 to int first before converting to string
-			id:          strconv.Itoa(int(AuthID)),
-			updateJSON:  `{"email": "grand@example.com", "current_password": "password", "new_password": "newpassword"}`,
-			statusCode:  200,
-			username:    AuthUsername, //the username does not change, even if a new name is provided, it will be ignored
-			updateEmail: "grand@example.com",
-			tokenGiven:  tokenString,
+			// The email only changes once the new address is confirmed, so the old one is still given
+			id:           strconv.Itoa(int(AuthID)),
+			updateJSON:   `{"email": "grand@example.com", "current_password": "password", "new_password": "newpassword"}`,
+			statusCode:   200,
+			username:     AuthUsername, //the username does not change, even if a new name is provided, it will be ignored
+			updateEmail:  AuthEmail,
+			pendingEmail: "grand@example.com",
+			tokenGiven:   tokenString,
 		},
 		{
 			// An attempt to change the username, will not work, the old name is still retained.
 			// Remember, the "current_password" is now "newpassword", changed in test 1
 			id:          strconv.Itoa(int(AuthID)),
-			updateJSON:  `{"username": "new_name", "email": "grand@example.com", "current_password": "newpassword", "new_password": "newpassword"}`,
+			updateJSON:  `{"username": "new_name", "email": "` + AuthEmail + `", "current_password": "newpassword", "new_password": "newpassword"}`,
 			statusCode:  200,
 			username:    AuthUsername, //irrespective of the username inputed above, the old one is still used
-			updateEmail: "grand@example.com",
+			updateEmail: AuthEmail,
 			tokenGiven:  tokenString,
 		},
 		{
-			// The user can update only his email address
-			id:          strconv.Itoa(int(AuthID)),
-			updateJSON:  `{"email": "fred@example.com"}`,
-			statusCode:  200,
-			username:    AuthUsername,
-			updateEmail: "fred@example.com",
-			tokenGiven:  tokenString,
+			// The user can ask for a new email address with only the current password
+			id:           strconv.Itoa(int(AuthID)),
+			updateJSON:   `{"email": "fred@example.com", "current_password": "newpassword"}`,
+			statusCode:   200,
+			username:     AuthUsername,
+			updateEmail:  AuthEmail,
+			pendingEmail: "fred@example.com",
+			tokenGiven:   tokenString,
 		},
 		{
-			id:          strconv.Itoa(int(AuthID)),
-			updateJSON:  `{"email": "alex@example.com", "current_password": "", "new_password": ""}`,
-			statusCode:  200,
-			username:    AuthUsername,
-			updateEmail: "alex@example.com",
-			tokenGiven:  tokenString,
+			// A new email address needs the current password
+			id:         strconv.Itoa(int(AuthID)),
+			updateJSON: `{"email": "alex@example.com", "current_password": "", "new_password": ""}`,
+			statusCode: 422,
+			tokenGiven: tokenString,
 		},
 		{
 			// When password the "current_password" is given and does not match with the one in the database
@@ -302,9 +384,9 @@ func TestUpdateUser(t *testing.T) {
 		{
 			// When password the "current_password" is correct but the "new_password" field is not given
 			id:          strconv.Itoa(int(AuthID)),
-			updateJSON:  `{"email": "alex@example.com", "current_password": "newpassword", "new_password": ""}`,
+			updateJSON:  `{"email": "` + AuthEmail + `", "current_password": "newpassword", "new_password": ""}`,
 			statusCode:  422,
-			updateEmail: "alex@example.com",
+			updateEmail: AuthEmail,
 			tokenGiven:  tokenString,
 		},
 		{
@@ -333,7 +415,7 @@ func TestUpdateUser(t *testing.T) {
 			// Remember "kenny@example.com" belongs to user 2, so, user 1 cannot use some else email that is in our database
 			id:         strconv.Itoa(int(AuthID)),
 			updateJSON: `{"email": "kenny@example.com", "current_password": "newpassword", "new_password": "password"}`,
-			statusCode: 500,
+			statusCode: 422,
 			tokenGiven: tokenString,
 		},
 		{
@@ -384,6 +466,7 @@ func TestUpdateUser(t *testing.T) {
 			responseMap := responseInterface["response"].(map[string]interface{})
 			assert.Equal(t, responseMap["email"], v.updateEmail)
 			// assert.Equal(t, responseMap["username"], v.username)
+			assert.Equal(t, responseInterface["pending_email"], v.pendingEmail)
 		}
 
 		if v.statusCode == 401 || v.statusCode == 422 || v.statusCode == 500 {
@@ -443,6 +526,10 @@ func TestDeleteUser(t *testing.T) {
 	token := tokenInterface["token"] //get only the token
 	tokenString := fmt.Sprintf("Bearer %v", token)
 
+	// The deletion is confirmed from an email, which is written to a file
+	mailbox := useMailbox()
+	defer os.RemoveAll(mailbox)
+
 	userSample := []struct {
 		id         string
 		tokenGiven string
@@ -492,7 +579,7 @@ func TestDeleteUser(t *testing.T) {
 		assert.Equal(t, rr.Code, v.statusCode)
 
 		if v.statusCode == 200 {
-			assert.Equal(t, responseInterface["response"], "User deleted")
+			assert.Equal(t, responseInterface["response"], "Please click on the link provided in your email to confirm")
 		}
 
 		if v.statusCode == 400 || v.statusCode == 401 {
@@ -0,0 +1,89 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/mailer"
+)
+
+func TestEveryTemplateCanBePreviewed(t *testing.T) {
+	for _, name := range mailer.Templates() {
+		for _, locale := range mailer.Locales() {
+			rendered, err := mailer.Preview(name, locale)
+			if err != nil {
+				t.Errorf("this is the error previewing %s in %s: %v\n", name, locale, err)
+				continue
+			}
+			assert.NotEqual(t, rendered.Subject, "")
+			assert.NotEqual(t, rendered.Text, "")
+			assert.NotEqual(t, rendered.HTML, "")
+		}
+	}
+	_, err := mailer.Preview("newsletter", "en")
+	assert.NotNil(t, err)
+}
+
+func TestTemplatesAreWrittenInTheLocaleOfTheUser(t *testing.T) {
+	notifications := []mailer.NotificationItem{{Type: "like", Actor: "steven", Count: 3}}
+
+	rendered, err := mailer.Render(mailer.TemplateDigest, "fr", mailer.Data{"Frequency": "weekly", "Notifications": notifications, "UnsubscribeURL": "https://seamflow.com/unsubscribe/token"})
+	if err != nil {
+		t.Errorf("this is the error rendering the digest: %v\n", err)
+		return
+	}
+	assert.Equal(t, rendered.Subject, "Votre résumé SeamFlow de la semaine")
+	assert.True(t, strings.Contains(rendered.HTML, "steven et 2 autres personnes ont aimé votre post"))
+
+	// A region without a catalog of its own gets the one of its language, an unknown language the default one
+	regional, err := mailer.Render(mailer.TemplateDigest, "fr-CA", mailer.Data{"Frequency": "weekly", "Notifications": notifications, "UnsubscribeURL": "https://seamflow.com/unsubscribe/token"})
+	if err != nil {
+		t.Errorf("this is the error rendering the digest: %v\n", err)
+		return
+	}
+	assert.Equal(t, regional.Subject, rendered.Subject)
+	rendered, err = mailer.Render(mailer.TemplateDigest, "xx", mailer.Data{"Frequency": "weekly", "Notifications": notifications, "UnsubscribeURL": "https://seamflow.com/unsubscribe/token"})
+	if err != nil {
+		t.Errorf("this is the error rendering the digest: %v\n", err)
+		return
+	}
+	assert.Equal(t, rendered.Subject, "Your weekly SeamFlow digest")
+	assert.True(t, strings.Contains(rendered.HTML, "steven and 2 others liked your post"))
+}
+
+func TestTemplatesCanBeOverridden(t *testing.T) {
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatalf("cannot create the templates directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	os.Setenv("MAIL_TEMPLATES_DIR", dir)
+	defer os.Unsetenv("MAIL_TEMPLATES_DIR")
+
+	err = ioutil.WriteFile(filepath.Join(dir, "reset.subject"), []byte("Reset your {{.Product}} password\n"), 0644)
+	if err != nil {
+		t.Fatalf("cannot write the template: %v", err)
+	}
+	err = ioutil.WriteFile(filepath.Join(dir, "reset.fr.subject"), []byte("Changez votre mot de passe {{.Product}}\n"), 0644)
+	if err != nil {
+		t.Fatalf("cannot write the template: %v", err)
+	}
+	rendered, err := mailer.Render(mailer.TemplateReset, "en", mailer.Data{"Token": "token"})
+	if err != nil {
+		t.Errorf("this is the error rendering the email: %v\n", err)
+		return
+	}
+	assert.Equal(t, rendered.Subject, "Reset your SeamFlow password")
+	// What is not overridden is still the copy of the catalog
+	assert.True(t, strings.Contains(rendered.Text, "token"))
+	rendered, err = mailer.Render(mailer.TemplateReset, "fr", mailer.Data{"Token": "token"})
+	if err != nil {
+		t.Errorf("this is the error rendering the email: %v\n", err)
+		return
+	}
+	assert.Equal(t, rendered.Subject, "Changez votre mot de passe SeamFlow")
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/workers"
+)
+
+func TestSignedURL(t *testing.T) {
+
+	path := "/api/v1/exports/1/download"
+	query, err := url.ParseQuery(auth.SignURL(path, time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Errorf("this is the error parsing the query: %v\n", err)
+		return
+	}
+	assert.True(t, auth.VerifySignedURL(path, query.Get("expires"), query.Get("signature")))
+	// The signature is only good for its path
+	assert.False(t, auth.VerifySignedURL("/api/v1/exports/2/download", query.Get("expires"), query.Get("signature")))
+
+	expired, err := url.ParseQuery(auth.SignURL(path, time.Now().Add(-time.Minute)))
+	if err != nil {
+		t.Errorf("this is the error parsing the query: %v\n", err)
+		return
+	}
+	assert.False(t, auth.VerifySignedURL(path, expired.Get("expires"), expired.Get("signature")))
+}
+
+func TestDataExport(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserPostAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post and comment table: %v\n", err)
+	}
+	user, post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Cannot seed user and post %v\n", err)
+	}
+	attachment := models.Attachment{ResourceType: models.AttachmentPost, ResourceID: post.ID, UserID: user.ID, Path: "attachments/notes.txt", FileName: "notes.txt", ContentType: "text/plain", Size: 5}
+	_, err = attachment.SaveAttachment(server.DB)
+	if err != nil {
+		log.Fatalf("Cannot seed attachment %v\n", err)
+	}
+	err = fileupload.Store.Put("attachments/notes.txt", []byte("notes"), "text/plain")
+	if err != nil {
+		log.Fatalf("Cannot store the attachment file %v\n", err)
+	}
+
+	mailbox := useMailbox()
+	defer os.RemoveAll(mailbox)
+
+	dataExport := models.DataExport{UserID: user.ID}
+	_, err = dataExport.SaveDataExport(server.DB)
+	if err != nil {
+		t.Errorf("this is the error asking for the export: %v\n", err)
+		return
+	}
+	done, err := workers.RunDataExports(server.DB)
+	if err != nil {
+		t.Errorf("this is the error running the exports: %v\n", err)
+		return
+	}
+	assert.Equal(t, done, 1)
+	// The archive is in the private storage only
+	exportFound, err := dataExport.FindDataExportByID(server.DB, dataExport.ID)
+	if err != nil {
+		t.Errorf("this is the error finding the export: %v\n", err)
+		return
+	}
+	_, err = fileupload.PrivateStore.Get(exportFound.Path)
+	assert.Nil(t, err)
+	_, err = fileupload.Store.Get(exportFound.Path)
+	assert.NotNil(t, err)
+	emails, err := readMailbox(mailbox)
+	if err != nil {
+		t.Errorf("this is the error reading the mailbox: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(emails), 1)
+	var link string
+	for _, emailLink := range emails[0].Links() {
+		if strings.Contains(emailLink, dataExport.DownloadPath()+"?") {
+			link = emailLink
+		}
+	}
+	assert.NotEqual(t, link, "")
+
+	// The link from the email gives the archive
+	r := gin.Default()
+	r.GET("/api/v1/exports/:id/download", server.DownloadExport)
+	linkURL, _ := url.Parse(link)
+	req, _ := http.NewRequest("GET", linkURL.Path+"?"+linkURL.RawQuery, nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusOK)
+
+	archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+	if err != nil {
+		t.Errorf("this is the error reading the archive: %v\n", err)
+		return
+	}
+	names := []string{}
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Contains(t, names, "profile.json")
+	assert.Contains(t, names, "posts.json")
+	assert.Contains(t, names, "comments.json")
+	assert.Contains(t, names, "likes.json")
+	assert.Contains(t, names, "attachments.json")
+	assert.Contains(t, names, "attachments/1-notes.txt")
+
+	// Without the signature, the archive is not given
+	req, _ = http.NewRequest("GET", linkURL.Path, nil)
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusForbidden)
+}
+
+func TestDataExportIsClaimedOnce(t *testing.T) {
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	dataExport := models.DataExport{UserID: user.ID}
+	_, err = dataExport.SaveDataExport(server.DB)
+	if err != nil {
+		t.Errorf("this is the error asking for the export: %v\n", err)
+		return
+	}
+	claimed, err := dataExport.Claim(server.DB)
+	if err != nil {
+		t.Errorf("this is the error claiming the export: %v\n", err)
+		return
+	}
+	assert.True(t, claimed)
+
+	// Another instance finds it claimed, and does not build it
+	other := models.DataExport{ID: dataExport.ID}
+	claimed, err = other.Claim(server.DB)
+	if err != nil {
+		t.Errorf("this is the error claiming the export: %v\n", err)
+		return
+	}
+	assert.False(t, claimed)
+	done, err := workers.RunDataExports(server.DB)
+	if err != nil {
+		t.Errorf("this is the error running the exports: %v\n", err)
+		return
+	}
+	assert.Equal(t, done, 0)
+
+	// An export left building by an instance that stopped is claimed again
+	err = server.DB.Model(&models.DataExport{}).Where("id = ?", dataExport.ID).UpdateColumn("updated_at", time.Now().Add(-2*time.Hour)).Error
+	if err != nil {
+		t.Errorf("this is the error updating the export: %v\n", err)
+		return
+	}
+	claimed, err = other.Claim(server.DB)
+	if err != nil {
+		t.Errorf("this is the error claiming the export: %v\n", err)
+		return
+	}
+	assert.True(t, claimed)
+}
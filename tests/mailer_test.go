@@ -0,0 +1,220 @@
+package tests
+
+import (
+	"bufio"
+	"html"
+	"io/ioutil"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/mailer"
+)
+
+// sentEmail is an email written by the file transport, read back as a mail client would
+type sentEmail struct {
+	To      string
+	Subject string
+	Header  mail.Header
+	Text    string
+	HTML    string
+}
+
+var emailLink = regexp.MustCompile(`href="([^"]+)"`)
+
+// Links gives the addresses the HTML body links to
+func (se sentEmail) Links() []string {
+	links := []string{}
+	for _, match := range emailLink.FindAllStringSubmatch(se.HTML, -1) {
+		links = append(links, html.UnescapeString(match[1]))
+	}
+	return links
+}
+
+// useMailbox writes the emails sent from now on to a new directory, which the caller removes once done
+func useMailbox() string {
+	dir, err := ioutil.TempDir("", "mails")
+	if err != nil {
+		log.Fatalf("cannot create the mailbox: %v", err)
+	}
+	mailer.Sender, err = mailer.NewFileTransport(dir)
+	if err != nil {
+		log.Fatalf("cannot set up the mail transport: %v", err)
+	}
+	return dir
+}
+
+// readMailbox gives the emails of the directory, in the order they were sent
+func readMailbox(dir string) ([]sentEmail, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	emails := []sentEmail{}
+	for _, file := range files {
+		content, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		email, err := readEmail(content)
+		content.Close()
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, email)
+	}
+	return emails, nil
+}
+
+func readEmail(content *os.File) (sentEmail, error) {
+	message, err := mail.ReadMessage(content)
+	if err != nil {
+		return sentEmail{}, err
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(message.Header.Get("Subject"))
+	if err != nil {
+		return sentEmail{}, err
+	}
+	to, err := mail.ParseAddress(message.Header.Get("To"))
+	if err != nil {
+		return sentEmail{}, err
+	}
+	email := sentEmail{To: to.Address, Subject: subject, Header: message.Header}
+	_, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil {
+		return sentEmail{}, err
+	}
+	// The quoted-printable parts are decoded by the reader
+	parts := multipart.NewReader(message.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err != nil {
+			break
+		}
+		body, err := ioutil.ReadAll(part)
+		if err != nil {
+			return sentEmail{}, err
+		}
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/html") {
+			email.HTML = string(body)
+		} else {
+			email.Text = string(body)
+		}
+	}
+	return email, nil
+}
+
+func TestFileTransportWritesTheRenderedEmail(t *testing.T) {
+	dir := useMailbox()
+	defer os.RemoveAll(dir)
+
+	_, err := mailer.SendMail.SendNotificationEmail("pet@example.com", "en", mailer.NotificationItem{Type: "like", Actor: "steven", Count: 1}, "posts/1", "7.like.signature")
+	if err != nil {
+		t.Errorf("this is the error sending the email: %v\n", err)
+		return
+	}
+	emails, err := readMailbox(dir)
+	if err != nil {
+		t.Errorf("this is the error reading the mailbox: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(emails), 1)
+	assert.Equal(t, emails[0].To, "pet@example.com")
+	assert.Equal(t, emails[0].Subject, "steven liked your post")
+	assert.Equal(t, emails[0].Header.Get("List-Unsubscribe-Post"), "List-Unsubscribe=One-Click")
+	assert.True(t, strings.Contains(emails[0].Header.Get("List-Unsubscribe"), "/api/v1/unsubscribe?token=7.like.signature"))
+	assert.True(t, strings.Contains(emails[0].Text, "steven liked your post"))
+	assert.Contains(t, emails[0].Links(), "http://127.0.0.1:3000/posts/1")
+}
+
+// fakeSMTPServer answers an SMTP client on the listener, and gives the data of the email it got
+func fakeSMTPServer(listener net.Listener, data chan<- string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		conn.Write([]byte(line + "\r\n"))
+	}
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.Fields(line + " x")[0])
+		switch command {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 Authenticated")
+		case "MAIL", "RCPT":
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+			var message strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil || dataLine == ".\r\n" {
+					break
+				}
+				message.WriteString(dataLine)
+			}
+			data <- message.String()
+			reply("250 Queued")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Not implemented")
+		}
+	}
+}
+
+func TestSMTPTransport(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer listener.Close()
+	data := make(chan string, 1)
+	go fakeSMTPServer(listener, data)
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	transport := mailer.NewSMTPTransport("127.0.0.1", port, "seamflow", "password", false)
+	err = transport.Send(&mailer.Message{From: "admin@seamflow.com", FromName: "SeamFlow", To: "pet@example.com", Subject: "Hello", Text: "Hello Pet", HTML: "<p>Hello Pet</p>"})
+	if err != nil {
+		t.Errorf("this is the error sending the email: %v\n", err)
+		return
+	}
+	message := <-data
+	assert.True(t, strings.Contains(message, "Subject: Hello\r\n"))
+	assert.True(t, strings.Contains(message, "Hello Pet"))
+}
+
+func TestSMTPTransportRequiresStartTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot listen: %v", err)
+	}
+	defer listener.Close()
+	go fakeSMTPServer(listener, make(chan string, 1))
+
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	transport := mailer.NewSMTPTransport("127.0.0.1", port, "seamflow", "password", true)
+	err = transport.Send(&mailer.Message{From: "admin@seamflow.com", To: "pet@example.com", Subject: "Hello", Text: "Hello Pet"})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/workers"
+)
+
+func TestDeactivateAndReactivateAccount(t *testing.T) {
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	closure := models.AccountClosure{UserID: user.ID, Mode: models.ClosureDeactivate, Token: "closure-token"}
+	_, err = closure.SaveAccountClosure(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the closure: %v\n", err)
+		return
+	}
+	confirmed, err := (&models.AccountClosure{}).ConfirmAccountClosure(server.DB, "closure-token")
+	if err != nil {
+		t.Errorf("this is the error confirming the closure: %v\n", err)
+		return
+	}
+	assert.NotNil(t, confirmed.PurgeAfter)
+
+	// The profile of a closed account is hidden
+	_, err = (&models.User{}).FindProfile(server.DB, user.Username, nil)
+	assert.NotNil(t, err)
+
+	// Logging in gives the account back and cancels the closure
+	_, err = server.SignIn(user.Email, "password")
+	if err != nil {
+		t.Errorf("this is the error signing in: %v\n", err)
+		return
+	}
+	_, err = (&models.User{}).FindProfile(server.DB, user.Username, nil)
+	assert.Nil(t, err)
+
+	due, err := (&models.AccountClosure{}).FindDueClosures(server.DB, time.Now().Add(models.AccountClosureGracePeriod+time.Hour))
+	if err != nil {
+		t.Errorf("this is the error finding the due closures: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*due), 0)
+}
+
+func TestNobodyCanSignUpAsTheDeletedUser(t *testing.T) {
+	user := models.User{Username: models.DeletedUsername, Email: "DELETED@seamflow.invalid", Password: "password"}
+	errorMessages := user.Validate("")
+	assert.Equal(t, errorMessages["Reserved_username"], "This username is reserved")
+	assert.Equal(t, errorMessages["Reserved_email"], "This email is reserved")
+}
+
+func TestDeletedAccountKeepsItsContent(t *testing.T) {
+
+	err := refreshTables(&models.User{}, &models.Post{}, &models.Like{}, &models.Comment{}, &models.ResetPassword{})
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v\n", err)
+	}
+	post, users, _, err := seedUsersPostsAndComments()
+	if err != nil {
+		log.Fatalf("Cannot seed tables %v\n", err)
+	}
+	err = server.DB.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("author_id", users[0].ID).Error
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	closure := models.AccountClosure{UserID: users[0].ID, Mode: models.ClosureDelete, Token: "closure-token"}
+	_, err = closure.SaveAccountClosure(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the closure: %v\n", err)
+		return
+	}
+	_, err = (&models.AccountClosure{}).ConfirmAccountClosure(server.DB, "closure-token")
+	if err != nil {
+		t.Errorf("this is the error confirming the closure: %v\n", err)
+		return
+	}
+
+	// Nothing happens before the grace period is over
+	purged, err := workers.PurgeClosedAccounts(server.DB, time.Now())
+	if err != nil {
+		t.Errorf("this is the error purging the accounts: %v\n", err)
+		return
+	}
+	assert.Equal(t, purged, 0)
+
+	purged, err = workers.PurgeClosedAccounts(server.DB, time.Now().Add(models.AccountClosureGracePeriod+time.Hour))
+	if err != nil {
+		t.Errorf("this is the error purging the accounts: %v\n", err)
+		return
+	}
+	assert.Equal(t, purged, 1)
+
+	deleted, err := models.DeletedUser(server.DB)
+	if err != nil {
+		t.Errorf("this is the error getting the deleted user: %v\n", err)
+		return
+	}
+	assert.True(t, deleted.SystemAccount)
+	var count int
+	server.DB.Model(&models.User{}).Where("email = ?", users[0].Email).Count(&count)
+	assert.Equal(t, count, 0)
+
+	postFound := models.Post{}
+	err = server.DB.Model(&models.Post{}).Where("id = ?", post.ID).Take(&postFound).Error
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, postFound.AuthorID, deleted.ID)
+
+	server.DB.Model(&models.Comment{}).Where("user_id = ?", deleted.ID).Count(&count)
+	assert.Equal(t, count, 1)
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/fileupload"
+	"github.com/victorsteven/forum/api/models"
+)
+
+func TestCheckAttachment(t *testing.T) {
+
+	var buffer bytes.Buffer
+	err := png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 16, 16)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fileType, errList := fileupload.CheckAttachment(buffer.Bytes())
+	assert.Nil(t, errList)
+	assert.Equal(t, fileType, "image/png")
+
+	// Only the start of the file looks like an image
+	truncated := buffer.Bytes()[:buffer.Len()/2]
+	_, errList = fileupload.CheckAttachment(truncated)
+	assert.Equal(t, errList["Invalid_file"], "Please upload a valid image")
+
+	// A small GIF declaring 60000x60000 pixels is refused before being decoded
+	var gifBuffer bytes.Buffer
+	err = gif.Encode(&gifBuffer, image.NewPaletted(image.Rect(0, 0, 16, 16), palette.Plan9), nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bomb := gifBuffer.Bytes()
+	binary.LittleEndian.PutUint16(bomb[6:8], 60000)
+	binary.LittleEndian.PutUint16(bomb[8:10], 60000)
+	_, errList = fileupload.CheckAttachment(bomb)
+	assert.Equal(t, errList["Too_large"], "Sorry, this image has too many pixels")
+
+	_, errList = fileupload.CheckAttachment([]byte("MZ\x90\x00\x03\x00\x00\x00"))
+	assert.Equal(t, errList["Invalid_type"], "Please upload an image, a PDF or a text file")
+
+	_, errList = fileupload.CheckAttachment(bytes.Repeat([]byte("a"), 2<<20))
+	assert.Equal(t, errList["Too_large"], "Sorry, this file should be 1MB or less")
+}
+
+func TestOrphanedAttachmentsAndQuota(t *testing.T) {
+
+	err := refreshUserPostCommentAndAttachmentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post, comment and attachment table: %v\n", err)
+	}
+	user, post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table: %v\n", err)
+	}
+	attachments := []models.Attachment{
+		models.Attachment{ResourceType: models.AttachmentPost, ResourceID: post.ID, UserID: user.ID, Path: "kept.png", FileName: "kept.png", ContentType: "image/png", Size: 1000},
+		models.Attachment{ResourceType: models.AttachmentComment, ResourceID: 42, UserID: user.ID, Path: "orphan.png", FileName: "orphan.png", ContentType: "image/png", Size: 500},
+	}
+	for i, _ := range attachments {
+		_, err = attachments[i].SaveAttachment(server.DB)
+		if err != nil {
+			t.Errorf("this is the error saving the attachment: %v\n", err)
+			return
+		}
+	}
+	used, err := (&models.Attachment{}).UserQuotaUsed(server.DB, user.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the quota: %v\n", err)
+		return
+	}
+	assert.Equal(t, used, int64(1500))
+
+	// The post in the trash still exists, so only the attachment of the missing comment is orphaned
+	_, err = post.DeleteAPost(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	orphans, err := (&models.Attachment{}).FindOrphanedAttachments(server.DB)
+	if err != nil {
+		t.Errorf("this is the error getting the orphans: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*orphans), 1)
+	assert.Equal(t, (*orphans)[0].Path, "orphan.png")
+}
+
+func TestSaveAttachmentsKeepsToTheQuota(t *testing.T) {
+
+	err := refreshUserPostCommentAndAttachmentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post, comment and attachment table: %v\n", err)
+	}
+	user, post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table: %v\n", err)
+	}
+	attachments := []models.Attachment{
+		models.Attachment{ResourceType: models.AttachmentPost, ResourceID: post.ID, UserID: user.ID, Path: "one.png", FileName: "one.png", ContentType: "image/png", Size: 600},
+		models.Attachment{ResourceType: models.AttachmentPost, ResourceID: post.ID, UserID: user.ID, Path: "two.png", FileName: "two.png", ContentType: "image/png", Size: 300},
+	}
+	saved, err := (&models.Attachment{}).SaveAttachments(server.DB, user.ID, 1000, attachments)
+	if err != nil {
+		t.Errorf("this is the error saving the attachments: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*saved), 2)
+
+	// The upload going over the quota is not saved at all, not even its first file
+	more := []models.Attachment{
+		models.Attachment{ResourceType: models.AttachmentPost, ResourceID: post.ID, UserID: user.ID, Path: "three.png", FileName: "three.png", ContentType: "image/png", Size: 50},
+		models.Attachment{ResourceType: models.AttachmentPost, ResourceID: post.ID, UserID: user.ID, Path: "four.png", FileName: "four.png", ContentType: "image/png", Size: 200},
+	}
+	_, err = (&models.Attachment{}).SaveAttachments(server.DB, user.ID, 1000, more)
+	assert.Equal(t, err, models.ErrQuotaExceeded)
+	used, err := (&models.Attachment{}).UserQuotaUsed(server.DB, user.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the quota: %v\n", err)
+		return
+	}
+	assert.Equal(t, used, int64(900))
+}
+
+// attachmentHeader gives the file as a browser would send it in a form
+func attachmentHeader(fileName string, content []byte) (*multipart.FileHeader, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("files", fileName)
+	if err != nil {
+		return nil, err
+	}
+	_, err = part.Write(content)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	req, _ := http.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	err = req.ParseMultipartForm(1 << 20)
+	if err != nil {
+		return nil, err
+	}
+	return req.MultipartForm.File["files"][0], nil
+}
+
+func TestAttachmentsAreStoredWithTheSniffedExtension(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	header, err := attachmentHeader("x.html", []byte("hello<script>alert(document.cookie)</script>"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	attachmentFile, errList := fileupload.FileUpload.ReadAttachment(header)
+	assert.Nil(t, errList)
+	uploaded, err := fileupload.FileUpload.StoreAttachment(attachmentFile)
+	if err != nil {
+		t.Errorf("this is the error storing the attachment: %v\n", err)
+		return
+	}
+	assert.Equal(t, uploaded.FileName, "x.html")
+	assert.True(t, strings.HasSuffix(uploaded.Path, ".txt"))
+	assert.False(t, strings.Contains(uploaded.Path, ".html"))
+
+	// It is served as the text it is, and browsers are told not to guess
+	r := gin.Default()
+	r.GET("/uploads/*filepath", server.ServeUpload)
+	req, _ := http.NewRequest("GET", "/uploads/"+uploaded.Path, nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusOK)
+	assert.Equal(t, rr.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	assert.Equal(t, rr.Header().Get("X-Content-Type-Options"), "nosniff")
+
+	// An SVG passes as text, and is stored as text too
+	header, err = attachmentHeader("x.svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	attachmentFile, errList = fileupload.FileUpload.ReadAttachment(header)
+	assert.Nil(t, errList)
+	uploaded, err = fileupload.FileUpload.StoreAttachment(attachmentFile)
+	if err != nil {
+		t.Errorf("this is the error storing the attachment: %v\n", err)
+		return
+	}
+	assert.True(t, strings.HasSuffix(uploaded.Path, ".txt"))
+	assert.Equal(t, fileupload.ContentType(uploaded.Path), "text/plain; charset=utf-8")
+
+	// Files stored before keep their extension, and are served as plain bytes
+	assert.Equal(t, fileupload.ContentType("old.html"), "application/octet-stream")
+	assert.Equal(t, fileupload.ContentType("old.svg"), "application/octet-stream")
+}
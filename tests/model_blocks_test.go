@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+)
+
+func TestBlockUser(t *testing.T) {
+
+	err := refreshUserPostAndFollowTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post and follow table: %v\n", err)
+	}
+	users, err := seedUsers()
+	if err != nil {
+		log.Fatalf("Cannot seed users %v\n", err)
+	}
+	follows := []models.Follow{
+		models.Follow{FollowerID: users[0].ID, FollowingID: users[1].ID},
+		models.Follow{FollowerID: users[1].ID, FollowingID: users[0].ID},
+	}
+	for i, _ := range follows {
+		_, err = follows[i].SaveFollow(server.DB)
+		if err != nil {
+			log.Fatalf("Cannot seed follows %v\n", err)
+		}
+	}
+
+	block := models.Block{BlockerID: users[0].ID, BlockedID: users[1].ID}
+	first, err := block.SaveBlock(server.DB)
+	if err != nil {
+		t.Errorf("this is the error blocking the user: %v\n", err)
+		return
+	}
+	// Blocking again keeps the first block
+	again := models.Block{BlockerID: users[0].ID, BlockedID: users[1].ID}
+	second, err := again.SaveBlock(server.DB)
+	if err != nil {
+		t.Errorf("this is the error blocking the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, second.ID, first.ID)
+
+	// The follows in both directions are gone
+	var count int
+	err = server.DB.Model(&models.Follow{}).Count(&count).Error
+	if err != nil {
+		t.Errorf("this is the error counting the follows: %v\n", err)
+		return
+	}
+	assert.Equal(t, count, 0)
+
+	blocked, err := block.IsBlocked(server.DB, users[0].ID, users[1].ID)
+	if err != nil {
+		t.Errorf("this is the error checking the block: %v\n", err)
+		return
+	}
+	assert.True(t, blocked)
+	blocked, err = block.IsBlocked(server.DB, users[1].ID, users[0].ID)
+	if err != nil {
+		t.Errorf("this is the error checking the block: %v\n", err)
+		return
+	}
+	assert.False(t, blocked)
+
+	deleted, err := block.DeleteBlock(server.DB)
+	if err != nil {
+		t.Errorf("this is the error unblocking the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, deleted, int64(1))
+}
+
+func TestMutedUsersAreHidden(t *testing.T) {
+
+	err := refreshUserPostAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post and comment table: %v\n", err)
+	}
+	post, users, comments, err := seedUsersPostsAndComments()
+	if err != nil {
+		log.Fatalf("Cannot seed tables %v\n", err)
+	}
+	viewer, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	mute := models.Mute{MuterID: viewer.ID, MutedID: users[0].ID}
+	_, err = mute.SaveMute(server.DB)
+	if err != nil {
+		t.Errorf("this is the error muting the user: %v\n", err)
+		return
+	}
+
+	posts, err := (&models.Post{}).FindAllPosts(server.DB, models.WithoutMutedAuthors(viewer.ID))
+	if err != nil {
+		t.Errorf("this is the error getting the posts: %v\n", err)
+		return
+	}
+	for _, p := range *posts {
+		assert.NotEqual(t, p.AuthorID, users[0].ID)
+	}
+
+	visible, err := (&models.Comment{}).GetComments(server.DB, post.ID, models.WithoutMutedUsers(viewer.ID))
+	if err != nil {
+		t.Errorf("this is the error getting the comments: %v\n", err)
+		return
+	}
+	muted := 0
+	for _, comment := range comments {
+		if comment.UserID == users[0].ID {
+			muted++
+		}
+	}
+	assert.Equal(t, len(*visible), len(comments)-muted)
+	for _, comment := range *visible {
+		assert.NotEqual(t, comment.UserID, users[0].ID)
+	}
+
+	deleted, err := mute.DeleteMute(server.DB)
+	if err != nil {
+		t.Errorf("this is the error unmuting the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, deleted, int64(1))
+}
@@ -125,3 +125,42 @@ func TestDeleteCommentsForAUser(t *testing.T) {
 	}
 	assert.Equal(t, numberDeleted, int64(1))
 }
+
+func TestRestoreAComment(t *testing.T) {
+
+	err := refreshUserPostAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post and comment table %v\n", err)
+	}
+	post, users, comments, err := seedUsersPostsAndComments()
+	if err != nil {
+		log.Fatalf("Error seeding user, post and comment table %v\n", err)
+	}
+	comment := comments[0]
+	_, err = comment.DeleteAComment(server.DB)
+	if err != nil {
+		t.Errorf("this is the error deleting the comment: %v\n", err)
+		return
+	}
+	postComments, err := commentInstance.GetComments(server.DB, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the comments: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*postComments), 1)
+
+	trash, err := commentInstance.FindDeletedComments(server.DB, users[0].ID)
+	if err != nil {
+		t.Errorf("this is the error getting the trash: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*trash), 1)
+
+	restoredComment, err := comment.RestoreAComment(server.DB)
+	if err != nil {
+		t.Errorf("this is the error restoring the comment: %v\n", err)
+		return
+	}
+	assert.Equal(t, restoredComment.ID, comment.ID)
+	assert.Nil(t, restoredComment.DeletedAt)
+}
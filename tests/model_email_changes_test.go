@@ -0,0 +1,189 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+)
+
+func TestConfirmAndRevertEmailChange(t *testing.T) {
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	emailChange := models.EmailChange{
+		UserID:       user.ID,
+		OldEmail:     user.Email,
+		NewEmail:     "new@example.com",
+		ConfirmToken: "confirm-token",
+		RevertToken:  "revert-token",
+	}
+	_, err = emailChange.SaveEmailChange(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the email change: %v\n", err)
+		return
+	}
+
+	confirmed, err := (&models.EmailChange{}).ConfirmEmailChange(server.DB, "confirm-token")
+	if err != nil {
+		t.Errorf("this is the error confirming the email change: %v\n", err)
+		return
+	}
+	assert.Equal(t, confirmed.Email, "new@example.com")
+
+	// The link only works once
+	_, err = (&models.EmailChange{}).ConfirmEmailChange(server.DB, "confirm-token")
+	assert.NotNil(t, err)
+
+	reverted, err := (&models.EmailChange{}).RevertEmailChange(server.DB, "revert-token")
+	if err != nil {
+		t.Errorf("this is the error reverting the email change: %v\n", err)
+		return
+	}
+	assert.Equal(t, reverted.Email, user.Email)
+}
+
+func TestExpiredEmailChange(t *testing.T) {
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	emailChange := models.EmailChange{
+		UserID:       user.ID,
+		OldEmail:     user.Email,
+		NewEmail:     "new@example.com",
+		ConfirmToken: "confirm-token",
+		RevertToken:  "revert-token",
+	}
+	_, err = emailChange.SaveEmailChange(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the email change: %v\n", err)
+		return
+	}
+	err = server.DB.Model(&models.EmailChange{}).Where("id = ?", emailChange.ID).UpdateColumn("created_at", time.Now().Add(-models.EmailConfirmLifetime-time.Hour)).Error
+	if err != nil {
+		t.Errorf("this is the error updating the email change: %v\n", err)
+		return
+	}
+	_, err = (&models.EmailChange{}).ConfirmEmailChange(server.DB, "confirm-token")
+	assert.Equal(t, err, models.ErrEmailChangeExpired)
+
+	// The old address can still cancel it
+	reverted, err := (&models.EmailChange{}).RevertEmailChange(server.DB, "revert-token")
+	if err != nil {
+		t.Errorf("this is the error reverting the email change: %v\n", err)
+		return
+	}
+	assert.Equal(t, reverted.Email, user.Email)
+}
+
+func TestConfirmEmailChangeTakenMeanwhile(t *testing.T) {
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user table: %v\n", err)
+	}
+	users, err := seedUsers()
+	if err != nil {
+		log.Fatalf("Cannot seed users %v\n", err)
+	}
+	emailChange := models.EmailChange{
+		UserID:       users[0].ID,
+		OldEmail:     users[0].Email,
+		NewEmail:     users[1].Email,
+		ConfirmToken: "confirm-token",
+		RevertToken:  "revert-token",
+	}
+	_, err = emailChange.SaveEmailChange(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the email change: %v\n", err)
+		return
+	}
+	_, err = (&models.EmailChange{}).ConfirmEmailChange(server.DB, "confirm-token")
+	assert.Equal(t, err, models.ErrEmailTaken)
+
+	user, err := (&models.User{}).FindUserByID(server.DB, users[0].ID)
+	if err != nil {
+		t.Errorf("this is the error finding the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, user.Email, users[0].Email)
+}
+
+func TestRevertEmailChangeToAnAddressTakenMeanwhile(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	emailChange := models.EmailChange{
+		UserID:       user.ID,
+		OldEmail:     user.Email,
+		NewEmail:     "new@example.com",
+		ConfirmToken: "confirm-token",
+		RevertToken:  "revert-token",
+	}
+	_, err = emailChange.SaveEmailChange(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the email change: %v\n", err)
+		return
+	}
+	_, err = (&models.EmailChange{}).ConfirmEmailChange(server.DB, "confirm-token")
+	if err != nil {
+		t.Errorf("this is the error confirming the email change: %v\n", err)
+		return
+	}
+	// Another account signs up with the address the user left
+	other := models.User{Username: "Other", Email: user.Email, Password: "password"}
+	err = server.DB.Model(&models.User{}).Create(&other).Error
+	if err != nil {
+		log.Fatalf("cannot seed user: %v", err)
+	}
+
+	r := gin.Default()
+	r.POST("/email/revert", server.RevertEmail)
+	req, _ := http.NewRequest("POST", "/email/revert", bytes.NewBufferString(`{"token": "revert-token"}`))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	assert.Equal(t, rr.Code, http.StatusConflict)
+
+	responseInterface := make(map[string]interface{})
+	err = json.Unmarshal(rr.Body.Bytes(), &responseInterface)
+	if err != nil {
+		t.Errorf("Cannot convert to json: %v", err)
+	}
+	responseMap := responseInterface["error"].(map[string]interface{})
+	assert.Equal(t, responseMap["Taken_email"], "Your previous email is now used by another account, Pls contact support")
+
+	// The user keeps the new address
+	found, err := (&models.User{}).FindUserByID(server.DB, user.ID)
+	if err != nil {
+		t.Errorf("this is the error finding the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, found.Email, "new@example.com")
+}
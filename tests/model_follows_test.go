@@ -0,0 +1,134 @@
+package tests
+
+import (
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+)
+
+func TestFollowUser(t *testing.T) {
+
+	err := refreshUserPostAndFollowTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post and follow table: %v\n", err)
+	}
+	users, err := seedUsers()
+	if err != nil {
+		log.Fatalf("Cannot seed users %v\n", err)
+	}
+	follow := models.Follow{FollowerID: users[0].ID, FollowingID: users[1].ID}
+	first, err := follow.SaveFollow(server.DB)
+	if err != nil {
+		t.Errorf("this is the error following the user: %v\n", err)
+		return
+	}
+	// Following again keeps the first follow
+	again := models.Follow{FollowerID: users[0].ID, FollowingID: users[1].ID}
+	second, err := again.SaveFollow(server.DB)
+	if err != nil {
+		t.Errorf("this is the error following the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, second.ID, first.ID)
+
+	self := models.Follow{FollowerID: users[0].ID, FollowingID: users[0].ID}
+	_, err = self.SaveFollow(server.DB)
+	assert.NotNil(t, err)
+
+	followers, err := follow.FindFollowers(server.DB, users[1].ID, 1, 20)
+	if err != nil {
+		t.Errorf("this is the error getting the followers: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*followers), 1)
+	assert.Equal(t, (*followers)[0].ID, users[0].ID)
+
+	following, err := follow.FindFollowing(server.DB, users[1].ID, 1, 20)
+	if err != nil {
+		t.Errorf("this is the error getting the following: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*following), 0)
+
+	deleted, err := follow.DeleteFollow(server.DB)
+	if err != nil {
+		t.Errorf("this is the error unfollowing the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, deleted, int64(1))
+}
+
+func TestFindFeed(t *testing.T) {
+
+	err := refreshUserPostAndFollowTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post and follow table: %v\n", err)
+	}
+	users, err := seedUsers()
+	if err != nil {
+		log.Fatalf("Cannot seed users %v\n", err)
+	}
+	stranger, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	// The posts of the followed user and of the followed category, from the oldest to the latest
+	posts := []models.Post{
+		models.Post{Title: "Followed 1", Content: "Content", AuthorID: users[1].ID},
+		models.Post{Title: "In the category", Content: "Content", AuthorID: stranger.ID, Category: "go"},
+		models.Post{Title: "Followed 2", Content: "Content", AuthorID: users[1].ID},
+		models.Post{Title: "Not followed", Content: "Content", AuthorID: stranger.ID},
+	}
+	for i, _ := range posts {
+		posts[i].Status = models.PostPublished
+		_, err = posts[i].SavePost(server.DB)
+		if err != nil {
+			t.Errorf("this is the error saving the post: %v\n", err)
+			return
+		}
+		// The publish time is set on save, the posts are spread over the last minutes here
+		publishAt := time.Now().Add(time.Duration(i-10) * time.Minute)
+		err = server.DB.Model(&models.Post{}).Where("id = ?", posts[i].ID).UpdateColumn("publish_at", publishAt).Error
+		if err != nil {
+			t.Errorf("this is the error setting the publish time: %v\n", err)
+			return
+		}
+		posts[i].PublishAt = &publishAt
+	}
+	_, err = (&models.Follow{FollowerID: users[0].ID, FollowingID: users[1].ID}).SaveFollow(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = (&models.CategoryFollow{UserID: users[0].ID, Category: "Go"}).SaveCategoryFollow(server.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	follow := models.Follow{}
+	page, next, err := follow.FindFeed(server.DB, users[0].ID, nil, 2)
+	if err != nil {
+		t.Errorf("this is the error getting the feed: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*page), 2)
+	assert.Equal(t, (*page)[0].ID, posts[2].ID)
+	assert.Equal(t, (*page)[1].ID, posts[1].ID)
+	assert.NotEqual(t, next, "")
+
+	cursor, err := models.ParseFeedCursor(next)
+	if err != nil {
+		t.Errorf("this is the error parsing the cursor: %v\n", err)
+		return
+	}
+	page, next, err = follow.FindFeed(server.DB, users[0].ID, &cursor, 2)
+	if err != nil {
+		t.Errorf("this is the error getting the feed: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*page), 1)
+	assert.Equal(t, (*page)[0].ID, posts[0].ID)
+	assert.Equal(t, next, "")
+}
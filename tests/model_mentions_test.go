@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/utils/mentions"
+)
+
+func TestParseMentions(t *testing.T) {
+	text := "Thanks @Steven and @magu, @steven again.\nWrite to pet@example.com, or see `@kenny` and\n```\n@victor\n```\n@ab is too short"
+	assert.Equal(t, mentions.Parse(text), []string{"Steven", "magu"})
+}
+
+func TestEditingACommentOnlyNotifiesNewMentions(t *testing.T) {
+
+	err := refreshUserPostLikeAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v\n", err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Cannot seed users and posts %v\n", err)
+	}
+	// Kenny comments on their own post, so only the mentions notify
+	kenny := models.User{Username: "Kenny", Email: "kenny@example.com", Password: "password"}
+	err = server.DB.Model(&models.User{}).Create(&kenny).Error
+	if err != nil {
+		log.Fatalf("cannot seed user: %v", err)
+	}
+	post := models.Post{Title: "Kenny's post", Content: "Hello world", AuthorID: kenny.ID}
+	err = server.DB.Model(&models.Post{}).Create(&post).Error
+	if err != nil {
+		log.Fatalf("cannot seed post: %v", err)
+	}
+
+	comment := models.Comment{UserID: kenny.ID, PostID: post.ID, Body: "What do you think @Steven?"}
+	commentSaved, err := comment.SaveComment(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the comment: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(commentSaved.Mentions), 1)
+	assert.Equal(t, commentSaved.Mentions[0].UserID, users[0].ID)
+
+	commentSaved.Body = "What do you think @Steven and @Magu?"
+	commentUpdated, err := commentSaved.UpdateAComment(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the comment: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(commentUpdated.Mentions), 2)
+
+	for i, _ := range users {
+		unread, err := (&models.Notification{}).CountUnread(server.DB, users[i].ID)
+		if err != nil {
+			t.Errorf("this is the error counting the notifications: %v\n", err)
+			return
+		}
+		assert.Equal(t, unread, 1)
+	}
+}
+
+func TestAMentionEditedOutAndBackIsNotNotifiedAgain(t *testing.T) {
+
+	err := refreshUserPostLikeAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v\n", err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Cannot seed users and posts %v\n", err)
+	}
+	kenny := models.User{Username: "Kenny", Email: "kenny@example.com", Password: "password"}
+	err = server.DB.Model(&models.User{}).Create(&kenny).Error
+	if err != nil {
+		log.Fatalf("cannot seed user: %v", err)
+	}
+	post := models.Post{Title: "Kenny's post", Content: "Hello world", AuthorID: kenny.ID}
+	err = server.DB.Model(&models.Post{}).Create(&post).Error
+	if err != nil {
+		log.Fatalf("cannot seed post: %v", err)
+	}
+
+	comment := models.Comment{UserID: kenny.ID, PostID: post.ID, Body: "What do you think @Steven?"}
+	commentSaved, err := comment.SaveComment(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the comment: %v\n", err)
+		return
+	}
+	for _, body := range []string{"What do you think?", "What do you think @Steven?"} {
+		commentSaved.Body = body
+		commentSaved, err = commentSaved.UpdateAComment(server.DB)
+		if err != nil {
+			t.Errorf("this is the error updating the comment: %v\n", err)
+			return
+		}
+	}
+	assert.Equal(t, len(commentSaved.Mentions), 1)
+	assert.Equal(t, commentSaved.Mentions[0].UserID, users[0].ID)
+
+	unread, err := (&models.Notification{}).CountUnread(server.DB, users[0].ID)
+	if err != nil {
+		t.Errorf("this is the error counting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, unread, 1)
+}
+
+func TestFindPostsMentions(t *testing.T) {
+
+	err := refreshUserPostLikeAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v\n", err)
+	}
+	users, _, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Cannot seed users and posts %v\n", err)
+	}
+	post := models.Post{Title: "Mentions", Content: "Thanks @Magu", AuthorID: users[0].ID, Status: models.PostPublished}
+	_, err = post.SavePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the post: %v\n", err)
+		return
+	}
+	posts, err := (&models.Post{}).FindAllPosts(server.DB)
+	if err != nil {
+		t.Errorf("this is the error finding the posts: %v\n", err)
+		return
+	}
+	err = (&models.Mention{}).FindPostsMentions(server.DB, posts)
+	if err != nil {
+		t.Errorf("this is the error finding the mentions: %v\n", err)
+		return
+	}
+	for _, found := range *posts {
+		if found.ID == post.ID {
+			assert.Equal(t, len(found.Mentions), 1)
+			assert.Equal(t, found.Mentions[0].UserID, users[1].ID)
+		} else {
+			assert.Equal(t, len(found.Mentions), 0)
+		}
+	}
+}
+
+func TestMentionsIgnoreTheCaseOfTheUsername(t *testing.T) {
+
+	err := refreshUserPostLikeAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v\n", err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Cannot seed users and posts %v\n", err)
+	}
+	// Magu comments on their own post and mentions Steven in lower case
+	comment := models.Comment{UserID: users[1].ID, PostID: posts[1].ID, Body: "What do you think @steven?"}
+	commentSaved, err := comment.SaveComment(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the comment: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(commentSaved.Mentions), 1)
+	assert.Equal(t, commentSaved.Mentions[0].UserID, users[0].ID)
+	assert.Equal(t, commentSaved.Mentions[0].Username, "steven")
+
+	unread, err := (&models.Notification{}).CountUnread(server.DB, users[0].ID)
+	if err != nil {
+		t.Errorf("this is the error counting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, unread, 1)
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+)
+
+func TestNotificationText(t *testing.T) {
+	assert.Equal(t, responses.NotificationText(models.NotificationLike, "steven", 1), "steven liked your post")
+	assert.Equal(t, responses.NotificationText(models.NotificationLike, "steven", 2), "steven and 1 other liked your post")
+	assert.Equal(t, responses.NotificationText(models.NotificationFollow, "steven", 5), "steven and 4 others followed you")
+}
+
+func TestLikesAreGroupedInOneNotification(t *testing.T) {
+
+	err := refreshUserPostLikeAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v\n", err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Cannot seed users and posts %v\n", err)
+	}
+	author := users[0]
+	post := posts[0]
+	others := []models.User{users[1]}
+	for _, username := range []string{"third", "fourth"} {
+		user := models.User{Username: username, Email: username + "@example.com", Password: "password"}
+		err = server.DB.Model(&models.User{}).Create(&user).Error
+		if err != nil {
+			log.Fatalf("cannot seed user: %v", err)
+		}
+		others = append(others, user)
+	}
+	for _, other := range others {
+		like := models.Like{UserID: other.ID, PostID: post.ID}
+		_, err = like.SaveLike(server.DB)
+		if err != nil {
+			t.Errorf("this is the error liking the post: %v\n", err)
+			return
+		}
+	}
+	// The author liking their own post is not notified
+	_, err = (&models.Like{UserID: author.ID, PostID: post.ID}).SaveLike(server.DB)
+	if err != nil {
+		t.Errorf("this is the error liking the post: %v\n", err)
+		return
+	}
+
+	notifications, err := (&models.Notification{}).FindNotifications(server.DB, author.ID, true, 1, 20)
+	if err != nil {
+		t.Errorf("this is the error getting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*notifications), 1)
+	assert.Equal(t, (*notifications)[0].Type, models.NotificationLike)
+	assert.Equal(t, (*notifications)[0].ActorCount, 3)
+	assert.Equal(t, (*notifications)[0].Actor.ID, others[2].ID)
+
+	// Once read, the next like starts a new notification
+	_, err = (&models.Notification{}).MarkAllRead(server.DB, author.ID)
+	if err != nil {
+		t.Errorf("this is the error marking the notifications read: %v\n", err)
+		return
+	}
+	like := models.Like{}
+	err = server.DB.Model(&models.Like{}).Where("user_id = ? AND post_id = ?", others[0].ID, post.ID).Take(&like).Error
+	if err != nil {
+		t.Errorf("this is the error getting the like: %v\n", err)
+		return
+	}
+	_, err = like.DeleteLike(server.DB)
+	if err != nil {
+		t.Errorf("this is the error unliking the post: %v\n", err)
+		return
+	}
+	_, err = (&models.Like{UserID: others[0].ID, PostID: post.ID}).SaveLike(server.DB)
+	if err != nil {
+		t.Errorf("this is the error liking the post: %v\n", err)
+		return
+	}
+	notifications, err = (&models.Notification{}).FindNotifications(server.DB, author.ID, true, 1, 20)
+	if err != nil {
+		t.Errorf("this is the error getting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*notifications), 1)
+	assert.Equal(t, (*notifications)[0].ActorCount, 1)
+}
+
+func TestMutedUsersDoNotNotify(t *testing.T) {
+
+	err := refreshUserPostAndFollowTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v\n", err)
+	}
+	users, err := seedUsers()
+	if err != nil {
+		log.Fatalf("Cannot seed users %v\n", err)
+	}
+	_, err = (&models.Mute{MuterID: users[0].ID, MutedID: users[1].ID}).SaveMute(server.DB)
+	if err != nil {
+		t.Errorf("this is the error muting the user: %v\n", err)
+		return
+	}
+	_, err = (&models.Follow{FollowerID: users[1].ID, FollowingID: users[0].ID}).SaveFollow(server.DB)
+	if err != nil {
+		t.Errorf("this is the error following the user: %v\n", err)
+		return
+	}
+	unread, err := (&models.Notification{}).CountUnread(server.DB, users[0].ID)
+	if err != nil {
+		t.Errorf("this is the error counting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, unread, 0)
+}
+
+func TestAnonymizedUsersLeaveTheGroupsOfNotifications(t *testing.T) {
+
+	err := refreshUserPostLikeAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v\n", err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Cannot seed users and posts %v\n", err)
+	}
+	others := []models.User{}
+	for _, username := range []string{"third", "fourth"} {
+		user := models.User{Username: username, Email: username + "@example.com", Password: "password"}
+		err = server.DB.Model(&models.User{}).Create(&user).Error
+		if err != nil {
+			log.Fatalf("cannot seed user: %v", err)
+		}
+		others = append(others, user)
+	}
+	// Both like the post of Steven, only the last one likes the post of Magu
+	for _, other := range others {
+		_, err = (&models.Like{UserID: other.ID, PostID: posts[0].ID}).SaveLike(server.DB)
+		if err != nil {
+			t.Errorf("this is the error liking the post: %v\n", err)
+			return
+		}
+	}
+	leaving := others[1]
+	_, err = (&models.Like{UserID: leaving.ID, PostID: posts[1].ID}).SaveLike(server.DB)
+	if err != nil {
+		t.Errorf("this is the error liking the post: %v\n", err)
+		return
+	}
+
+	err = models.AnonymizeUser(server.DB, leaving.ID)
+	if err != nil {
+		t.Errorf("this is the error anonymizing the user: %v\n", err)
+		return
+	}
+
+	// The group of Steven is left with the other actor
+	notifications, err := (&models.Notification{}).FindNotifications(server.DB, users[0].ID, true, 1, 20)
+	if err != nil {
+		t.Errorf("this is the error getting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*notifications), 1)
+	assert.Equal(t, (*notifications)[0].ActorCount, 1)
+	assert.Equal(t, (*notifications)[0].ActorID, others[0].ID)
+
+	// The group of Magu had nobody else, so it is gone
+	notifications, err = (&models.Notification{}).FindNotifications(server.DB, users[1].ID, true, 1, 20)
+	if err != nil {
+		t.Errorf("this is the error getting the notifications: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*notifications), 0)
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+)
+
+func TestSavePollAndVote(t *testing.T) {
+
+	err := refreshUserPostAndPollTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post and poll table: %v\n", err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Error seeding user and post table: %v\n", err)
+	}
+	closesAt := time.Now().Add(time.Hour)
+	poll := models.Poll{
+		PostID:   posts[0].ID,
+		ClosesAt: &closesAt,
+		Options: []models.PollOption{
+			models.PollOption{Text: " Yes "},
+			models.PollOption{Text: "No"},
+		},
+	}
+	poll.Prepare()
+	assert.Equal(t, len(poll.Validate()), 0)
+
+	savedPoll, err := poll.SavePoll(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the poll: %v\n", err)
+		return
+	}
+	assert.Equal(t, savedPoll.Options[0].Text, "Yes")
+
+	for i, _ := range users {
+		vote := models.PollVote{PollID: savedPoll.ID, UserID: users[i].ID}
+		_, err = vote.SaveVote(server.DB, []uint64{savedPoll.Options[0].ID})
+		if err != nil {
+			t.Errorf("this is the error saving the vote: %v\n", err)
+			return
+		}
+	}
+	// The unique index refuses a second vote from the same user
+	vote := models.PollVote{PollID: savedPoll.ID, UserID: users[0].ID}
+	_, err = vote.SaveVote(server.DB, []uint64{savedPoll.Options[1].ID})
+	assert.Equal(t, err, models.ErrDoubleVote)
+
+	results, err := (&models.Poll{}).FindPostPoll(server.DB, posts[0].ID)
+	if err != nil {
+		t.Errorf("this is the error getting the poll: %v\n", err)
+		return
+	}
+	assert.Equal(t, results.TotalVoters, 2)
+	assert.Equal(t, results.Options[0].Votes, 2)
+	assert.Equal(t, results.Options[1].Votes, 0)
+	assert.Equal(t, len(results.Options[0].Voters), 2)
+	assert.Equal(t, results.Closed, false)
+}
+
+func TestValidatePoll(t *testing.T) {
+
+	closesAt := time.Now().Add(-time.Hour)
+	poll := models.Poll{
+		ClosesAt: &closesAt,
+		Options: []models.PollOption{
+			models.PollOption{Text: " "},
+		},
+	}
+	poll.Prepare()
+	errorMessages := poll.Validate()
+	assert.Equal(t, errorMessages["Invalid_poll_options"], "A poll should have between 2 and 10 options")
+	assert.Equal(t, errorMessages["Required_poll_option"], "Required Option Text")
+	assert.Equal(t, errorMessages["Invalid_closes_at"], "The poll should close in the future")
+	assert.Equal(t, poll.IsClosed(), true)
+}
+
+func TestAPostIsSavedWithItsPollOrNotAtAll(t *testing.T) {
+
+	err := refreshUserPostAndPollTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post and poll table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	longOption := strings.Repeat("a", 300)
+	poll := models.Poll{Options: []models.PollOption{
+		models.PollOption{Text: "Yes"},
+		models.PollOption{Text: longOption},
+	}}
+	poll.Prepare()
+	assert.Equal(t, poll.Validate()["Invalid_poll_option"], "Option Text should be at most 255 characters")
+
+	// The option does not fit the column, so nothing of the post is kept
+	post := models.Post{Title: "Lunch", Content: "Where do we go?", AuthorID: user.ID, Poll: &poll}
+	_, err = post.SavePost(server.DB)
+	assert.NotNil(t, err)
+	var count int
+	err = server.DB.Model(&models.Post{}).Unscoped().Where("title = ?", "Lunch").Count(&count).Error
+	if err != nil {
+		t.Errorf("this is the error counting the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, count, 0)
+	err = server.DB.Model(&models.Revision{}).Count(&count).Error
+	if err != nil {
+		t.Errorf("this is the error counting the revisions: %v\n", err)
+		return
+	}
+	assert.Equal(t, count, 0)
+
+	// With a valid poll, the post gets its poll and its first revision
+	poll = models.Poll{Options: []models.PollOption{
+		models.PollOption{Text: "Yes"},
+		models.PollOption{Text: "No"},
+	}}
+	poll.Prepare()
+	post = models.Post{Title: "Lunch", Content: "Where do we go?", AuthorID: user.ID, Poll: &poll}
+	savedPost, err := post.SavePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the post: %v\n", err)
+		return
+	}
+	savedPoll, err := (&models.Poll{}).FindPostPoll(server.DB, savedPost.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the poll: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(savedPoll.Options), 2)
+	revisions, err := (&models.Revision{}).FindRevisions(server.DB, models.RevisionPost, savedPost.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the revisions: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*revisions), 1)
+	assert.Equal(t, (*revisions)[0].Number, 1)
+}
@@ -2,7 +2,10 @@ package tests
 
 import (
 	"log"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	"github.com/stretchr/testify/assert"
@@ -138,3 +141,303 @@ func TestDeleteUserPosts(t *testing.T) {
 	}
 	assert.Equal(t, numberDeleted, int64(1))
 }
+
+func TestDeletedPostIsHiddenAndRestored(t *testing.T) {
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	user, post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding tables")
+	}
+	_, err = post.DeleteAPost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error deleting the post: %v\n", err)
+		return
+	}
+	_, err = (&models.Post{}).FindPostByID(server.DB, post.ID)
+	assert.NotNil(t, err)
+
+	trash, err := postInstance.FindDeletedPosts(server.DB, user.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the trash: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*trash), 1)
+
+	restoredPost, err := post.RestoreAPost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error restoring the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, restoredPost.ID, post.ID)
+	assert.Nil(t, restoredPost.DeletedAt)
+}
+
+func TestPurgeDeletedPosts(t *testing.T) {
+
+	err := refreshUserPostLikeAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user, post, like and comment table: %v\n", err)
+	}
+	post, _, _, err := seedUsersPostsAndComments()
+	if err != nil {
+		log.Fatalf("Error seeding user, post and comment table %v\n", err)
+	}
+	_, err = post.DeleteAPost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error deleting the post: %v\n", err)
+		return
+	}
+	// The post was deleted just now, so it is still within the retention period
+	numberPurged, err := postInstance.PurgeDeletedPosts(server.DB, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Errorf("this is the error purging the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, numberPurged, int64(0))
+
+	numberPurged, err = postInstance.PurgeDeletedPosts(server.DB, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Errorf("this is the error purging the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, numberPurged, int64(1))
+
+	var commentCount int
+	server.DB.Unscoped().Model(&models.Comment{}).Where("post_id = ?", post.ID).Count(&commentCount)
+	assert.Equal(t, commentCount, 0)
+}
+
+func TestPostsWithTheSameTitleGetTheirOwnSlugs(t *testing.T) {
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	// Posts created at the same time retry with the next suffix instead of failing
+	var wg sync.WaitGroup
+	slugs := make(chan string, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			post := models.Post{Title: "Help!", Content: "Please", AuthorID: user.ID}
+			_, err := post.SavePost(server.DB)
+			if err != nil {
+				t.Errorf("this is the error saving the post: %v\n", err)
+				return
+			}
+			slugs <- post.Slug
+		}()
+	}
+	wg.Wait()
+	close(slugs)
+	saved := []string{}
+	for slug := range slugs {
+		saved = append(saved, slug)
+	}
+	sort.Strings(saved)
+	assert.Equal(t, saved, []string{"help", "help-2", "help-3", "help-4", "help-5"})
+}
+
+func TestDraftsAndScheduledPosts(t *testing.T) {
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	publishAt := time.Now().Add(time.Hour)
+	posts := []models.Post{
+		models.Post{Title: "The draft", Content: "Not ready", AuthorID: user.ID, Status: models.PostDraft},
+		models.Post{Title: "The scheduled", Content: "Ready soon", AuthorID: user.ID, Status: models.PostScheduled, PublishAt: &publishAt},
+		models.Post{Title: "The published", Content: "Ready", AuthorID: user.ID},
+	}
+	for i, _ := range posts {
+		_, err = posts[i].SavePost(server.DB)
+		if err != nil {
+			t.Errorf("this is the error saving the post: %v\n", err)
+			return
+		}
+	}
+	assert.Equal(t, posts[2].Status, models.PostPublished)
+
+	// Only the published post is listed
+	allPosts, err := postInstance.FindAllPosts(server.DB)
+	if err != nil {
+		t.Errorf("this is the error getting the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*allPosts), 1)
+	_, err = (&models.Post{}).FindPostByID(server.DB, posts[0].ID)
+	assert.NotNil(t, err)
+
+	drafts, err := postInstance.FindUserDrafts(server.DB, user.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the drafts: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*drafts), 2)
+
+	// Nothing is due yet
+	published, err := postInstance.PublishDuePosts(server.DB)
+	if err != nil {
+		t.Errorf("this is the error publishing the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, published, int64(0))
+
+	err = server.DB.Model(&models.Post{}).Where("id = ?", posts[1].ID).UpdateColumn("publish_at", time.Now().Add(-time.Minute)).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	published, err = postInstance.PublishDuePosts(server.DB)
+	if err != nil {
+		t.Errorf("this is the error publishing the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, published, int64(1))
+
+	foundPost, err := (&models.Post{}).FindPostByID(server.DB, posts[1].ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, foundPost.Status, models.PostPublished)
+}
+
+func TestPublishedPostsCannotBeDatedByTheClient(t *testing.T) {
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	future := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	post := models.Post{Title: "The future", Content: "Always first", AuthorID: user.ID, Status: models.PostPublished, PublishAt: &future}
+	errorMessages := post.Validate()
+	assert.Equal(t, errorMessages["Invalid_publish_at"], "Only scheduled posts can have a publish time in the future")
+
+	// A publish time in the past is ignored too, the post is published now
+	past := time.Now().Add(-24 * time.Hour)
+	post.PublishAt = &past
+	_, err = post.SavePost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error saving the post: %v\n", err)
+		return
+	}
+	assert.True(t, post.PublishAt.After(past.Add(time.Hour)))
+	firstPublished := *post.PublishAt
+
+	// Editing the published post keeps the time it was first published at
+	edit := models.Post{ID: post.ID, Title: "The future", Content: "Edited", AuthorID: user.ID, Status: models.PostPublished, PublishAt: &past}
+	updated, err := edit.UpdateAPost(server.DB)
+	if err != nil {
+		t.Errorf("this is the error updating the post: %v\n", err)
+		return
+	}
+	// The database may keep it less precisely than the clock gave it
+	assert.True(t, updated.PublishAt.After(firstPublished.Add(-time.Second)) && updated.PublishAt.Before(firstPublished.Add(time.Second)))
+}
+
+func TestPinnedAndLockedPosts(t *testing.T) {
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	user, err := seedOneUser()
+	if err != nil {
+		log.Fatalf("Cannot seed user %v\n", err)
+	}
+	posts := []models.Post{
+		models.Post{Title: "The announcement", Content: "Read me", AuthorID: user.ID, Category: "news"},
+		models.Post{Title: "The pinned", Content: "Read me too", AuthorID: user.ID, Category: "Help "},
+		models.Post{Title: "The latest", Content: "Just a post", AuthorID: user.ID, Category: "help"},
+	}
+	for i, _ := range posts {
+		posts[i].Prepare()
+		_, err = posts[i].SavePost(server.DB)
+		if err != nil {
+			t.Errorf("this is the error saving the post: %v\n", err)
+			return
+		}
+	}
+	assert.Equal(t, posts[1].Category, "help")
+
+	until := time.Now().Add(time.Hour)
+	_, err = posts[0].Pin(server.DB, true, false, &until)
+	if err != nil {
+		t.Errorf("this is the error pinning the post: %v\n", err)
+		return
+	}
+	_, err = posts[1].Pin(server.DB, false, true, nil)
+	if err != nil {
+		t.Errorf("this is the error pinning the post: %v\n", err)
+		return
+	}
+
+	// The post pinned globally comes first, the one pinned in its category is not moved
+	allPosts, err := (&models.Post{}).FindAllPosts(server.DB)
+	if err != nil {
+		t.Errorf("this is the error getting the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, (*allPosts)[0].ID, posts[0].ID)
+	assert.Equal(t, (*allPosts)[1].ID, posts[2].ID)
+
+	categoryPosts, err := (&models.Post{}).FindCategoryPosts(server.DB, "help")
+	if err != nil {
+		t.Errorf("this is the error getting the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*categoryPosts), 2)
+	assert.Equal(t, (*categoryPosts)[0].ID, posts[1].ID)
+
+	// Nothing has expired yet
+	unpinned, err := (&models.Post{}).UnpinExpiredAnnouncements(server.DB)
+	if err != nil {
+		t.Errorf("this is the error unpinning the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, unpinned, int64(0))
+
+	err = server.DB.Model(&models.Post{}).Where("id = ?", posts[0].ID).UpdateColumn("pinned_until", time.Now().Add(-time.Minute)).Error
+	if err != nil {
+		log.Fatal(err)
+	}
+	unpinned, err = (&models.Post{}).UnpinExpiredAnnouncements(server.DB)
+	if err != nil {
+		t.Errorf("this is the error unpinning the posts: %v\n", err)
+		return
+	}
+	assert.Equal(t, unpinned, int64(1))
+
+	foundPost, err := (&models.Post{}).FindPostByID(server.DB, posts[0].ID)
+	if err != nil {
+		t.Errorf("this is the error getting the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, foundPost.PinnedGlobally, false)
+	assert.Nil(t, foundPost.PinnedUntil)
+
+	lockedPost, err := posts[2].SetLocked(server.DB, true)
+	if err != nil {
+		t.Errorf("this is the error locking the post: %v\n", err)
+		return
+	}
+	assert.Equal(t, lockedPost.Locked, true)
+}
@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"log"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+)
+
+func TestSavePostRevisions(t *testing.T) {
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	user, post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+	revision := models.Revision{}
+	err = revision.EnsurePostHistory(server.DB, &post)
+	if err != nil {
+		t.Errorf("this is the error saving the first revision: %v\n", err)
+		return
+	}
+	post.Content = "This is the edited content sam"
+	savedRevision, err := revision.SavePostRevision(server.DB, &post, user.ID, "")
+	if err != nil {
+		t.Errorf("this is the error saving the revision: %v\n", err)
+		return
+	}
+	assert.Equal(t, savedRevision.Number, 2)
+
+	revisions, err := revision.FindRevisions(server.DB, models.RevisionPost, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the revisions: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*revisions), 2)
+	assert.Equal(t, (*revisions)[0].Content, "This is the edited content sam")
+	assert.Equal(t, (*revisions)[1].Content, "This is the content sam")
+}
+
+func TestConcurrentRevisionsGetTheirOwnNumbers(t *testing.T) {
+
+	err := refreshUserAndPostTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user and post table: %v\n", err)
+	}
+	user, post, err := seedOneUserAndOnePost()
+	if err != nil {
+		log.Fatalf("Error Seeding table")
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := (&models.Revision{}).SavePostRevision(server.DB, &post, user.ID, "")
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.Nil(t, err)
+	}
+	revisions, err := (&models.Revision{}).FindRevisions(server.DB, models.RevisionPost, post.ID)
+	if err != nil {
+		t.Errorf("this is the error getting the revisions: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(*revisions), 5)
+	for i, revision := range *revisions {
+		assert.Equal(t, revision.Number, 5-i)
+	}
+}
+
+func TestRevisionDiff(t *testing.T) {
+
+	first := models.Revision{ResourceType: models.RevisionComment, ResourceID: 1, Number: 1, Content: "line one\nline two"}
+	second := models.Revision{ResourceType: models.RevisionComment, ResourceID: 1, Number: 2, Content: "line one\nline 2"}
+
+	diff, err := first.Diff(&second)
+	if err != nil {
+		t.Errorf("this is the error getting the diff: %v\n", err)
+		return
+	}
+	assert.Contains(t, diff, "--- revision 1")
+	assert.Contains(t, diff, "+++ revision 2")
+	assert.Contains(t, diff, "-line two")
+	assert.Contains(t, diff, "+line 2")
+
+	other := models.Revision{ResourceType: models.RevisionComment, ResourceID: 2, Number: 1}
+	_, err = first.Diff(&other)
+	assert.NotNil(t, err)
+}
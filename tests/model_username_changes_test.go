@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+)
+
+func TestValidateUsername(t *testing.T) {
+
+	samples := []struct {
+		username string
+		errorKey string
+	}{
+		{username: "new_name1", errorKey: ""},
+		{username: "ab", errorKey: "Invalid_username"},
+		{username: "has space", errorKey: "Invalid_username"},
+		{username: "Admin", errorKey: "Reserved_username"},
+		{username: "sh1t_happens", errorKey: "Offensive_username"},
+	}
+	for _, v := range samples {
+		errorMessages := models.ValidateUsername(v.username)
+		if v.errorKey == "" {
+			assert.Empty(t, errorMessages, v.username)
+			continue
+		}
+		assert.NotEmpty(t, errorMessages[v.errorKey], v.username)
+	}
+}
+
+func TestChangeUsername(t *testing.T) {
+
+	err := refreshUserTable()
+	if err != nil {
+		log.Fatalf("Error refreshing user table: %v\n", err)
+	}
+	users, err := seedUsers()
+	if err != nil {
+		log.Fatalf("Cannot seed users %v\n", err)
+	}
+	oldUsername := users[0].Username
+
+	// Another user's username is taken
+	_, err = (&models.User{}).ChangeUsername(server.DB, users[0].ID, users[1].Username)
+	assert.Equal(t, err, models.ErrUsernameTaken)
+
+	renamed, err := (&models.User{}).ChangeUsername(server.DB, users[0].ID, "steven_new")
+	if err != nil {
+		t.Errorf("this is the error changing the username: %v\n", err)
+		return
+	}
+	assert.Equal(t, renamed.Username, "steven_new")
+
+	// The old username still leads to the user
+	found, err := (&models.User{}).FindUserByUsername(server.DB, oldUsername)
+	if err != nil {
+		t.Errorf("this is the error finding the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, found.ID, users[0].ID)
+	// Whatever the case it is written in, as the reservation does
+	found, err = (&models.User{}).FindUserByUsername(server.DB, strings.ToUpper(oldUsername))
+	if err != nil {
+		t.Errorf("this is the error finding the user: %v\n", err)
+		return
+	}
+	assert.Equal(t, found.ID, users[0].ID)
+
+	// Nobody else can take it during the grace period
+	_, err = (&models.User{}).ChangeUsername(server.DB, users[1].ID, oldUsername)
+	assert.Equal(t, err, models.ErrUsernameTaken)
+	newUser := models.User{Username: oldUsername, Email: "other@example.com", Password: "password"}
+	_, err = newUser.SaveUser(server.DB)
+	assert.Equal(t, err, models.ErrUsernameTaken)
+
+	// A second change has to wait for the cooldown
+	_, err = (&models.User{}).ChangeUsername(server.DB, users[0].ID, "steven_again")
+	assert.Equal(t, err, models.ErrUsernameCooldown)
+
+	// Once the grace period is over, the old username is free and leads nowhere
+	err = server.DB.Model(&models.UsernameChange{}).Where("user_id = ?", users[0].ID).UpdateColumn("created_at", time.Now().Add(-models.UsernameGracePeriod-time.Hour)).Error
+	if err != nil {
+		t.Errorf("this is the error updating the change: %v\n", err)
+		return
+	}
+	_, err = (&models.User{}).FindUserByUsername(server.DB, oldUsername)
+	assert.NotNil(t, err)
+	renamed, err = (&models.User{}).ChangeUsername(server.DB, users[1].ID, oldUsername)
+	if err != nil {
+		t.Errorf("this is the error changing the username: %v\n", err)
+		return
+	}
+	assert.Equal(t, renamed.Username, oldUsername)
+}
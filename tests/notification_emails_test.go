@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/auth"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/workers"
+)
+
+func TestUnsubscribeToken(t *testing.T) {
+	token := auth.UnsubscribeToken(4, models.NotificationLike)
+	uid, kind, ok := auth.ParseUnsubscribeToken(token)
+	assert.True(t, ok)
+	assert.Equal(t, uid, uint32(4))
+	assert.Equal(t, kind, models.NotificationLike)
+
+	// Changing the user or the kind breaks the signature
+	_, _, ok = auth.ParseUnsubscribeToken("5" + token[1:])
+	assert.False(t, ok)
+	_, _, ok = auth.ParseUnsubscribeToken("")
+	assert.False(t, ok)
+}
+
+func TestValidatePreferences(t *testing.T) {
+	assert.Equal(t, len(models.ValidatePreferences(map[string]string{models.NotificationLike: models.EmailWeekly})), 0)
+	errorMessages := models.ValidatePreferences(map[string]string{"poke": "hourly"})
+	assert.Equal(t, errorMessages["Invalid_type"], "Type should be like, comment, reply, mention or follow")
+	assert.Equal(t, errorMessages["Invalid_frequency"], "Frequency should be immediate, daily, weekly or off")
+}
+
+func TestNotificationEmailsFollowThePreferences(t *testing.T) {
+
+	err := refreshUserPostLikeAndCommentTable()
+	if err != nil {
+		log.Fatalf("Error refreshing tables: %v\n", err)
+	}
+	users, posts, err := seedUsersAndPosts()
+	if err != nil {
+		log.Fatalf("Cannot seed users and posts %v\n", err)
+	}
+	author := users[0]
+	_, err = (&models.NotificationPreference{}).SavePreferences(server.DB, author.ID, map[string]string{models.NotificationLike: models.EmailImmediate})
+	if err != nil {
+		t.Errorf("this is the error saving the preferences: %v\n", err)
+		return
+	}
+	_, err = (&models.Like{UserID: users[1].ID, PostID: posts[0].ID}).SaveLike(server.DB)
+	if err != nil {
+		t.Errorf("this is the error liking the post: %v\n", err)
+		return
+	}
+	_, err = (&models.Comment{UserID: users[1].ID, PostID: posts[0].ID, Body: "This is the comment"}).SaveComment(server.DB)
+	if err != nil {
+		t.Errorf("this is the error commenting on the post: %v\n", err)
+		return
+	}
+
+	mailbox := useMailbox()
+	defer os.RemoveAll(mailbox)
+
+	// The like is emailed on its own, the comment goes in the daily digest
+	now := time.Now()
+	sent, err := workers.SendNotificationEmails(server.DB, now)
+	if err != nil {
+		t.Errorf("this is the error sending the emails: %v\n", err)
+		return
+	}
+	assert.Equal(t, sent, 2)
+	emails, err := readMailbox(mailbox)
+	if err != nil {
+		t.Errorf("this is the error reading the mailbox: %v\n", err)
+		return
+	}
+	assert.Equal(t, len(emails), 2)
+	assert.Equal(t, emails[0].Subject, "Magu liked your post")
+	assert.True(t, strings.Contains(emails[0].Header.Get("List-Unsubscribe"), url.QueryEscape(auth.UnsubscribeToken(author.ID, models.NotificationLike))))
+	assert.Equal(t, emails[1].Subject, "Your daily SeamFlow digest")
+	assert.True(t, strings.Contains(emails[1].Text, "Magu commented on your post"))
+	assert.True(t, strings.Contains(emails[1].Header.Get("List-Unsubscribe"), url.QueryEscape(auth.UnsubscribeToken(author.ID, models.UnsubscribeAll))))
+
+	// Nothing is sent twice, and the next digest waits for the day to be over
+	_, err = (&models.Comment{UserID: users[1].ID, PostID: posts[0].ID, Body: "This is another comment"}).SaveComment(server.DB)
+	if err != nil {
+		t.Errorf("this is the error commenting on the post: %v\n", err)
+		return
+	}
+	sent, err = workers.SendNotificationEmails(server.DB, now.Add(time.Hour))
+	if err != nil {
+		t.Errorf("this is the error sending the emails: %v\n", err)
+		return
+	}
+	assert.Equal(t, sent, 0)
+
+	// Unsubscribing from every email leaves the comment out of the next digest
+	_, err = (&models.NotificationPreference{}).Unsubscribe(server.DB, author.ID, models.UnsubscribeAll)
+	if err != nil {
+		t.Errorf("this is the error unsubscribing: %v\n", err)
+		return
+	}
+	sent, err = workers.SendNotificationEmails(server.DB, now.Add(25*time.Hour))
+	if err != nil {
+		t.Errorf("this is the error sending the emails: %v\n", err)
+		return
+	}
+	assert.Equal(t, sent, 0)
+}
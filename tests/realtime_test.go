@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/realtime"
+)
+
+func TestHubPublishesToTheTopicSubscribers(t *testing.T) {
+	hub := realtime.NewHub()
+	messages, unsubscribe, err := hub.Subscribe(realtime.PostTopic(1))
+	if err != nil {
+		t.Fatalf("cannot subscribe: %v", err)
+	}
+	others, unsubscribeOthers, err := hub.Subscribe(realtime.PostTopic(2))
+	if err != nil {
+		t.Fatalf("cannot subscribe: %v", err)
+	}
+	defer unsubscribeOthers()
+
+	err = hub.Publish(realtime.PostTopic(1), realtime.Message{Event: models.EventLikeCreated, Data: []byte(`{}`)})
+	if err != nil {
+		t.Fatalf("cannot publish: %v", err)
+	}
+	message := <-messages
+	assert.Equal(t, message.Event, models.EventLikeCreated)
+	assert.Equal(t, len(others), 0)
+
+	// Once unsubscribed, the channel is closed and gets nothing more
+	unsubscribe()
+	_, ok := <-messages
+	assert.False(t, ok)
+	err = hub.Publish(realtime.PostTopic(1), realtime.Message{Event: models.EventLikeCreated, Data: []byte(`{}`)})
+	assert.Nil(t, err)
+}
+
+func TestPublishedEventsHideSecrets(t *testing.T) {
+	defaultBroker := realtime.Default
+	defer func() { realtime.Default = defaultBroker }()
+	hub := realtime.NewHub()
+	realtime.Default = hub
+
+	messages, unsubscribe, err := hub.Subscribe(realtime.PostTopic(7))
+	if err != nil {
+		t.Fatalf("cannot subscribe: %v", err)
+	}
+	defer unsubscribe()
+	comment := models.Comment{
+		ID:     3,
+		UserID: 1,
+		PostID: 7,
+		Body:   "This is the comment",
+		User:   models.User{ID: 1, Username: "steven", Email: "steven@example.com", Password: "$2a$10$hashedpassword"},
+	}
+	realtime.Publish(models.EventCommentCreated, &comment)
+
+	message := <-messages
+	assert.Equal(t, message.Event, models.EventCommentCreated)
+	assert.Equal(t, message.ActorID, uint32(1))
+	assert.False(t, strings.Contains(string(message.Data), "password"))
+	assert.False(t, strings.Contains(string(message.Data), "steven@example.com"))
+}
+
+// streamRecorder is a recorder the streams can run on, they stop when the client goes
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (sr *streamRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+// subscribeSignal tells when the stream subscribed, and gives the test the means to end it
+type subscribeSignal struct {
+	*realtime.Hub
+	unsubscribes chan func()
+}
+
+func (ss *subscribeSignal) Subscribe(topic string) (<-chan realtime.Message, func(), error) {
+	messages, unsubscribe, err := ss.Hub.Subscribe(topic)
+	ss.unsubscribes <- unsubscribe
+	return messages, unsubscribe, err
+}
+
+func TestStreamPosts(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	defaultBroker := realtime.Default
+	defer func() { realtime.Default = defaultBroker }()
+	broker := &subscribeSignal{Hub: realtime.NewHub(), unsubscribes: make(chan func(), 1)}
+	realtime.Default = broker
+
+	r := gin.Default()
+	r.GET("/events/posts", server.StreamPosts)
+	req, err := http.NewRequest(http.MethodGet, "/events/posts", nil)
+	if err != nil {
+		t.Fatalf("this is the error: %v\n", err)
+	}
+	rr := &streamRecorder{httptest.NewRecorder()}
+	done := make(chan bool)
+	go func() {
+		r.ServeHTTP(rr, req)
+		done <- true
+	}()
+
+	unsubscribe := <-broker.unsubscribes
+	post := models.Post{ID: 1, Title: "The title", Content: "The content", AuthorID: 1, Author: models.User{ID: 1, Username: "steven"}}
+	realtime.Publish(models.EventPostPublished, &post)
+	// The stream ends once the messages sent before are written
+	unsubscribe()
+	<-done
+
+	assert.Equal(t, rr.Header().Get("Content-Type"), "text/event-stream")
+	assert.True(t, strings.Contains(rr.Body.String(), "event:"+models.EventPostPublished))
+	assert.True(t, strings.Contains(rr.Body.String(), "The title"))
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/models"
+	"github.com/victorsteven/forum/api/responses"
+)
+
+// passwordFields gives the path of every field of the JSON value whose name contains "password"
+func passwordFields(value interface{}, path string) []string {
+	var found []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if strings.Contains(strings.ToLower(key), "password") {
+				found = append(found, path+"."+key)
+			}
+			found = append(found, passwordFields(field, path+"."+key)...)
+		}
+	case []interface{}:
+		for i, item := range v {
+			found = append(found, passwordFields(item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+	return found
+}
+
+func TestResponsesHideUserSecrets(t *testing.T) {
+
+	user := models.User{ID: 1, Username: "Steven", Email: "steven@example.com", Password: "$2a$10$hash"}
+	post := models.Post{ID: 1, Title: "Title", Content: "Content", Author: user, AuthorID: user.ID}
+	post.Poll = &models.Poll{ID: 1, PostID: post.ID, Options: []models.PollOption{
+		models.PollOption{ID: 1, Text: "Yes", Voters: []models.User{user}},
+	}}
+	comment := models.Comment{ID: 1, Body: "Body", User: user, UserID: user.ID, PostID: post.ID}
+	revision := models.Revision{ID: 1, Editor: user, EditorID: user.ID}
+
+	values := []interface{}{
+		responses.NewPost(&post),
+		responses.NewComment(&comment),
+		responses.NewRevision(&revision),
+		responses.NewAuthors([]models.User{user}),
+		responses.NewUser(&user),
+	}
+	for _, value := range values {
+		body, err := json.Marshal(value)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+			continue
+		}
+		assert.NotContains(t, string(body), user.Password)
+
+		var decoded interface{}
+		err = json.Unmarshal(body, &decoded)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+			continue
+		}
+		assert.Empty(t, passwordFields(decoded, ""))
+	}
+	// Only the account of the user itself has the email
+	for _, value := range values[:4] {
+		body, _ := json.Marshal(value)
+		assert.NotContains(t, string(body), user.Email)
+	}
+}
+
+func TestNoPasswordInResponses(t *testing.T) {
+
+	gin.SetMode(gin.TestMode)
+
+	err := refreshUserPostLikeAndCommentTable()
+	if err != nil {
+		log.Fatal(err)
+	}
+	post, users, _, err := seedUsersPostsAndComments()
+	if err != nil {
+		log.Fatalf("Cannot seed tables %v\n", err)
+	}
+	err = server.DB.Model(&models.Post{}).Where("id = ?", post.ID).UpdateColumn("author_id", users[0].ID).Error
+	if err != nil {
+		log.Fatalf("Cannot update post %v\n", err)
+	}
+	poll := models.Poll{PostID: post.ID, Options: []models.PollOption{
+		models.PollOption{Text: "Yes"},
+		models.PollOption{Text: "No"},
+	}}
+	poll.Prepare()
+	_, err = poll.SavePoll(server.DB)
+	if err != nil {
+		log.Fatalf("Cannot seed poll %v\n", err)
+	}
+	vote := models.PollVote{PollID: poll.ID, UserID: users[1].ID}
+	_, err = vote.SaveVote(server.DB, []uint64{poll.Options[0].ID})
+	if err != nil {
+		log.Fatalf("Cannot seed vote %v\n", err)
+	}
+	_, err = (&models.Revision{}).SavePostRevision(server.DB, &post, users[0].ID, "")
+	if err != nil {
+		log.Fatalf("Cannot seed revision %v\n", err)
+	}
+	follow := models.Follow{FollowerID: users[1].ID, FollowingID: users[0].ID}
+	_, err = follow.SaveFollow(server.DB)
+	if err != nil {
+		log.Fatalf("Cannot seed follow %v\n", err)
+	}
+	tokenInterface, err := server.SignIn(users[1].Email, "password")
+	if err != nil {
+		log.Fatalf("cannot login: %v\n", err)
+	}
+	tokenString := fmt.Sprintf("Bearer %v", tokenInterface["token"])
+
+	r := gin.Default()
+	r.GET("/posts", server.GetPosts)
+	r.GET("/posts/:id", server.GetPost)
+	r.GET("/user_posts/:id", server.GetUserPosts)
+	r.GET("/posts_by_slug/:slug", server.GetPostBySlug)
+	r.GET("/comments/:id", server.GetComments)
+	r.GET("/posts/:id/revisions", server.GetPostRevisions)
+	r.GET("/users/:id", server.GetUserProfile)
+	r.GET("/users/:id/followers", server.GetFollowers)
+	r.GET("/feed", server.GetFeed)
+	r.GET("/me/blocks", server.GetBlocksAndMutes)
+	r.GET("/trash", server.GetTrash)
+
+	postID := strconv.Itoa(int(post.ID))
+	paths := []string{
+		"/posts",
+		"/posts/" + postID,
+		"/user_posts/" + strconv.Itoa(int(users[0].ID)),
+		"/posts_by_slug/" + post.Slug,
+		"/comments/" + postID,
+		"/posts/" + postID + "/revisions",
+		"/users/" + users[0].Username,
+		"/users/" + strconv.Itoa(int(users[0].ID)) + "/followers",
+		"/feed",
+		"/me/blocks",
+		"/trash",
+	}
+	for _, path := range paths {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", tokenString)
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, http.StatusOK, path)
+
+		var responseInterface interface{}
+		err = json.Unmarshal([]byte(rr.Body.String()), &responseInterface)
+		if err != nil {
+			t.Errorf("Cannot convert to json: %v", err)
+			continue
+		}
+		assert.Empty(t, passwordFields(responseInterface, ""), path)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/victorsteven/forum/api/controllers"
+	"github.com/victorsteven/forum/api/fileupload"
 	"github.com/victorsteven/forum/api/models"
 )
 
@@ -30,6 +31,9 @@ func TestMain(m *testing.M) {
 	} else {
 		CIBuild()
 	}
+	// Uploaded files are kept in memory while testing
+	fileupload.Store = fileupload.NewMemoryStorage("https://files.example.com/")
+	fileupload.PrivateStore = fileupload.NewMemoryStorage("")
 	os.Exit(m.Run())
 }
 
@@ -73,12 +77,41 @@ func Database() {
 	}
 }
 
-func refreshUserTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}).Error
+// Posts, comments and users are read along with these tables, so every refresh recreates them too
+var sideTables = []interface{}{
+	&models.Revision{},
+	&models.PostSlug{},
+	&models.Poll{},
+	&models.PollOption{},
+	&models.PollVote{},
+	&models.PollChoice{},
+	&models.Attachment{},
+	&models.Follow{},
+	&models.CategoryFollow{},
+	&models.Block{},
+	&models.Mute{},
+	&models.UsernameChange{},
+	&models.EmailChange{},
+	&models.DataExport{},
+	&models.AccountClosure{},
+	&models.Notification{},
+	&models.NotificationActor{},
+	&models.Mention{},
+	&models.NotificationPreference{},
+	&models.EmailDigest{},
+}
+
+func refreshTables(tables ...interface{}) error {
+	tables = append(tables, sideTables...)
+	err := server.DB.DropTableIfExists(tables...).Error
 	if err != nil {
 		return err
 	}
-	err = server.DB.AutoMigrate(&models.User{}).Error
+	return server.DB.AutoMigrate(tables...).Error
+}
+
+func refreshUserTable() error {
+	err := refreshTables(&models.User{})
 	if err != nil {
 		return err
 	}
@@ -131,11 +164,7 @@ func seedUsers() ([]models.User, error) {
 
 func refreshUserAndPostTable() error {
 
-	err := server.DB.DropTableIfExists(&models.User{}, &models.Post{}).Error
-	if err != nil {
-		return err
-	}
-	err = server.DB.AutoMigrate(&models.User{}, &models.Post{}).Error
+	err := refreshTables(&models.User{}, &models.Post{})
 	if err != nil {
 		return err
 	}
@@ -212,11 +241,7 @@ func seedUsersAndPosts() ([]models.User, []models.Post, error) {
 }
 
 func refreshUserPostAndLikeTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}, &models.Post{}, &models.Like{}).Error
-	if err != nil {
-		return err
-	}
-	err = server.DB.AutoMigrate(&models.User{}, &models.Post{}, &models.Like{}).Error
+	err := refreshTables(&models.User{}, &models.Post{}, &models.Like{})
 	if err != nil {
 		return err
 	}
@@ -271,11 +296,7 @@ func seedUsersPostsAndLikes() (models.Post, []models.User, []models.Like, error)
 }
 
 func refreshUserPostAndCommentTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}, &models.Post{}, &models.Comment{}).Error
-	if err != nil {
-		return err
-	}
-	err = server.DB.AutoMigrate(&models.User{}, &models.Post{}, &models.Comment{}).Error
+	err := refreshTables(&models.User{}, &models.Post{}, &models.Comment{})
 	if err != nil {
 		return err
 	}
@@ -331,12 +352,59 @@ func seedUsersPostsAndComments() (models.Post, []models.User, []models.Comment,
 	return post, users, comments, nil
 }
 
-func refreshUserAndResetPasswordTable() error {
-	err := server.DB.DropTableIfExists(&models.User{}, &models.ResetPassword{}).Error
+func refreshUserPostLikeAndCommentTable() error {
+	err := refreshTables(&models.User{}, &models.Post{}, &models.Like{}, &models.Comment{})
+	if err != nil {
+		return err
+	}
+	log.Printf("Successfully refreshed user, post, like and comment tables")
+	return nil
+}
+
+func refreshUserPostAndPollTable() error {
+	err := refreshTables(&models.User{}, &models.Post{})
 	if err != nil {
 		return err
 	}
-	err = server.DB.AutoMigrate(&models.User{}, &models.ResetPassword{}).Error
+	log.Printf("Successfully refreshed user, post and poll tables")
+	return nil
+}
+
+func refreshUserPostCommentAndAttachmentTable() error {
+	err := refreshTables(&models.User{}, &models.Post{}, &models.Comment{})
+	if err != nil {
+		return err
+	}
+	log.Printf("Successfully refreshed user, post, comment and attachment tables")
+	return nil
+}
+
+func refreshUserPostAndFollowTable() error {
+	err := refreshTables(&models.User{}, &models.Post{})
+	if err != nil {
+		return err
+	}
+	log.Printf("Successfully refreshed user, post and follow tables")
+	return nil
+}
+
+func seedModerator() (models.User, error) {
+
+	user := models.User{
+		Username: "Mod",
+		Email:    "mod@example.com",
+		Password: "password",
+		Role:     models.RoleModerator,
+	}
+	err := server.DB.Model(&models.User{}).Create(&user).Error
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+func refreshUserAndResetPasswordTable() error {
+	err := refreshTables(&models.User{}, &models.ResetPassword{})
 	if err != nil {
 		return err
 	}
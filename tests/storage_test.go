@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/victorsteven/forum/api/fileupload"
+)
+
+func TestStorageBackends(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "forum-uploads")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	localStore, err := fileupload.NewLocalStorage(dir, "/uploads/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := []fileupload.Storage{
+		localStore,
+		fileupload.NewMemoryStorage("/uploads/"),
+	}
+	for _, store := range stores {
+		err = store.Put("avatar.png", []byte("content"), "image/png")
+		if err != nil {
+			t.Errorf("this is the error putting the file: %v\n", err)
+			return
+		}
+		content, err := store.Get("avatar.png")
+		if err != nil {
+			t.Errorf("this is the error getting the file: %v\n", err)
+			return
+		}
+		assert.Equal(t, string(content), "content")
+		assert.Equal(t, store.URL("avatar.png"), "/uploads/avatar.png")
+
+		err = store.Delete("avatar.png")
+		if err != nil {
+			t.Errorf("this is the error deleting the file: %v\n", err)
+			return
+		}
+		_, err = store.Get("avatar.png")
+		assert.NotNil(t, err)
+	}
+	// The local storage keeps to its directory
+	err = localStore.Put("../outside.png", []byte("content"), "image/png")
+	assert.NotNil(t, err)
+}
+
+func TestS3StorageNeedsAPrivateBucket(t *testing.T) {
+
+	store, privateStore := fileupload.Store, fileupload.PrivateStore
+	defer func() {
+		fileupload.Store, fileupload.PrivateStore = store, privateStore
+	}()
+	endpoint := os.Getenv("DO_SPACES_ENDPOINT")
+	defer os.Setenv("DO_SPACES_ENDPOINT", endpoint)
+	privateBucket := os.Getenv("STORAGE_PRIVATE_BUCKET")
+	defer os.Setenv("STORAGE_PRIVATE_BUCKET", privateBucket)
+	bucket := os.Getenv("STORAGE_BUCKET")
+	defer os.Setenv("STORAGE_BUCKET", bucket)
+
+	os.Setenv("DO_SPACES_ENDPOINT", "nyc3.digitaloceanspaces.com")
+	os.Setenv("STORAGE_BUCKET", "forum")
+	os.Setenv("STORAGE_PRIVATE_BUCKET", "")
+	assert.NotNil(t, fileupload.Configure(fileupload.DriverS3))
+
+	// The public bucket is not private
+	os.Setenv("STORAGE_PRIVATE_BUCKET", "forum")
+	assert.NotNil(t, fileupload.Configure(fileupload.DriverS3))
+
+	os.Setenv("STORAGE_PRIVATE_BUCKET", "forum-private")
+	assert.Nil(t, fileupload.Configure(fileupload.DriverS3))
+}